@@ -0,0 +1,868 @@
+package stream_chat
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AppBackupVersion is the version of the backup format written by this package.
+const AppBackupVersion = 1
+
+const appBackupManifest = "manifest.json"
+
+// AppBackup is a snapshot of the configuration of an app. Only custom roles and
+// permissions are included, since the built-in ones exist in every app.
+type AppBackup struct {
+	Version   int
+	CreatedAt time.Time
+	// SecretsOmitted is true if credentials were left out of the backup.
+	SecretsOmitted bool
+
+	// AppSettings are the app settings without the event hooks, which are in EventHooks.
+	AppSettings   *AppSettings
+	ChannelTypes  []*ChannelType
+	Commands      []*Command
+	Roles         []*Role
+	Permissions   []*Permission
+	Blocklists    []*Blocklist
+	PushProviders []*PushProvider
+	EventHooks    []EventHook
+}
+
+type appBackupManifestFile struct {
+	Version        int       `json:"version"`
+	CreatedAt      time.Time `json:"created_at"`
+	SecretsOmitted bool      `json:"secrets_omitted"`
+	Files          []string  `json:"files"`
+}
+
+type appBackupSection struct {
+	file  string
+	value interface{}
+}
+
+// sections are the files of a backup and the fields they are decoded into.
+func (b *AppBackup) sections() []appBackupSection {
+	return []appBackupSection{
+		{"app_settings.json", &b.AppSettings},
+		{"channel_types.json", &b.ChannelTypes},
+		{"commands.json", &b.Commands},
+		{"roles.json", &b.Roles},
+		{"permissions.json", &b.Permissions},
+		{"blocklists.json", &b.Blocklists},
+		{"push_providers.json", &b.PushProviders},
+		{"event_hooks.json", &b.EventHooks},
+	}
+}
+
+// BackupAppOptions configures BackupApp.
+type BackupAppOptions struct {
+	// OmitSecrets leaves out credentials such as push provider keys and SQS secrets.
+	OmitSecrets bool
+}
+
+// BackupApp returns a snapshot of the app settings, channel types, commands, custom roles and
+// permissions, blocklists, push providers and event hooks of the app.
+func (c *Client) BackupApp(ctx context.Context, opts *BackupAppOptions) (*AppBackup, error) {
+	if opts == nil {
+		opts = &BackupAppOptions{}
+	}
+	b := &AppBackup{Version: AppBackupVersion, CreatedAt: time.Now().UTC(), SecretsOmitted: opts.OmitSecrets}
+
+	app, err := c.GetAppSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get app settings: %w", err)
+	}
+	if app.App == nil {
+		return nil, errors.New("unexpected error: app settings response is nil")
+	}
+	b.AppSettings = app.App
+	b.EventHooks = app.App.EventHooks
+	b.AppSettings.EventHooks = nil
+
+	channelTypes, err := c.ListChannelTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list channel types: %w", err)
+	}
+	for _, name := range sortedKeys(channelTypes.ChannelTypes) {
+		b.ChannelTypes = append(b.ChannelTypes, channelTypes.ChannelTypes[name])
+	}
+
+	commands, err := c.ListCommands(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list commands: %w", err)
+	}
+	b.Commands = commands.Commands
+
+	roles, err := c.Permissions().ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+	for _, r := range roles.Roles {
+		if r.Custom {
+			b.Roles = append(b.Roles, r)
+		}
+	}
+
+	perms, err := c.Permissions().ListPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list permissions: %w", err)
+	}
+	for _, p := range perms.Permissions {
+		if p.Custom {
+			b.Permissions = append(b.Permissions, p)
+		}
+	}
+
+	blocklists, err := c.ListBlocklists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list blocklists: %w", err)
+	}
+	b.Blocklists = blocklists.Blocklists
+
+	providers, err := c.ListPushProviders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list push providers: %w", err)
+	}
+	for i := range providers.PushProviders {
+		b.PushProviders = append(b.PushProviders, &providers.PushProviders[i])
+	}
+
+	if opts.OmitSecrets {
+		for _, v := range []interface{}{b.AppSettings, &b.PushProviders, &b.EventHooks} {
+			if err := omitSecrets(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+// omitSecrets removes the credential fields from the value v points to.
+func omitSecrets(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(removeSensitiveFields(generic)); err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	rv.Set(reflect.Zero(rv.Type()))
+	return json.Unmarshal(data, v)
+}
+
+func removeSensitiveFields(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			if isSensitiveField(k) {
+				delete(vv, k)
+				continue
+			}
+			vv[k] = removeSensitiveFields(item)
+		}
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = removeSensitiveFields(item)
+		}
+	}
+	return v
+}
+
+func (b *AppBackup) files() (map[string][]byte, error) {
+	manifest := appBackupManifestFile{Version: b.Version, CreatedAt: b.CreatedAt, SecretsOmitted: b.SecretsOmitted}
+	files := make(map[string][]byte)
+	for _, s := range b.sections() {
+		data, err := json.MarshalIndent(s.value, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.file, err)
+		}
+		files[s.file] = data
+		manifest.Files = append(manifest.Files, s.file)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files[appBackupManifest] = data
+	return files, nil
+}
+
+// WriteDir writes the backup as JSON files into dir, which is created if needed.
+func (b *AppBackup) WriteDir(dir string) error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// the manifest is written last, so a directory with one holds a complete backup
+	for _, name := range sortedKeys(files) {
+		if name == appBackupManifest {
+			continue
+		}
+		if err := writeFileAtomic(filepath.Join(dir, name), files[name]); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(filepath.Join(dir, appBackupManifest), files[appBackupManifest])
+}
+
+// WriteArchive writes the backup as a gzipped tar archive of the files written by WriteDir.
+func (b *AppBackup) WriteArchive(w io.Writer) error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	names := append([]string{appBackupManifest}, sortedKeys(files)...)
+	for i, name := range names {
+		if i > 0 && name == appBackupManifest {
+			continue
+		}
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), ModTime: b.CreatedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadAppBackupDir reads a backup written by WriteDir.
+func ReadAppBackupDir(dir string) (*AppBackup, error) {
+	return readAppBackup(func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dir, name))
+	})
+}
+
+// ReadAppBackupArchive reads a backup written by WriteArchive.
+func ReadAppBackupArchive(r io.Reader) (*AppBackup, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if files[hdr.Name], err = io.ReadAll(tr); err != nil {
+			return nil, err
+		}
+	}
+
+	return readAppBackup(func(name string) ([]byte, error) {
+		data, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return data, nil
+	})
+}
+
+func readAppBackup(read func(name string) ([]byte, error)) (*AppBackup, error) {
+	data, err := read(appBackupManifest)
+	if err != nil {
+		return nil, fmt.Errorf("read backup manifest: %w", err)
+	}
+	var manifest appBackupManifestFile
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", appBackupManifest, err)
+	}
+	if manifest.Version < 1 || manifest.Version > AppBackupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+
+	b := &AppBackup{Version: manifest.Version, CreatedAt: manifest.CreatedAt, SecretsOmitted: manifest.SecretsOmitted}
+	for _, s := range b.sections() {
+		data, err := read(s.file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, s.value); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.file, err)
+		}
+	}
+	return b, nil
+}
+
+// AppRestoreItem is a restored, conflicting or skipped item of a backup.
+type AppRestoreItem struct {
+	// Kind is the kind of item, such as "channel_type" or "push_provider".
+	Kind   string         `json:"kind"`
+	Name   string         `json:"name"`
+	Reason string         `json:"reason,omitempty"`
+	Fields []*FieldChange `json:"fields,omitempty"`
+}
+
+func (i *AppRestoreItem) String() string {
+	s := fmt.Sprintf("%s %q", i.Kind, i.Name)
+	if i.Reason != "" {
+		s += ": " + i.Reason
+	}
+	return s
+}
+
+// AppRestoreReport is the result of RestoreApp.
+type AppRestoreReport struct {
+	Created   []*AppRestoreItem `json:"created"`
+	Updated   []*AppRestoreItem `json:"updated"`
+	Conflicts []*AppRestoreItem `json:"conflicts"`
+	Skipped   []*AppRestoreItem `json:"skipped"`
+	// Unchanged is the number of items which already match the backup.
+	Unchanged int  `json:"unchanged"`
+	DryRun    bool `json:"dry_run"`
+}
+
+// String formats the report for humans. Credentials are not printed.
+func (r *AppRestoreReport) String() string {
+	var sb strings.Builder
+	if r.DryRun {
+		sb.WriteString("Dry run, nothing was changed.\n")
+	}
+	fmt.Fprintf(&sb, "%d created, %d updated, %d conflicts, %d skipped, %d unchanged\n",
+		len(r.Created), len(r.Updated), len(r.Conflicts), len(r.Skipped), r.Unchanged)
+	for _, group := range []struct {
+		symbol string
+		items  []*AppRestoreItem
+	}{{"+", r.Created}, {"~", r.Updated}, {"!", r.Conflicts}, {"?", r.Skipped}} {
+		for _, item := range group.items {
+			fmt.Fprintf(&sb, "%s %s\n", group.symbol, item)
+			for _, f := range (&AppSettingsDiff{Changes: item.Fields}).Redact().Changes {
+				fmt.Fprintf(&sb, "    %s\n", f)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// RestoreAppOptions configures RestoreApp.
+type RestoreAppOptions struct {
+	// Overwrite updates existing items which differ from the backup. By default they
+	// are reported as conflicts and left unchanged.
+	Overwrite bool
+	// DryRun reports what would be restored without changing anything.
+	DryRun bool
+}
+
+// RestoreApp replays a backup into the app, for example to clone the configuration of a
+// production app into a staging app. Items are restored in dependency order: roles,
+// permissions, commands and blocklists before the channel types and app settings which
+// reference them. Nothing is deleted. Items holding credentials are skipped if the backup
+// was made without secrets. It stops at the first error and returns the report so far.
+func (c *Client) RestoreApp(ctx context.Context, backup *AppBackup, opts *RestoreAppOptions) (*AppRestoreReport, error) {
+	if backup == nil {
+		return nil, errors.New("backup is nil")
+	}
+	if opts == nil {
+		opts = &RestoreAppOptions{}
+	}
+
+	r := &appRestorer{c: c, backup: backup, opts: opts, report: &AppRestoreReport{DryRun: opts.DryRun}}
+	for _, step := range []func(context.Context) error{
+		r.restoreRoles,
+		r.restorePermissions,
+		r.restoreCommands,
+		r.restoreBlocklists,
+		r.restoreChannelTypes,
+		r.restoreAppSettings,
+		r.restorePushProviders,
+		r.restoreEventHooks,
+	} {
+		if err := step(ctx); err != nil {
+			return r.report, err
+		}
+	}
+	return r.report, nil
+}
+
+type appRestorer struct {
+	c      *Client
+	backup *AppBackup
+	opts   *RestoreAppOptions
+	report *AppRestoreReport
+}
+
+// apply creates or updates an item, or reports it as a conflict if it exists and
+// overwriting is disabled. It reports whether the item was applied.
+func (r *appRestorer) apply(item *AppRestoreItem, exists bool, fn func() error) (bool, error) {
+	if r.conflict(item, exists) {
+		return false, nil
+	}
+	if !r.opts.DryRun {
+		if err := fn(); err != nil {
+			return false, fmt.Errorf("restore %s %q: %w", item.Kind, item.Name, err)
+		}
+	}
+	r.applied(item, exists)
+	return true, nil
+}
+
+// conflict reports an existing item as a conflict if overwriting is disabled.
+func (r *appRestorer) conflict(item *AppRestoreItem, exists bool) bool {
+	if !exists || r.opts.Overwrite {
+		return false
+	}
+	if item.Reason == "" {
+		item.Reason = "differs from the backup"
+	}
+	r.report.Conflicts = append(r.report.Conflicts, item)
+	return true
+}
+
+// applied reports an item as created or updated.
+func (r *appRestorer) applied(item *AppRestoreItem, exists bool) {
+	if exists {
+		r.report.Updated = append(r.report.Updated, item)
+	} else {
+		r.report.Created = append(r.report.Created, item)
+	}
+}
+
+func (r *appRestorer) skip(kind, name, reason string) {
+	r.report.Skipped = append(r.report.Skipped, &AppRestoreItem{Kind: kind, Name: name, Reason: reason})
+}
+
+func (r *appRestorer) restoreRoles(ctx context.Context) error {
+	if len(r.backup.Roles) == 0 {
+		return nil
+	}
+	live, err := r.c.Permissions().ListRoles(ctx)
+	if err != nil {
+		return fmt.Errorf("list roles: %w", err)
+	}
+	existing := make(map[string]bool, len(live.Roles))
+	for _, role := range live.Roles {
+		existing[role.Name] = true
+	}
+
+	for _, role := range r.backup.Roles {
+		if existing[role.Name] {
+			r.report.Unchanged++
+			continue
+		}
+		name := role.Name
+		if _, err := r.apply(&AppRestoreItem{Kind: "role", Name: name}, false, func() error {
+			_, err := r.c.Permissions().CreateRole(ctx, name)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *appRestorer) restorePermissions(ctx context.Context) error {
+	if len(r.backup.Permissions) == 0 {
+		return nil
+	}
+	live, err := r.c.Permissions().ListPermissions(ctx)
+	if err != nil {
+		return fmt.Errorf("list permissions: %w", err)
+	}
+	existing := make(map[string]*Permission, len(live.Permissions))
+	for _, p := range live.Permissions {
+		existing[p.ID] = p
+	}
+
+	for _, perm := range r.backup.Permissions {
+		perm := perm
+		change, err := diffPermission(perm, existing[perm.ID])
+		if err != nil {
+			r.report.Conflicts = append(r.report.Conflicts, &AppRestoreItem{Kind: "permission", Name: perm.ID, Reason: err.Error()})
+			continue
+		}
+		if change == nil {
+			r.report.Unchanged++
+			continue
+		}
+
+		item := &AppRestoreItem{Kind: "permission", Name: perm.ID, Fields: change.Fields}
+		exists := change.Action == ChangeUpdate
+		if _, err := r.apply(item, exists, func() error {
+			if exists {
+				_, err := r.c.Permissions().UpdatePermission(ctx, perm.ID, perm)
+				return err
+			}
+			_, err := r.c.Permissions().CreatePermission(ctx, perm)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *appRestorer) restoreCommands(ctx context.Context) error {
+	if len(r.backup.Commands) == 0 {
+		return nil
+	}
+	live, err := r.c.ListCommands(ctx)
+	if err != nil {
+		return fmt.Errorf("list commands: %w", err)
+	}
+	existing := make(map[string]*Command, len(live.Commands))
+	for _, cmd := range live.Commands {
+		existing[cmd.Name] = cmd
+	}
+
+	for _, cmd := range r.backup.Commands {
+		cmd := cmd
+		have, exists := existing[cmd.Name]
+		if exists && *have == *cmd {
+			r.report.Unchanged++
+			continue
+		}
+
+		item := &AppRestoreItem{Kind: "command", Name: cmd.Name}
+		if exists {
+			item.Fields = []*FieldChange{{Field: "command", From: have, To: cmd}}
+		}
+		if _, err := r.apply(item, exists, func() error {
+			var err error
+			if exists {
+				_, err = r.c.UpdateCommand(ctx, cmd.Name, &Command{Description: cmd.Description, Args: cmd.Args, Set: cmd.Set})
+			} else {
+				_, err = r.c.CreateCommand(ctx, cmd)
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *appRestorer) restoreBlocklists(ctx context.Context) error {
+	if len(r.backup.Blocklists) == 0 {
+		return nil
+	}
+	live, err := r.c.ListBlocklists(ctx)
+	if err != nil {
+		return fmt.Errorf("list blocklists: %w", err)
+	}
+	existing := make(map[string]*Blocklist, len(live.Blocklists))
+	for _, b := range live.Blocklists {
+		existing[b.Name] = b
+	}
+
+	for _, b := range r.backup.Blocklists {
+		b := b
+		have, exists := existing[b.Name]
+		if exists && sameStringSet(have.Words, b.Words) {
+			r.report.Unchanged++
+			continue
+		}
+
+		item := &AppRestoreItem{Kind: "blocklist", Name: b.Name}
+		if exists {
+			item.Fields = []*FieldChange{{Field: "words", From: len(have.Words), To: len(b.Words)}}
+		}
+		if _, err := r.apply(item, exists, func() error {
+			var err error
+			if exists {
+				_, err = r.c.UpdateBlocklist(ctx, b.Name, b.Words)
+			} else {
+				_, err = r.c.CreateBlocklist(ctx, &BlocklistCreateRequest{BlocklistBase: b.BlocklistBase})
+			}
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *appRestorer) restoreChannelTypes(ctx context.Context) error {
+	if len(r.backup.ChannelTypes) == 0 {
+		return nil
+	}
+
+	spec := &ChannelTypeSpec{}
+	for _, ct := range r.backup.ChannelTypes {
+		def, err := channelTypeDefinitionOf(ct)
+		if err != nil {
+			return fmt.Errorf("channel type %q: %w", ct.Name, err)
+		}
+		spec.ChannelTypes = append(spec.ChannelTypes, def)
+	}
+
+	live, err := r.c.ListChannelTypes(ctx)
+	if err != nil {
+		return fmt.Errorf("list channel types: %w", err)
+	}
+	plan, err := planChannelTypes(spec, live.ChannelTypes)
+	if err != nil {
+		return err
+	}
+	r.report.Unchanged += len(spec.ChannelTypes) - len(plan.Changes)
+
+	for _, change := range plan.Changes {
+		change := change
+		item := &AppRestoreItem{Kind: "channel_type", Name: change.Name}
+		if change.Action == ChangeUpdate {
+			item.Fields = change.Fields
+		}
+		if _, err := r.apply(item, change.Action == ChangeUpdate, func() error {
+			return r.c.ApplyChannelTypePlan(ctx, &ChannelTypePlan{Changes: []*ChannelTypeChange{change}})
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// channelTypeDefinitionOf returns the definition of all the settings, commands and grants of ct.
+func channelTypeDefinitionOf(ct *ChannelType) (*ChannelTypeDefinition, error) {
+	fields, err := toJSONMap(ct.ChannelConfig)
+	if err != nil {
+		return nil, err
+	}
+	def := &ChannelTypeDefinition{Name: ct.Name, Settings: make(map[string]interface{}), Commands: []string{}, Grants: ct.Grants}
+	for key, v := range fields {
+		if channelConfigFields[key] && v != nil {
+			def.Settings[key] = v
+		}
+	}
+	for _, cmd := range ct.Commands {
+		def.Commands = append(def.Commands, cmd.Name)
+	}
+	return def, nil
+}
+
+func (r *appRestorer) restoreAppSettings(ctx context.Context) error {
+	if r.backup.AppSettings == nil {
+		return nil
+	}
+	live, err := r.c.GetAppSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("get app settings: %w", err)
+	}
+	if live.App == nil {
+		return errors.New("unexpected error: app settings response is nil")
+	}
+
+	desired := *r.backup.AppSettings
+	desired.EventHooks = nil
+	plan, err := planAppSettings(&desired, live.App)
+	if err != nil {
+		return err
+	}
+
+	// settings whose credentials were omitted would be reset, so they are left as they are
+	if r.backup.SecretsOmitted {
+		omitted := make(map[string]bool)
+		for _, c := range plan.Changes {
+			if isSensitiveField(c.Field) && isZeroJSONValue(c.To) {
+				omitted[topLevelField(c.Field)] = true
+			}
+		}
+		changes := plan.Changes[:0]
+		for _, c := range plan.Changes {
+			if !omitted[topLevelField(c.Field)] {
+				changes = append(changes, c)
+			}
+		}
+		plan.Changes = changes
+		for _, key := range sortedKeys(omitted) {
+			delete(plan.update, key)
+			r.skip("app_settings", key, "secrets were omitted from the backup")
+		}
+	}
+
+	if plan.Empty() {
+		r.report.Unchanged++
+		return nil
+	}
+	_, err = r.apply(&AppRestoreItem{Kind: "app_settings", Name: "app", Fields: plan.Changes}, true, func() error {
+		_, err := r.c.ApplyAppSettingsPlan(ctx, plan)
+		return err
+	})
+	return err
+}
+
+func topLevelField(field string) string {
+	if i := strings.IndexAny(field, ".["); i >= 0 {
+		return field[:i]
+	}
+	return field
+}
+
+func (r *appRestorer) restorePushProviders(ctx context.Context) error {
+	if len(r.backup.PushProviders) == 0 {
+		return nil
+	}
+	live, err := r.c.ListPushProviders(ctx)
+	if err != nil {
+		return fmt.Errorf("list push providers: %w", err)
+	}
+	existing := make(map[string]PushProvider, len(live.PushProviders))
+	for _, p := range live.PushProviders {
+		existing[string(p.Type)+"/"+p.Name] = p
+	}
+
+	for _, p := range r.backup.PushProviders {
+		p := p
+		key := string(p.Type) + "/" + p.Name
+		have, exists := existing[key]
+		if exists && samePushProvider(have, *p) {
+			r.report.Unchanged++
+			continue
+		}
+		if r.backup.SecretsOmitted {
+			r.skip("push_provider", key, "secrets were omitted from the backup")
+			continue
+		}
+
+		if _, err := r.apply(&AppRestoreItem{Kind: "push_provider", Name: key}, exists, func() error {
+			_, err := r.c.UpsertPushProvider(ctx, p)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// samePushProvider compares providers ignoring their disabled state, which is set by the
+// server, and their credentials, which the API doesn't return.
+func samePushProvider(a, b PushProvider) bool {
+	for _, p := range []*PushProvider{&a, &b} {
+		p.DisabledAt, p.DisabledReason = nil, ""
+		p.APNAuthKey, p.FirebaseCredentials, p.HuaweiAppSecret, p.XiaomiAppSecret = "", "", "", ""
+		if p.FirebaseNotificationTemplate != nil && *p.FirebaseNotificationTemplate == "" {
+			p.FirebaseNotificationTemplate = nil
+		}
+		if p.FirebaseAPNTemplate != nil && *p.FirebaseAPNTemplate == "" {
+			p.FirebaseAPNTemplate = nil
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// eventHookTarget identifies an event hook across apps, where hook IDs differ.
+func eventHookTarget(h *EventHook) string {
+	for _, target := range []string{h.WebhookURL, h.SQSQueueURL, h.SNSTopicARN} {
+		if target != "" {
+			return string(h.HookType) + " " + target
+		}
+	}
+	return string(h.HookType)
+}
+
+func sameEventHook(a, b EventHook) bool {
+	a.ID, a.CreatedAt, a.UpdatedAt = "", time.Time{}, time.Time{}
+	b.ID, b.CreatedAt, b.UpdatedAt = "", time.Time{}, time.Time{}
+	sort.Strings(a.EventTypes)
+	sort.Strings(b.EventTypes)
+	return reflect.DeepEqual(a, b)
+}
+
+func (r *appRestorer) restoreEventHooks(ctx context.Context) error {
+	if len(r.backup.EventHooks) == 0 {
+		return nil
+	}
+	live, err := r.c.GetAppSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("get app settings: %w", err)
+	}
+	if live.App == nil {
+		return errors.New("unexpected error: app settings response is nil")
+	}
+
+	hooks := append([]EventHook{}, live.App.EventHooks...)
+	index := make(map[string]int, len(hooks))
+	for i := range hooks {
+		index[eventHookTarget(&hooks[i])] = i
+	}
+
+	// all hooks are sent in a single request, so they are only reported once it succeeded
+	type pendingHook struct {
+		item   *AppRestoreItem
+		exists bool
+	}
+	var pending []pendingHook
+	for _, h := range r.backup.EventHooks {
+		h := h
+		target := eventHookTarget(&h)
+		i, exists := index[target]
+		if exists && sameEventHook(hooks[i], h) {
+			r.report.Unchanged++
+			continue
+		}
+		if r.backup.SecretsOmitted && (h.SQSAuthType == "keys" || h.SNSAuthType == "keys") {
+			r.skip("event_hook", target, "secrets were omitted from the backup")
+			continue
+		}
+
+		h.CreatedAt, h.UpdatedAt = time.Time{}, time.Time{}
+		if exists {
+			h.ID = hooks[i].ID
+		} else {
+			h.ID = ""
+		}
+		item := &AppRestoreItem{Kind: "event_hook", Name: target}
+		if r.conflict(item, exists) {
+			continue
+		}
+		pending = append(pending, pendingHook{item, exists})
+		if exists {
+			hooks[i] = h
+		} else {
+			index[target] = len(hooks)
+			hooks = append(hooks, h)
+		}
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+	if !r.opts.DryRun {
+		var resp Response
+		if err := r.c.makeRequest(ctx, http.MethodPatch, "app", nil, map[string]interface{}{"event_hooks": hooks}, &resp); err != nil {
+			return fmt.Errorf("restore event hooks: %w", err)
+		}
+	}
+	for _, p := range pending {
+		r.applied(p.item, p.exists)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package stream_chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAppConfigServer struct {
+	mu       sync.Mutex
+	t        *testing.T
+	get      map[string]interface{}
+	fail     map[string]bool
+	requests []string
+	bodies   []map[string]interface{}
+}
+
+func (s *fakeAppConfigServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method == http.MethodGet {
+		resp, ok := s.get[r.URL.Path]
+		require.True(s.t, ok, r.URL.Path)
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	req := r.Method + " " + r.URL.Path
+	s.requests = append(s.requests, req)
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	s.bodies = append(s.bodies, body)
+
+	if s.fail[req] {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"message": "boom", "StatusCode": 500}`))
+		return
+	}
+
+	switch req {
+	case "POST /commands":
+		_, _ = w.Write([]byte(`{"command": {"name": "ticket"}}`))
+	case "POST /channeltypes":
+		_, _ = w.Write([]byte(`{"name": "support"}`))
+	default:
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+func newFakeAppConfigServer(t *testing.T, get map[string]interface{}) *fakeAppConfigServer {
+	return &fakeAppConfigServer{t: t, get: get}
+}
+
+func sourceAppConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"/app": map[string]interface{}{"app": map[string]interface{}{
+			"name":                     "production",
+			"webhook_url":              "https://hooks.example.com",
+			"sqs_key":                  "key",
+			"sqs_secret":               "secret",
+			"image_moderation_enabled": true,
+			"apn_config":               map[string]interface{}{"auth_type": "token", "auth_key": "apn-key", "team_id": "T1"},
+			"event_hooks": []map[string]interface{}{
+				{"id": "h1", "hook_type": "webhook", "enabled": true, "webhook_url": "https://hooks.example.com/v2", "event_types": []string{"message.new"}},
+				{"id": "h2", "hook_type": "sqs", "enabled": true, "sqs_queue_url": "https://sqs/q", "sqs_auth_type": "keys", "sqs_key": "k", "sqs_secret": "s"},
+			},
+		}},
+		"/channeltypes": map[string]interface{}{"channel_types": map[string]interface{}{
+			"messaging": map[string]interface{}{"name": "messaging", "typing_events": true, "max_message_length": 5000,
+				"commands": []map[string]interface{}{{"name": "giphy"}}, "grants": map[string][]string{"channel_member": {"read-channel"}}},
+			"support": map[string]interface{}{"name": "support", "reactions": true, "max_message_length": 2000,
+				"commands": []map[string]interface{}{{"name": "ticket"}}},
+		}},
+		"/commands": map[string]interface{}{"commands": []map[string]interface{}{
+			{"name": "ticket", "description": "Open a ticket", "args": "[text]", "set": "support_set"},
+		}},
+		"/roles": map[string]interface{}{"roles": []map[string]interface{}{
+			{"name": "admin"}, {"name": "support_agent", "custom": true},
+		}},
+		"/permissions": map[string]interface{}{"permissions": []map[string]interface{}{
+			{"id": "read-channel", "action": "ReadChannel"},
+			{"id": "read-support", "name": "Read support", "action": "ReadChannel", "custom": true},
+		}},
+		"/blocklists": map[string]interface{}{"blocklists": []map[string]interface{}{
+			{"name": "swear", "words": []string{"darn", "heck"}},
+		}},
+		"/push_providers": map[string]interface{}{"push_providers": []map[string]interface{}{
+			{"type": "apn", "name": "ios", "apn_auth_key": "secret-key", "apn_key_id": "K1"},
+		}},
+	}
+}
+
+func TestClient_BackupApp(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newFakeAppConfigServer(t, sourceAppConfig()))
+
+	b, err := c.BackupApp(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, AppBackupVersion, b.Version)
+	require.False(t, b.SecretsOmitted)
+	require.Equal(t, "secret", *b.AppSettings.SqsSecret)
+	require.Nil(t, b.AppSettings.EventHooks)
+	require.Len(t, b.EventHooks, 2)
+	require.Equal(t, []string{"messaging", "support"}, []string{b.ChannelTypes[0].Name, b.ChannelTypes[1].Name})
+	require.Len(t, b.Roles, 1)
+	require.Len(t, b.Permissions, 1)
+	require.Equal(t, "secret-key", b.PushProviders[0].APNAuthKey)
+
+	b, err = c.BackupApp(ctx, &BackupAppOptions{OmitSecrets: true})
+	require.NoError(t, err)
+	require.True(t, b.SecretsOmitted)
+	require.Nil(t, b.AppSettings.SqsSecret)
+	require.Nil(t, b.AppSettings.SqsKey)
+	require.Equal(t, "https://hooks.example.com", *b.AppSettings.WebhookURL)
+	require.Empty(t, b.AppSettings.APNConfig.AuthKey)
+	require.Equal(t, "T1", b.AppSettings.APNConfig.TeamID)
+	require.Empty(t, b.PushProviders[0].APNAuthKey)
+	require.Equal(t, "K1", b.PushProviders[0].APNKeyID)
+	require.Empty(t, b.EventHooks[1].SQSSecret)
+	require.Equal(t, "https://sqs/q", b.EventHooks[1].SQSQueueURL)
+}
+
+func TestAppBackup_WriteAndRead(t *testing.T) {
+	c := newTestClient(t, newFakeAppConfigServer(t, sourceAppConfig()))
+	b, err := c.BackupApp(context.Background(), nil)
+	require.NoError(t, err)
+	b.CreatedAt = b.CreatedAt.Truncate(time.Second)
+
+	dir := filepath.Join(t.TempDir(), "backup")
+	require.NoError(t, b.WriteDir(dir))
+	fromDir, err := ReadAppBackupDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, b, fromDir)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.WriteArchive(&buf))
+	fromArchive, err := ReadAppBackupArchive(&buf)
+	require.NoError(t, err)
+	require.Equal(t, b, fromArchive)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"version": 99}`), 0o644))
+	_, err = ReadAppBackupDir(dir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unsupported backup version 99")
+
+	_, err = ReadAppBackupDir(t.TempDir())
+	require.Error(t, err)
+}
+
+func TestClient_RestoreApp(t *testing.T) {
+	ctx := context.Background()
+	source := newTestClient(t, newFakeAppConfigServer(t, sourceAppConfig()))
+	backup, err := source.BackupApp(ctx, &BackupAppOptions{OmitSecrets: true})
+	require.NoError(t, err)
+
+	target := func() (*Client, *fakeAppConfigServer) {
+		srv := newFakeAppConfigServer(t, map[string]interface{}{
+			"/app": map[string]interface{}{"app": map[string]interface{}{
+				"name":        "staging",
+				"webhook_url": "https://hooks.example.com",
+				"apn_config":  map[string]interface{}{"auth_type": "token", "auth_key": "staging-key", "team_id": "T2"},
+				"event_hooks": []map[string]interface{}{
+					{"id": "x1", "hook_type": "webhook", "enabled": false, "webhook_url": "https://hooks.example.com/v2", "event_types": []string{"message.new"}},
+				},
+			}},
+			"/channeltypes": map[string]interface{}{"channel_types": map[string]interface{}{
+				"messaging": map[string]interface{}{"name": "messaging", "typing_events": true, "max_message_length": 4000,
+					"commands": []map[string]interface{}{{"name": "giphy"}}, "grants": map[string][]string{"channel_member": {"read-channel"}}},
+			}},
+			"/commands":       map[string]interface{}{"commands": []map[string]interface{}{}},
+			"/roles":          map[string]interface{}{"roles": []map[string]interface{}{{"name": "admin"}}},
+			"/permissions":    map[string]interface{}{"permissions": []map[string]interface{}{{"id": "read-channel", "action": "ReadChannel"}}},
+			"/blocklists":     map[string]interface{}{"blocklists": []map[string]interface{}{{"name": "swear", "words": []string{"heck", "darn"}}}},
+			"/push_providers": map[string]interface{}{"push_providers": []map[string]interface{}{}},
+		})
+		return newTestClient(t, srv), srv
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		c, srv := target()
+		report, err := c.RestoreApp(ctx, backup, &RestoreAppOptions{DryRun: true})
+		require.NoError(t, err)
+		require.Empty(t, srv.requests)
+		require.Len(t, report.Created, 4)
+		require.True(t, report.DryRun)
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		c, srv := target()
+		report, err := c.RestoreApp(ctx, backup, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"POST /roles",
+			"POST /permissions",
+			"POST /commands",
+			"POST /channeltypes",
+		}, srv.requests)
+
+		var conflicts, skipped []string
+		for _, item := range report.Conflicts {
+			conflicts = append(conflicts, item.Kind+" "+item.Name)
+		}
+		for _, item := range report.Skipped {
+			skipped = append(skipped, item.Kind+" "+item.Name)
+		}
+		require.Equal(t, []string{
+			"channel_type messaging",
+			"app_settings app",
+			"event_hook webhook https://hooks.example.com/v2",
+		}, conflicts)
+		require.Equal(t, []string{
+			"app_settings apn_config",
+			"push_provider apn/ios",
+			"event_hook sqs https://sqs/q",
+		}, skipped)
+		require.Equal(t, 1, report.Unchanged)
+		require.Contains(t, report.String(), "! app_settings \"app\": differs from the backup")
+		require.NotContains(t, report.String(), "staging-key")
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		c, srv := target()
+		report, err := c.RestoreApp(ctx, backup, &RestoreAppOptions{Overwrite: true})
+		require.NoError(t, err)
+		require.Empty(t, report.Conflicts)
+		require.Equal(t, []string{
+			"POST /roles",
+			"POST /permissions",
+			"POST /commands",
+			"PUT /channeltypes/messaging",
+			"POST /channeltypes",
+			"PATCH /app",
+			"PATCH /app",
+		}, srv.requests)
+
+		require.Equal(t, float64(5000), srv.bodies[3]["max_message_length"])
+		require.Equal(t, map[string]interface{}{"image_moderation_enabled": true}, srv.bodies[5])
+		hooks := srv.bodies[6]["event_hooks"].([]interface{})
+		require.Len(t, hooks, 1)
+		require.Equal(t, "x1", hooks[0].(map[string]interface{})["id"])
+		require.Equal(t, true, hooks[0].(map[string]interface{})["enabled"])
+	})
+
+	t.Run("failed event hooks request", func(t *testing.T) {
+		c, srv := target()
+		srv.fail = map[string]bool{"PATCH /app": true}
+		srv.get["/app"].(map[string]interface{})["app"].(map[string]interface{})["image_moderation_enabled"] = true
+
+		report, err := c.RestoreApp(ctx, backup, &RestoreAppOptions{Overwrite: true})
+		require.Error(t, err)
+		for _, item := range append(report.Created, report.Updated...) {
+			require.NotEqual(t, "event_hook", item.Kind)
+		}
+	})
+
+	t.Run("unchanged push provider", func(t *testing.T) {
+		backup, err := source.BackupApp(ctx, nil)
+		require.NoError(t, err)
+
+		c, srv := target()
+		srv.get["/push_providers"] = map[string]interface{}{"push_providers": []map[string]interface{}{
+			{"type": "apn", "name": "ios", "apn_key_id": "K1", "disabled_at": "2024-01-02T03:04:05Z", "disabled_reason": "invalid credentials"},
+		}}
+
+		report, err := c.RestoreApp(ctx, backup, nil)
+		require.NoError(t, err)
+		for _, item := range append(report.Conflicts, report.Updated...) {
+			require.NotEqual(t, "push_provider", item.Kind)
+		}
+	})
+}
@@ -0,0 +1,399 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// readOnlyAppSettings are returned by GetAppSettings but can't be changed with UpdateAppSettings.
+// Channel configs are managed with the channel type APIs, see PlanChannelTypes.
+var readOnlyAppSettings = map[string]bool{
+	"name":                  true,
+	"organization":          true,
+	"suspended":             true,
+	"suspended_explanation": true,
+	"channel_configs":       true,
+	"push_notifications":    true,
+	"policies":              true,
+}
+
+// sensitiveAppSettings are the names of fields holding credentials, which are never printed.
+var sensitiveAppSettings = []string{"secret", "auth_key", "server_key", "credentials", "sqs_key", "sns_key"}
+
+func isSensitiveField(field string) bool {
+	name := field
+	if i := strings.LastIndex(field, "."); i >= 0 {
+		name = field[i+1:]
+	}
+	for _, s := range sensitiveAppSettings {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppSettingsDiff is the difference between two app settings. Field names are JSON paths
+// such as "file_upload_config.size_limit", "grants.user" or "event_hooks[id].enabled".
+type AppSettingsDiff struct {
+	Changes []*FieldChange `json:"changes"`
+}
+
+// Empty reports whether the settings are the same.
+func (d *AppSettingsDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// String formats the diff for humans, one line per changed field. Credentials are not printed.
+func (d *AppSettingsDiff) String() string {
+	if d.Empty() {
+		return "No differences.\n"
+	}
+
+	var sb strings.Builder
+	for _, c := range d.Redact().Changes {
+		fmt.Fprintf(&sb, "~ %s\n", c)
+	}
+	return sb.String()
+}
+
+// Redact returns a copy of the diff with the values of credential fields replaced.
+func (d *AppSettingsDiff) Redact() *AppSettingsDiff {
+	redacted := &AppSettingsDiff{Changes: make([]*FieldChange, 0, len(d.Changes))}
+	for _, c := range d.Changes {
+		if isSensitiveField(c.Field) {
+			c = &FieldChange{Field: c.Field, From: redactValue(c.From), To: redactValue(c.To)}
+		} else {
+			c = &FieldChange{Field: c.Field, From: redactNested(c.From), To: redactNested(c.To)}
+		}
+		redacted.Changes = append(redacted.Changes, c)
+	}
+	return redacted
+}
+
+// redactNested redacts the credential fields of objects, for example of a push config
+// which was added as a whole.
+func redactNested(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for k, item := range vv {
+			if isSensitiveField(k) {
+				out[k] = redactValue(item)
+			} else {
+				out[k] = redactNested(item)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(vv))
+		for i, item := range vv {
+			out[i] = redactNested(item)
+		}
+		return out
+	}
+	return v
+}
+
+func redactValue(v interface{}) interface{} {
+	if v == nil || v == "" {
+		return v
+	}
+	return "(sensitive)"
+}
+
+// DiffAppSettings compares two app settings snapshots, for example of two environments or of
+// the same app before and after a deploy. Creation and update times are ignored, lists of
+// strings are compared regardless of their order and event hooks are matched by ID.
+func DiffAppSettings(from, to *AppSettings) (*AppSettingsDiff, error) {
+	if from == nil || to == nil {
+		return nil, errors.New("app settings are nil")
+	}
+
+	a, err := toJSONMap(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toJSONMap(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &AppSettingsDiff{}
+	diffJSONValues("", a, b, &diff.Changes)
+	return diff, nil
+}
+
+func diffJSONValues(field string, a, b interface{}, changes *[]*FieldChange) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			keys := make(map[string]bool, len(av)+len(bv))
+			for k := range av {
+				keys[k] = true
+			}
+			for k := range bv {
+				keys[k] = true
+			}
+			for _, k := range sortedKeys(keys) {
+				if field != "" && (k == "created_at" || k == "updated_at") {
+					continue
+				}
+				diffJSONValues(joinField(field, k), av[k], bv[k], changes)
+			}
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			if field == "event_hooks" {
+				diffJSONListByID(field, av, bv, changes)
+				return
+			}
+			if a, b := stringSet(av), stringSet(bv); a != nil && b != nil {
+				if !reflect.DeepEqual(a, b) {
+					*changes = append(*changes, &FieldChange{Field: field, From: a, To: b})
+				}
+				return
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(stripTimestamps(a), stripTimestamps(b)) {
+		*changes = append(*changes, &FieldChange{Field: field, From: a, To: b})
+	}
+}
+
+// diffJSONListByID compares lists of objects with an "id", such as event hooks.
+func diffJSONListByID(field string, a, b []interface{}, changes *[]*FieldChange) {
+	index := func(list []interface{}) (map[string]interface{}, []string) {
+		m := make(map[string]interface{}, len(list))
+		var ids []string
+		for i, item := range list {
+			id := fmt.Sprintf("+%d", i)
+			if obj, ok := item.(map[string]interface{}); ok {
+				if s, _ := obj["id"].(string); s != "" {
+					id = s
+				}
+			}
+			m[id] = item
+			ids = append(ids, id)
+		}
+		return m, ids
+	}
+
+	am, aIDs := index(a)
+	bm, bIDs := index(b)
+	seen := make(map[string]bool)
+	for _, id := range append(aIDs, bIDs...) {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		itemField := fmt.Sprintf("%s[%s]", field, id)
+		av, aok := am[id]
+		bv, bok := bm[id]
+		if aok && bok {
+			diffJSONValues(itemField, av, bv, changes)
+		} else {
+			*changes = append(*changes, &FieldChange{Field: itemField, From: stripTimestamps(av), To: stripTimestamps(bv)})
+		}
+	}
+}
+
+func joinField(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// stringSet returns the sorted strings of list, or nil if it holds other values.
+func stringSet(list []interface{}) []string {
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil
+		}
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func stripTimestamps(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for k, item := range vv {
+			if k == "created_at" || k == "updated_at" {
+				continue
+			}
+			out[k] = stripTimestamps(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(vv))
+		for i, item := range vv {
+			out[i] = stripTimestamps(item)
+		}
+		return out
+	}
+	return v
+}
+
+// ParseAppSettings parses app settings in JSON or YAML. Both the settings and the response
+// of GetAppSettings, with the settings in an "app" field, are accepted.
+func ParseAppSettings(data []byte) (*AppSettings, error) {
+	data, err := specToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var wrapper struct {
+		App *AppSettings `json:"app"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err == nil && wrapper.App != nil {
+		return wrapper.App, nil
+	}
+
+	var settings AppSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// LoadAppSettings reads app settings from a JSON or YAML file, see ParseAppSettings.
+func LoadAppSettings(filename string) (*AppSettings, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAppSettings(data)
+}
+
+// AppSettingsPlan is the update needed for the live app to match the desired settings.
+type AppSettingsPlan struct {
+	AppSettingsDiff
+	// ReadOnly are the differences in settings that can't be updated through UpdateAppSettings.
+	ReadOnly []*FieldChange `json:"read_only,omitempty"`
+
+	update map[string]interface{}
+}
+
+// String formats the plan for humans. Credentials are not printed.
+func (p *AppSettingsPlan) String() string {
+	s := p.AppSettingsDiff.String()
+	if p.Empty() {
+		s = "No changes. App settings match the desired settings.\n"
+	}
+	if len(p.ReadOnly) > 0 {
+		s += "Read-only settings that differ and won't be changed:\n"
+		for _, c := range (&AppSettingsDiff{Changes: p.ReadOnly}).Redact().Changes {
+			s += fmt.Sprintf("  %s\n", c)
+		}
+	}
+	return s
+}
+
+// Update returns the minimal UpdateAppSettings request body of the plan: the top level
+// settings with differences, set to their desired value.
+func (p *AppSettingsPlan) Update() map[string]interface{} {
+	return copyMap(p.update)
+}
+
+// PlanAppSettings compares the desired settings with the live app. Only the settings set in
+// desired are compared, so a partial AppSettings, or a file read with LoadAppSettings, can be used.
+// Grants of roles which are left out are not changed.
+func (c *Client) PlanAppSettings(ctx context.Context, desired *AppSettings) (*AppSettingsPlan, error) {
+	if desired == nil {
+		return nil, errors.New("desired app settings are nil")
+	}
+
+	resp, err := c.GetAppSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.App == nil {
+		return nil, errors.New("unexpected error: app settings response is nil")
+	}
+	return planAppSettings(desired, resp.App)
+}
+
+func planAppSettings(desired, live *AppSettings) (*AppSettingsPlan, error) {
+	want, err := toJSONMap(desired)
+	if err != nil {
+		return nil, err
+	}
+	have, err := toJSONMap(live)
+	if err != nil {
+		return nil, err
+	}
+
+	// grants of roles left out of the desired settings are kept as they are
+	if wantGrants, ok := want["grants"].(map[string]interface{}); ok {
+		if haveGrants, ok := have["grants"].(map[string]interface{}); ok {
+			merged := copyMap(haveGrants)
+			for role, grants := range wantGrants {
+				merged[role] = grants
+			}
+			want["grants"] = merged
+		}
+	}
+
+	plan := &AppSettingsPlan{update: make(map[string]interface{})}
+	for _, key := range sortedKeys(want) {
+		if want[key] == nil {
+			continue
+		}
+
+		var changes []*FieldChange
+		diffJSONValues(key, have[key], want[key], &changes)
+		if len(changes) == 0 {
+			continue
+		}
+		if readOnlyAppSettings[key] {
+			if !isZeroJSONValue(want[key]) {
+				plan.ReadOnly = append(plan.ReadOnly, changes...)
+			}
+			continue
+		}
+		plan.Changes = append(plan.Changes, changes...)
+		plan.update[key] = want[key]
+	}
+	return plan, nil
+}
+
+// ApplyAppSettingsPlan sends the minimal update of the plan. Nothing is sent if the plan is empty.
+func (c *Client) ApplyAppSettingsPlan(ctx context.Context, plan *AppSettingsPlan) (*Response, error) {
+	if plan == nil {
+		return nil, errors.New("app settings plan is nil")
+	}
+	if len(plan.update) == 0 {
+		return &Response{}, nil
+	}
+
+	var resp Response
+	err := c.makeRequest(ctx, http.MethodPatch, "app", nil, plan.update, &resp)
+	return &resp, err
+}
+
+// ApplyAppSettings plans the desired settings and applies the differences. The plan is
+// returned even if applying it failed.
+func (c *Client) ApplyAppSettings(ctx context.Context, desired *AppSettings) (*AppSettingsPlan, error) {
+	plan, err := c.PlanAppSettings(ctx, desired)
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.ApplyAppSettingsPlan(ctx, plan)
+	return plan, err
+}
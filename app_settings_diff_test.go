@@ -0,0 +1,170 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func liveAppSettings() *AppSettings {
+	size := 1024
+	return &AppSettings{
+		Name:          "staging",
+		WebhookURL:    strPtr("https://hooks.example.com/stream"),
+		WebhookEvents: []string{"message.new", "message.updated"},
+		SqsSecret:     strPtr("old-secret"),
+		Grants: map[string][]string{
+			"user":  {"read-channel", "create-message"},
+			"admin": {"delete-channel"},
+		},
+		FileUploadConfig: &FileUploadConfig{BlockedFileExtensions: []string{".exe"}, SizeLimit: &size},
+		EventHooks: []EventHook{
+			{ID: "h1", HookType: WebhookHook, Enabled: true, WebhookURL: "https://a", CreatedAt: time.Now()},
+			{ID: "h2", HookType: SQSHook, Enabled: true, SQSQueueURL: "https://sqs"},
+		},
+		Policies: map[string][]Policy{"messaging": {{Name: "allow", Action: 1, CreatedAt: time.Now()}}},
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestDiffAppSettings(t *testing.T) {
+	from := liveAppSettings()
+	to := liveAppSettings()
+	to.Name = "production"
+	to.WebhookEvents = []string{"message.updated", "message.new"}
+	to.SqsSecret = strPtr("new-secret")
+	to.Grants["user"] = []string{"read-channel"}
+	to.FileUploadConfig.BlockedFileExtensions = []string{".exe", ".bat"}
+	to.EventHooks = []EventHook{
+		{ID: "h2", HookType: SQSHook, Enabled: false, SQSQueueURL: "https://sqs", CreatedAt: time.Now()},
+		{ID: "h1", HookType: WebhookHook, Enabled: true, WebhookURL: "https://a"},
+		{HookType: SNSHook, Enabled: true, SNSTopicARN: "arn"},
+	}
+	to.Policies["messaging"][0].CreatedAt = time.Now().Add(time.Hour)
+
+	diff, err := DiffAppSettings(from, to)
+	require.NoError(t, err)
+
+	fields := make([]string, 0, len(diff.Changes))
+	for _, c := range diff.Changes {
+		fields = append(fields, c.Field)
+	}
+	require.Equal(t, []string{
+		"event_hooks[h2].enabled",
+		"event_hooks[+2]",
+		"file_upload_config.blocked_file_extensions",
+		"grants.user",
+		"name",
+		"sqs_secret",
+	}, fields)
+	require.Equal(t, []string{"create-message", "read-channel"}, diff.Changes[3].From)
+
+	out := diff.String()
+	require.Contains(t, out, `~ name: "staging" => "production"`)
+	require.Contains(t, out, `~ sqs_secret: "(sensitive)" => "(sensitive)"`)
+	require.NotContains(t, out, "new-secret")
+	require.Equal(t, "new-secret", diff.Changes[5].To)
+
+	same, err := DiffAppSettings(liveAppSettings(), liveAppSettings())
+	require.NoError(t, err)
+	require.True(t, same.Empty())
+}
+
+func TestAppSettingsDiff_RedactsCredentials(t *testing.T) {
+	from := liveAppSettings()
+	from.APNConfig = &APNConfig{AuthKey: "old-apn-key", KeyID: "k1"}
+	to := liveAppSettings()
+	to.APNConfig = &APNConfig{AuthKey: "new-apn-key", KeyID: "k1"}
+	to.FirebaseConfig = &FirebaseConfigRequest{CredentialsJSON: `{"private_key": "firebase-key"}`}
+
+	diff, err := DiffAppSettings(from, to)
+	require.NoError(t, err)
+	require.Len(t, diff.Changes, 2)
+
+	out := diff.String()
+	require.Contains(t, out, `~ apn_config.auth_key: "(sensitive)" => "(sensitive)"`)
+	require.Contains(t, out, `"credentials_json":"(sensitive)"`)
+	for _, secret := range []string{"old-apn-key", "new-apn-key", "firebase-key"} {
+		require.NotContains(t, out, secret)
+	}
+
+	from.FirebaseConfig = &FirebaseConfigRequest{CredentialsJSON: "old"}
+	to.FirebaseConfig.CredentialsJSON = "new"
+	diff, err = DiffAppSettings(from, to)
+	require.NoError(t, err)
+	redacted := diff.Redact()
+	for _, c := range redacted.Changes {
+		require.Equal(t, "(sensitive)", c.To, c.Field)
+	}
+	require.Equal(t, "firebase_config.credentials_json", redacted.Changes[1].Field)
+}
+
+func TestParseAppSettings(t *testing.T) {
+	settings, err := ParseAppSettings([]byte(`{"app": {"name": "prod", "webhook_url": "https://x"}}`))
+	require.NoError(t, err)
+	require.Equal(t, "prod", settings.Name)
+
+	settings, err = ParseAppSettings([]byte("webhook_url: https://x\ngrants:\n  user: [read-channel]\n"))
+	require.NoError(t, err)
+	require.Equal(t, "https://x", *settings.WebhookURL)
+	require.Equal(t, []string{"read-channel"}, settings.Grants["user"])
+}
+
+func TestClient_PlanAndApplyAppSettings(t *testing.T) {
+	ctx := context.Background()
+
+	var patches []map[string]interface{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/app", r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"app": liveAppSettings()})
+		case http.MethodPatch:
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			patches = append(patches, body)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+
+	desired, err := ParseAppSettings([]byte(`
+name: renamed
+webhook_url: https://hooks.example.com/stream
+webhook_events: [message.updated, message.new, message.deleted]
+grants:
+  user: [read-channel, create-message]
+image_moderation_enabled: true
+`))
+	require.NoError(t, err)
+
+	plan, err := c.PlanAppSettings(ctx, desired)
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 2)
+	require.Equal(t, "image_moderation_enabled", plan.Changes[0].Field)
+	require.Equal(t, "webhook_events", plan.Changes[1].Field)
+	require.Len(t, plan.ReadOnly, 1)
+	require.Equal(t, "name", plan.ReadOnly[0].Field)
+	require.Contains(t, plan.String(), "Read-only settings that differ")
+
+	_, err = c.ApplyAppSettingsPlan(ctx, plan)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{
+		"image_moderation_enabled": true,
+		"webhook_events":           []interface{}{"message.updated", "message.new", "message.deleted"},
+	}}, patches)
+
+	t.Run("no changes", func(t *testing.T) {
+		patches = nil
+		desired := NewAppSettings().SetWebhookURL("https://hooks.example.com/stream")
+
+		plan, err := c.ApplyAppSettings(ctx, desired)
+		require.NoError(t, err)
+		require.True(t, plan.Empty())
+		require.Empty(t, patches)
+	})
+}
@@ -2,11 +2,13 @@ package stream_chat
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"path"
+	"sort"
 	"time"
 )
 
@@ -43,6 +45,239 @@ func (c *Client) GetTask(ctx context.Context, id string) (*TaskResponse, error)
 	return &task, err
 }
 
+// Done reports whether the task reached a final state.
+func (t *TaskResponse) Done() bool {
+	return t.Status == TaskStatusCompleted || t.Status == TaskStatusFailed
+}
+
+// DecodeResult decodes the untyped task result into v.
+func (t *TaskResponse) DecodeResult(v interface{}) error {
+	b, err := json.Marshal(t.Result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// ExportTaskResult is the result of a completed ExportChannels or ExportUsers task.
+type ExportTaskResult struct {
+	URL string `json:"url"`
+}
+
+// ExportResult returns the download URL of an export task.
+func (t *TaskResponse) ExportResult() (*ExportTaskResult, error) {
+	var res ExportTaskResult
+	if err := t.DecodeResult(&res); err != nil {
+		return nil, err
+	}
+	if res.URL == "" {
+		return nil, fmt.Errorf("task %s has no export url", t.TaskID)
+	}
+	return &res, nil
+}
+
+// TaskItemResult is the outcome for a single channel or user of a
+// DeleteChannels, DeleteUsers, DeactivateUsers or ReactivateUsers task.
+type TaskItemResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// OK reports whether the item was processed successfully.
+func (r TaskItemResult) OK() bool {
+	return r.Status == "ok"
+}
+
+// ItemResults returns the per item outcomes of the task, keyed by channel CID
+// or user ID. Entries of the result which are not item outcomes are skipped.
+func (t *TaskResponse) ItemResults() map[string]TaskItemResult {
+	items := make(map[string]TaskItemResult)
+	for id, v := range t.Result {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		status, ok := m["status"].(string)
+		if !ok {
+			continue
+		}
+		item := TaskItemResult{Status: status}
+		item.Error, _ = m["error"].(string)
+		items[id] = item
+	}
+	return items
+}
+
+// ChannelsBatchFailure groups the channels of a batch update that failed for the same reason.
+type ChannelsBatchFailure struct {
+	Reason string   `json:"reason"`
+	CIDs   []string `json:"cids"`
+}
+
+// ChannelsBatchTaskResult is the result of an UpdateChannelsBatch task.
+type ChannelsBatchTaskResult struct {
+	Operation            ChannelBatchOperation  `json:"operation"`
+	Status               string                 `json:"status"`
+	SuccessChannelsCount int                    `json:"success_channels_count"`
+	FailedChannels       []ChannelsBatchFailure `json:"failed_channels"`
+	BatchCreatedAt       *time.Time             `json:"batch_created_at,omitempty"`
+	FinishedAt           *time.Time             `json:"finished_at,omitempty"`
+}
+
+// FailedCIDs returns the CIDs of all channels the batch update failed for.
+func (r *ChannelsBatchTaskResult) FailedCIDs() []string {
+	var cids []string
+	for _, f := range r.FailedChannels {
+		cids = append(cids, f.CIDs...)
+	}
+	return cids
+}
+
+// ChannelsBatchResult decodes the result of an UpdateChannelsBatch task.
+func (t *TaskResponse) ChannelsBatchResult() (*ChannelsBatchTaskResult, error) {
+	var res ChannelsBatchTaskResult
+	if err := t.DecodeResult(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// FailedIDs returns the sorted channel CIDs or user IDs the task failed to process.
+func (t *TaskResponse) FailedIDs() []string {
+	var ids []string
+	for id, item := range t.ItemResults() {
+		if !item.OK() {
+			ids = append(ids, id)
+		}
+	}
+	if res, err := t.ChannelsBatchResult(); err == nil {
+		ids = append(ids, res.FailedCIDs()...)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// TaskErrorResult is the result of a failed task.
+type TaskErrorResult struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// ErrorResult returns the error details of a failed task, or nil if the task did not fail.
+func (t *TaskResponse) ErrorResult() *TaskErrorResult {
+	if t.Status != TaskStatusFailed {
+		return nil
+	}
+	res := &TaskErrorResult{}
+	res.Type, _ = t.Result["type"].(string)
+	res.Description, _ = t.Result["description"].(string)
+	return res
+}
+
+// TaskFailedError is returned by WaitForTask when the task ends in TaskStatusFailed.
+type TaskFailedError struct {
+	TaskID      string
+	Description string
+	Task        *TaskResponse
+}
+
+func (e *TaskFailedError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("task %s failed", e.TaskID)
+	}
+	return fmt.Sprintf("task %s failed: %s", e.TaskID, e.Description)
+}
+
+// WaitForTaskOptions configures the polling done by WaitForTask.
+type WaitForTaskOptions struct {
+	// InitialInterval is the delay between the first two polls. Defaults to 1 second.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between polls. Defaults to 30 seconds.
+	MaxInterval time.Duration
+	// Multiplier is applied to the delay after every poll. Defaults to 2.
+	Multiplier float64
+	// OnPoll is called with every task state received, if set.
+	OnPoll func(*TaskResponse)
+}
+
+const (
+	defaultTaskPollInterval    = time.Second
+	defaultTaskPollMaxInterval = 30 * time.Second
+	defaultTaskPollMultiplier  = 2
+)
+
+func (o *WaitForTaskOptions) withDefaults() WaitForTaskOptions {
+	var opts WaitForTaskOptions
+	if o != nil {
+		opts = *o
+	}
+	if opts.InitialInterval <= 0 {
+		opts.InitialInterval = defaultTaskPollInterval
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = defaultTaskPollMaxInterval
+	}
+	if opts.MaxInterval < opts.InitialInterval {
+		opts.MaxInterval = opts.InitialInterval
+	}
+	if opts.Multiplier < 1 {
+		opts.Multiplier = defaultTaskPollMultiplier
+	}
+	return opts
+}
+
+// WaitForTask polls GetTask with exponential backoff until the task is completed or failed.
+// Transient API errors (rate limits and server errors) are retried, the wait can be bounded with ctx.
+// If the task fails, the final task state is returned together with a *TaskFailedError.
+func (c *Client) WaitForTask(ctx context.Context, taskID string, opts *WaitForTaskOptions) (*TaskResponse, error) {
+	if taskID == "" {
+		return nil, errors.New("task ID must be not empty")
+	}
+
+	o := opts.withDefaults()
+	interval := o.InitialInterval
+
+	for {
+		task, err := c.GetTask(ctx, taskID)
+		switch {
+		case err != nil && !isTransientError(err):
+			return nil, err
+		case err == nil:
+			if o.OnPoll != nil {
+				o.OnPoll(task)
+			}
+			switch task.Status {
+			case TaskStatusCompleted:
+				return task, nil
+			case TaskStatusFailed:
+				taskErr := &TaskFailedError{TaskID: taskID, Task: task}
+				if res := task.ErrorResult(); res != nil {
+					taskErr.Description = res.Description
+				}
+				return task, taskErr
+			}
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		interval = time.Duration(float64(interval) * o.Multiplier)
+		if interval > o.MaxInterval {
+			interval = o.MaxInterval
+		}
+	}
+}
+
+// isTransientError reports whether err is an API error worth retrying.
+func isTransientError(err error) bool {
+	var apiErr Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+}
+
 type AsyncTaskResponse struct {
 	TaskID string `json:"task_id"`
 	Response
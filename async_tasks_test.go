@@ -2,6 +2,10 @@ package stream_chat
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -155,3 +159,131 @@ func TestClient_ExportUsers(t *testing.T) {
 		}
 	})
 }
+
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient("key", "secret")
+	require.NoError(t, err)
+	c.BaseURL = srv.URL
+	return c
+}
+
+func TestClient_WaitForTask(t *testing.T) {
+	ctx := context.Background()
+	opts := &WaitForTaskOptions{InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond}
+
+	t.Run("polls until completed", func(t *testing.T) {
+		var polls int32
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/tasks/task-1", r.URL.Path)
+			switch atomic.AddInt32(&polls, 1) {
+			case 1:
+				_, _ = w.Write([]byte(`{"task_id":"task-1","status":"waiting"}`))
+			case 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"code":-1,"message":"unavailable","StatusCode":503}`))
+			case 3:
+				_, _ = w.Write([]byte(`{"task_id":"task-1","status":"running"}`))
+			default:
+				_, _ = w.Write([]byte(`{"task_id":"task-1","status":"completed","result":{"url":"https://example.com/export.json"}}`))
+			}
+		}))
+
+		var seen []TaskStatus
+		o := *opts
+		o.OnPoll = func(task *TaskResponse) { seen = append(seen, task.Status) }
+
+		task, err := c.WaitForTask(ctx, "task-1", &o)
+		require.NoError(t, err)
+		require.Equal(t, TaskStatusCompleted, task.Status)
+		require.Equal(t, []TaskStatus{TaskStatusWaiting, TaskStatusRunning, TaskStatusCompleted}, seen)
+
+		res, err := task.ExportResult()
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/export.json", res.URL)
+	})
+
+	t.Run("returns failure reason", func(t *testing.T) {
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"task_id":"task-2","status":"failed","result":{"type":"error","description":"rate limit exceeded"}}`))
+		}))
+
+		task, err := c.WaitForTask(ctx, "task-2", opts)
+		var taskErr *TaskFailedError
+		require.ErrorAs(t, err, &taskErr)
+		require.Equal(t, "rate limit exceeded", taskErr.Description)
+		require.Equal(t, TaskStatusFailed, task.Status)
+		require.Equal(t, &TaskErrorResult{Type: "error", Description: "rate limit exceeded"}, task.ErrorResult())
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":16,"message":"task not found","StatusCode":404}`))
+		}))
+
+		_, err := c.WaitForTask(ctx, "missing", opts)
+		require.EqualError(t, err, "task not found")
+	})
+
+	t.Run("stops when context is done", func(t *testing.T) {
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"task_id":"task-3","status":"pending"}`))
+		}))
+
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		_, err := c.WaitForTask(ctx, "task-3", opts)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestTaskResponse_TypedResults(t *testing.T) {
+	t.Run("item results", func(t *testing.T) {
+		task := &TaskResponse{Result: map[string]interface{}{
+			"messaging:a": map[string]interface{}{"status": "ok"},
+			"messaging:b": map[string]interface{}{"status": "error", "error": "channel not found"},
+		}}
+
+		items := task.ItemResults()
+		require.True(t, items["messaging:a"].OK())
+		require.Equal(t, "channel not found", items["messaging:b"].Error)
+		require.Equal(t, []string{"messaging:b"}, task.FailedIDs())
+	})
+
+	t.Run("channels batch result", func(t *testing.T) {
+		var task TaskResponse
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"task_id": "t",
+			"status": "completed",
+			"result": {
+				"operation": "addMembers",
+				"status": "completed",
+				"success_channels_count": 3,
+				"failed_channels": [
+					{"reason": "user not found", "cids": ["team:x", "team:y"]}
+				],
+				"finished_at": "2025-12-13T10:35:00.512579053Z"
+			}
+		}`), &task))
+
+		res, err := task.ChannelsBatchResult()
+		require.NoError(t, err)
+		require.Equal(t, BatchUpdateOperationAddMembers, res.Operation)
+		require.Equal(t, 3, res.SuccessChannelsCount)
+		require.NotNil(t, res.FinishedAt)
+		require.Equal(t, []string{"team:x", "team:y"}, task.FailedIDs())
+		require.Nil(t, task.ErrorResult())
+	})
+
+	t.Run("missing export url", func(t *testing.T) {
+		task := &TaskResponse{TaskID: "t", Result: map[string]interface{}{}}
+		_, err := task.ExportResult()
+		require.Error(t, err)
+	})
+}
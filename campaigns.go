@@ -0,0 +1,667 @@
+package stream_chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+const (
+	maxCampaignSegments = 25
+	maxCampaignUsers    = 10000
+)
+
+// ErrCampaignStopped is returned by WaitForCampaign when the campaign was
+// stopped before it completed.
+var ErrCampaignStopped = errors.New("campaign stopped")
+
+// CampaignStatus is the status of a campaign.
+type CampaignStatus string
+
+const (
+	CampaignStatusDraft      CampaignStatus = "draft"
+	CampaignStatusScheduled  CampaignStatus = "scheduled"
+	CampaignStatusInProgress CampaignStatus = "in_progress"
+	CampaignStatusStopped    CampaignStatus = "stopped"
+	CampaignStatusCompleted  CampaignStatus = "completed"
+)
+
+// CampaignSenderMode controls how the sender is added to the target channels.
+// When empty, the sender is added to new channels only.
+type CampaignSenderMode string
+
+const (
+	// CampaignSenderExclude does not add the sender to any channel.
+	CampaignSenderExclude CampaignSenderMode = "exclude"
+	// CampaignSenderInclude adds the sender to all channels, new and existing.
+	CampaignSenderInclude CampaignSenderMode = "include"
+)
+
+// CampaignSenderVisibility controls the visibility of new channels for the
+// sender. When empty, the channels are visible.
+type CampaignSenderVisibility string
+
+const (
+	CampaignSenderHidden   CampaignSenderVisibility = "hidden"
+	CampaignSenderArchived CampaignSenderVisibility = "archived"
+)
+
+// CampaignMessageTemplate is the message sent by a campaign. Text and custom
+// values can use Jinja style variables such as {{ receiver.name }},
+// {{ sender.name }} or {{ channel.name }}.
+type CampaignMessageTemplate struct {
+	Text        string         `json:"text"`
+	Attachments []*Attachment  `json:"attachments,omitempty"`
+	PollID      string         `json:"poll_id,omitempty"`
+	Custom      map[string]any `json:"custom,omitempty"`
+}
+
+// CampaignChannelTemplate is used to find or create the channel a campaign
+// message is sent to, e.g. with the ID "{{ receiver.id }}-{{ sender.id }}".
+type CampaignChannelTemplate struct {
+	Type    string         `json:"type"`
+	ID      string         `json:"id,omitempty"`
+	Team    string         `json:"team,omitempty"`
+	Members []string       `json:"members,omitempty"`
+	Custom  map[string]any `json:"custom,omitempty"`
+}
+
+// CampaignStats are the delivery statistics of a campaign.
+type CampaignStats struct {
+	ProgressPercent      float64    `json:"stats_progress"`
+	UsersTargeted        int        `json:"stats_users_targeted"`
+	UsersSent            int        `json:"stats_users_sent"`
+	UsersRead            int        `json:"stats_users_read"`
+	MessagesSent         int        `json:"stats_messages_sent"`
+	ChannelsCreated      int        `json:"stats_channels_created"`
+	StartedAt            *time.Time `json:"stats_started_at,omitempty"`
+	CompletedAt          *time.Time `json:"stats_completed_at,omitempty"`
+	CompletedWithErrorAt *time.Time `json:"stats_completed_with_error_at,omitempty"`
+}
+
+// Campaign sends a templated message to the users of a list or of segments,
+// or to the channels of segments, on behalf of a sender.
+type Campaign struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// SegmentIDs or UserIDs select the targets of the campaign.
+	SegmentIDs []string `json:"segment_ids,omitempty"`
+	UserIDs    []string `json:"user_ids,omitempty"`
+
+	SenderID         string                   `json:"sender_id"`
+	SenderMode       CampaignSenderMode       `json:"sender_mode,omitempty"`
+	SenderVisibility CampaignSenderVisibility `json:"sender_visibility,omitempty"`
+
+	MessageTemplate *CampaignMessageTemplate `json:"message_template"`
+	ChannelTemplate *CampaignChannelTemplate `json:"channel_template,omitempty"`
+	CreateChannels  bool                     `json:"create_channels,omitempty"`
+	ShowChannels    bool                     `json:"show_channels,omitempty"`
+	SkipPush        bool                     `json:"skip_push,omitempty"`
+	SkipWebhook     bool                     `json:"skip_webhook,omitempty"`
+
+	Status       CampaignStatus `json:"status,omitempty"`
+	Sender       *User          `json:"sender,omitempty"`
+	Segments     []*Segment     `json:"segments,omitempty"`
+	Stats        *CampaignStats `json:"stats,omitempty"`
+	ScheduledFor *time.Time     `json:"scheduled_for,omitempty"`
+	StopAt       *time.Time     `json:"stop_at,omitempty"`
+	CreatedAt    *time.Time     `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time     `json:"updated_at,omitempty"`
+}
+
+func (c *Campaign) toRequest() campaignRequest {
+	return campaignRequest{
+		ID:               c.ID,
+		Name:             c.Name,
+		Description:      c.Description,
+		SegmentIDs:       c.SegmentIDs,
+		UserIDs:          c.UserIDs,
+		SenderID:         c.SenderID,
+		SenderMode:       c.SenderMode,
+		SenderVisibility: c.SenderVisibility,
+		MessageTemplate:  c.MessageTemplate,
+		ChannelTemplate:  c.ChannelTemplate,
+		CreateChannels:   c.CreateChannels,
+		ShowChannels:     c.ShowChannels,
+		SkipPush:         c.SkipPush,
+		SkipWebhook:      c.SkipWebhook,
+	}
+}
+
+func (c *Campaign) validate() error {
+	switch {
+	case c.SenderID == "":
+		return errors.New("sender ID must be not empty")
+	case c.MessageTemplate == nil:
+		return errors.New("message template must be not empty")
+	case len(c.SegmentIDs) > 0 && len(c.UserIDs) > 0:
+		return errors.New("segment IDs and user IDs cannot be used together")
+	case len(c.SegmentIDs) > maxCampaignSegments:
+		return fmt.Errorf("at most %d segment IDs are allowed", maxCampaignSegments)
+	case len(c.UserIDs) > maxCampaignUsers:
+		return fmt.Errorf("at most %d user IDs are allowed, create a segment for more", maxCampaignUsers)
+	case c.CreateChannels && c.ChannelTemplate == nil:
+		return errors.New("channel template must be not empty to create channels")
+	case c.ChannelTemplate != nil && c.ChannelTemplate.Type == "":
+		return errors.New("channel template type must be not empty")
+	}
+	return nil
+}
+
+// campaignRequest holds the fields of a Campaign accepted on create and update.
+type campaignRequest struct {
+	ID               string                   `json:"id,omitempty"`
+	Name             string                   `json:"name,omitempty"`
+	Description      string                   `json:"description,omitempty"`
+	SegmentIDs       []string                 `json:"segment_ids,omitempty"`
+	UserIDs          []string                 `json:"user_ids,omitempty"`
+	SenderID         string                   `json:"sender_id"`
+	SenderMode       CampaignSenderMode       `json:"sender_mode,omitempty"`
+	SenderVisibility CampaignSenderVisibility `json:"sender_visibility,omitempty"`
+	MessageTemplate  *CampaignMessageTemplate `json:"message_template"`
+	ChannelTemplate  *CampaignChannelTemplate `json:"channel_template,omitempty"`
+	CreateChannels   bool                     `json:"create_channels"`
+	ShowChannels     bool                     `json:"show_channels"`
+	SkipPush         bool                     `json:"skip_push"`
+	SkipWebhook      bool                     `json:"skip_webhook"`
+}
+
+type CampaignResponse struct {
+	Campaign *Campaign `json:"campaign"`
+	Response
+}
+
+// CreateCampaign creates a campaign in draft status. It is sent with StartCampaign
+// or ScheduleCampaign; a campaign can only be sent once.
+func (c *Client) CreateCampaign(ctx context.Context, campaign *Campaign) (*CampaignResponse, error) {
+	if campaign == nil {
+		return nil, errors.New("campaign is nil")
+	}
+	if err := campaign.validate(); err != nil {
+		return nil, err
+	}
+
+	var resp CampaignResponse
+	err := c.makeRequest(ctx, http.MethodPost, "campaigns", nil, campaign.toRequest(), &resp)
+	return &resp, err
+}
+
+// GetCampaign returns the campaign with the given ID, including its status and stats.
+func (c *Client) GetCampaign(ctx context.Context, campaignID string) (*CampaignResponse, error) {
+	if campaignID == "" {
+		return nil, errors.New("campaign ID must be not empty")
+	}
+
+	p := path.Join("campaigns", url.PathEscape(campaignID))
+
+	var resp CampaignResponse
+	err := c.makeRequest(ctx, http.MethodGet, p, nil, nil, &resp)
+	return &resp, err
+}
+
+// GetCampaignStats returns the delivery statistics of a campaign.
+func (c *Client) GetCampaignStats(ctx context.Context, campaignID string) (*CampaignStats, error) {
+	resp, err := c.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Campaign == nil || resp.Campaign.Stats == nil {
+		return &CampaignStats{}, nil
+	}
+	return resp.Campaign.Stats, nil
+}
+
+// UpdateCampaign fully updates a campaign which was not started yet.
+func (c *Client) UpdateCampaign(ctx context.Context, campaign *Campaign) (*CampaignResponse, error) {
+	if campaign == nil {
+		return nil, errors.New("campaign is nil")
+	}
+	if campaign.ID == "" {
+		return nil, errors.New("campaign ID must be not empty")
+	}
+	if err := campaign.validate(); err != nil {
+		return nil, err
+	}
+
+	p := path.Join("campaigns", url.PathEscape(campaign.ID))
+
+	var resp CampaignResponse
+	err := c.makeRequest(ctx, http.MethodPut, p, nil, campaign.toRequest(), &resp)
+	return &resp, err
+}
+
+// DeleteCampaign deletes a campaign.
+func (c *Client) DeleteCampaign(ctx context.Context, campaignID string) (*Response, error) {
+	if campaignID == "" {
+		return nil, errors.New("campaign ID must be not empty")
+	}
+
+	p := path.Join("campaigns", url.PathEscape(campaignID))
+
+	var resp Response
+	err := c.makeRequest(ctx, http.MethodDelete, p, nil, nil, &resp)
+	return &resp, err
+}
+
+// StartCampaign starts sending a campaign now. When stopAt is set, the
+// campaign is stopped at that time if it is still running.
+func (c *Client) StartCampaign(ctx context.Context, campaignID string, stopAt *time.Time) (*CampaignResponse, error) {
+	return c.startCampaign(ctx, campaignID, nil, stopAt)
+}
+
+// ScheduleCampaign schedules a campaign to start at the given time. When
+// stopAt is set, the campaign is stopped at that time if it is still running.
+func (c *Client) ScheduleCampaign(ctx context.Context, campaignID string, at time.Time, stopAt *time.Time) (*CampaignResponse, error) {
+	if at.IsZero() {
+		return nil, errors.New("scheduled time must be not empty")
+	}
+	if stopAt != nil && !stopAt.After(at) {
+		return nil, errors.New("stop time must be after the scheduled time")
+	}
+	return c.startCampaign(ctx, campaignID, &at, stopAt)
+}
+
+func (c *Client) startCampaign(ctx context.Context, campaignID string, scheduledFor, stopAt *time.Time) (*CampaignResponse, error) {
+	if campaignID == "" {
+		return nil, errors.New("campaign ID must be not empty")
+	}
+
+	data := map[string]interface{}{}
+	if scheduledFor != nil {
+		data["scheduled_for"] = scheduledFor.UTC().Format(time.RFC3339)
+	}
+	if stopAt != nil {
+		data["stop_at"] = stopAt.UTC().Format(time.RFC3339)
+	}
+
+	p := path.Join("campaigns", url.PathEscape(campaignID), "start")
+
+	var resp CampaignResponse
+	err := c.makeRequest(ctx, http.MethodPost, p, nil, data, &resp)
+	return &resp, err
+}
+
+// StopCampaign stops a scheduled or running campaign.
+func (c *Client) StopCampaign(ctx context.Context, campaignID string) (*CampaignResponse, error) {
+	if campaignID == "" {
+		return nil, errors.New("campaign ID must be not empty")
+	}
+
+	p := path.Join("campaigns", url.PathEscape(campaignID), "stop")
+
+	var resp CampaignResponse
+	err := c.makeRequest(ctx, http.MethodPost, p, nil, nil, &resp)
+	return &resp, err
+}
+
+// QueryCampaignsRequest represents the options for the QueryCampaigns request.
+type QueryCampaignsRequest struct {
+	// Filter on fields such as 'id', 'name', 'status', 'sender_id', 'segments' or 'created_at'.
+	Filter map[string]any `json:"filter,omitempty"`
+	Sort   []*SortOption  `json:"sort,omitempty"`
+
+	// Limit the number of campaigns returned.
+	Limit int `json:"limit,omitempty"`
+	// Pagination parameter. Pass the 'next' value from a previous response to continue from that point.
+	Next string `json:"next,omitempty"`
+	// Pagination parameter. Pass the 'prev' value from a previous response to continue from that point.
+	Prev string `json:"prev,omitempty"`
+}
+
+type QueryCampaignsResponse struct {
+	Campaigns []*Campaign `json:"campaigns"`
+	// Next is to be used as the 'next' parameter to get the next page.
+	Next *string `json:"next,omitempty"`
+	// Prev is to be used as the 'prev' parameter to get the previous page.
+	Prev *string `json:"prev,omitempty"`
+	Response
+}
+
+// QueryCampaigns returns the campaigns matching the request.
+func (c *Client) QueryCampaigns(ctx context.Context, q *QueryCampaignsRequest) (*QueryCampaignsResponse, error) {
+	if q == nil {
+		q = &QueryCampaignsRequest{}
+	}
+
+	var resp QueryCampaignsResponse
+	err := c.makeRequest(ctx, http.MethodPost, "campaigns/query", nil, q, &resp)
+	return &resp, err
+}
+
+// WaitForCampaignOptions configures the polling done by WaitForCampaign.
+type WaitForCampaignOptions struct {
+	// InitialInterval is the delay between the first two polls. Defaults to 1 second.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between polls. Defaults to 30 seconds.
+	MaxInterval time.Duration
+	// Multiplier is applied to the delay after every poll. Defaults to 2.
+	Multiplier float64
+	// OnPoll is called with every campaign state received, if set.
+	OnPoll func(*Campaign)
+}
+
+// WaitForCampaign polls GetCampaign with exponential backoff until the campaign is
+// completed or stopped. Transient API errors are retried, the wait can be bounded with ctx.
+// If the campaign was stopped, its final state is returned together with ErrCampaignStopped.
+// Waiting for a campaign which was not started fails immediately.
+func (c *Client) WaitForCampaign(ctx context.Context, campaignID string, opts *WaitForCampaignOptions) (*Campaign, error) {
+	if campaignID == "" {
+		return nil, errors.New("campaign ID must be not empty")
+	}
+
+	var o WaitForCampaignOptions
+	if opts != nil {
+		o = *opts
+	}
+	poll := (&WaitForTaskOptions{InitialInterval: o.InitialInterval, MaxInterval: o.MaxInterval, Multiplier: o.Multiplier}).withDefaults()
+	interval := poll.InitialInterval
+
+	for {
+		resp, err := c.GetCampaign(ctx, campaignID)
+		switch {
+		case err != nil && !isTransientError(err):
+			return nil, err
+		case err == nil:
+			campaign := resp.Campaign
+			if campaign == nil {
+				return nil, fmt.Errorf("campaign %s not returned", campaignID)
+			}
+			if o.OnPoll != nil {
+				o.OnPoll(campaign)
+			}
+			switch campaign.Status {
+			case CampaignStatusCompleted:
+				return campaign, nil
+			case CampaignStatusStopped:
+				return campaign, fmt.Errorf("campaign %s: %w", campaignID, ErrCampaignStopped)
+			case CampaignStatusDraft:
+				return campaign, fmt.Errorf("campaign %s is not started", campaignID)
+			}
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		interval = time.Duration(float64(interval) * poll.Multiplier)
+		if interval > poll.MaxInterval {
+			interval = poll.MaxInterval
+		}
+	}
+}
+
+// SegmentType is the kind of targets of a segment.
+type SegmentType string
+
+const (
+	SegmentTypeUser    SegmentType = "user"
+	SegmentTypeChannel SegmentType = "channel"
+)
+
+// Segment is a group of users or channels targeted by campaigns. Targets are
+// selected by a filter, by AllUsers or AllSenderChannels, or added manually.
+type Segment struct {
+	ID          string      `json:"id,omitempty"`
+	Type        SegmentType `json:"type"`
+	Name        string      `json:"name,omitempty"`
+	Description string      `json:"description,omitempty"`
+
+	Filter            map[string]any `json:"filter,omitempty"`
+	AllUsers          bool           `json:"all_users,omitempty"`
+	AllSenderChannels bool           `json:"all_sender_channels,omitempty"`
+
+	// Size is the number of targets. It is computed asynchronously for
+	// filters and AllUsers, and not at all for AllSenderChannels.
+	Size      int        `json:"size,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (s *Segment) validate() error {
+	selectors := 0
+	if s.Filter != nil {
+		selectors++
+	}
+	if s.AllUsers {
+		selectors++
+	}
+	if s.AllSenderChannels {
+		selectors++
+	}
+
+	switch {
+	case s.Type != SegmentTypeUser && s.Type != SegmentTypeChannel:
+		return fmt.Errorf("invalid segment type %q", s.Type)
+	case selectors > 1:
+		return errors.New("filter, all users and all sender channels are mutually exclusive")
+	case s.AllUsers && s.Type != SegmentTypeUser:
+		return errors.New("all users is only supported by user segments")
+	case s.AllSenderChannels && s.Type != SegmentTypeChannel:
+		return errors.New("all sender channels is only supported by channel segments")
+	}
+	return nil
+}
+
+// segmentRequest holds the fields of a Segment accepted on create and update.
+type segmentRequest struct {
+	ID                string         `json:"id,omitempty"`
+	Type              SegmentType    `json:"type,omitempty"`
+	Name              string         `json:"name,omitempty"`
+	Description       string         `json:"description,omitempty"`
+	Filter            map[string]any `json:"filter,omitempty"`
+	AllUsers          bool           `json:"all_users,omitempty"`
+	AllSenderChannels bool           `json:"all_sender_channels,omitempty"`
+}
+
+type SegmentResponse struct {
+	Segment *Segment `json:"segment"`
+	Response
+}
+
+// CreateSegment creates a user or channel segment.
+func (c *Client) CreateSegment(ctx context.Context, segment *Segment) (*SegmentResponse, error) {
+	if segment == nil {
+		return nil, errors.New("segment is nil")
+	}
+	if err := segment.validate(); err != nil {
+		return nil, err
+	}
+
+	req := segmentRequest{
+		ID: segment.ID, Type: segment.Type, Name: segment.Name, Description: segment.Description,
+		Filter: segment.Filter, AllUsers: segment.AllUsers, AllSenderChannels: segment.AllSenderChannels,
+	}
+
+	var resp SegmentResponse
+	err := c.makeRequest(ctx, http.MethodPost, "segments", nil, req, &resp)
+	return &resp, err
+}
+
+// GetSegment returns the segment with the given ID.
+func (c *Client) GetSegment(ctx context.Context, segmentID string) (*SegmentResponse, error) {
+	if segmentID == "" {
+		return nil, errors.New("segment ID must be not empty")
+	}
+
+	p := path.Join("segments", url.PathEscape(segmentID))
+
+	var resp SegmentResponse
+	err := c.makeRequest(ctx, http.MethodGet, p, nil, nil, &resp)
+	return &resp, err
+}
+
+// UpdateSegment updates the name, description and target selection of a segment.
+// The type of a segment cannot be changed.
+func (c *Client) UpdateSegment(ctx context.Context, segment *Segment) (*SegmentResponse, error) {
+	if segment == nil {
+		return nil, errors.New("segment is nil")
+	}
+	if segment.ID == "" {
+		return nil, errors.New("segment ID must be not empty")
+	}
+	if err := segment.validate(); err != nil {
+		return nil, err
+	}
+
+	req := segmentRequest{
+		Name: segment.Name, Description: segment.Description,
+		Filter: segment.Filter, AllUsers: segment.AllUsers, AllSenderChannels: segment.AllSenderChannels,
+	}
+	p := path.Join("segments", url.PathEscape(segment.ID))
+
+	var resp SegmentResponse
+	err := c.makeRequest(ctx, http.MethodPut, p, nil, req, &resp)
+	return &resp, err
+}
+
+// DeleteSegment deletes a segment.
+func (c *Client) DeleteSegment(ctx context.Context, segmentID string) (*Response, error) {
+	if segmentID == "" {
+		return nil, errors.New("segment ID must be not empty")
+	}
+
+	p := path.Join("segments", url.PathEscape(segmentID))
+
+	var resp Response
+	err := c.makeRequest(ctx, http.MethodDelete, p, nil, nil, &resp)
+	return &resp, err
+}
+
+// QuerySegmentsRequest represents the options for the QuerySegments request.
+type QuerySegmentsRequest struct {
+	// Filter on fields such as 'id', 'type', 'name' or 'created_at'.
+	Filter map[string]any `json:"filter,omitempty"`
+	Sort   []*SortOption  `json:"sort,omitempty"`
+
+	// Limit the number of segments returned.
+	Limit int `json:"limit,omitempty"`
+	// Pagination parameter. Pass the 'next' value from a previous response to continue from that point.
+	Next string `json:"next,omitempty"`
+	// Pagination parameter. Pass the 'prev' value from a previous response to continue from that point.
+	Prev string `json:"prev,omitempty"`
+}
+
+type QuerySegmentsResponse struct {
+	Segments []*Segment `json:"segments"`
+	// Next is to be used as the 'next' parameter to get the next page.
+	Next *string `json:"next,omitempty"`
+	// Prev is to be used as the 'prev' parameter to get the previous page.
+	Prev *string `json:"prev,omitempty"`
+	Response
+}
+
+// QuerySegments returns the segments matching the request.
+func (c *Client) QuerySegments(ctx context.Context, q *QuerySegmentsRequest) (*QuerySegmentsResponse, error) {
+	if q == nil {
+		q = &QuerySegmentsRequest{}
+	}
+
+	var resp QuerySegmentsResponse
+	err := c.makeRequest(ctx, http.MethodPost, "segments/query", nil, q, &resp)
+	return &resp, err
+}
+
+// maxSegmentTargetsPerRequest is the number of targets sent per add or remove request.
+const maxSegmentTargetsPerRequest = 10000
+
+func (c *Client) changeSegmentTargets(ctx context.Context, segmentID, action string, targetIDs []string) (*Response, error) {
+	switch {
+	case segmentID == "":
+		return nil, errors.New("segment ID must be not empty")
+	case len(targetIDs) == 0:
+		return nil, errors.New("target IDs must be not empty")
+	}
+
+	p := path.Join("segments", url.PathEscape(segmentID), action)
+
+	var resp Response
+	for _, batch := range batches(targetIDs, maxSegmentTargetsPerRequest) {
+		resp = Response{}
+		data := map[string]interface{}{"target_ids": batch}
+		if err := c.makeRequest(ctx, http.MethodPost, p, nil, data, &resp); err != nil {
+			return &resp, err
+		}
+	}
+	return &resp, nil
+}
+
+// AddSegmentTargets adds users or channels, by ID or CID, to a segment.
+// Large lists are sent in batches of 10,000.
+func (c *Client) AddSegmentTargets(ctx context.Context, segmentID string, targetIDs ...string) (*Response, error) {
+	return c.changeSegmentTargets(ctx, segmentID, "addtargets", targetIDs)
+}
+
+// RemoveSegmentTargets removes users or channels, by ID or CID, from a segment.
+// Large lists are sent in batches of 10,000.
+func (c *Client) RemoveSegmentTargets(ctx context.Context, segmentID string, targetIDs ...string) (*Response, error) {
+	return c.changeSegmentTargets(ctx, segmentID, "deletetargets", targetIDs)
+}
+
+// SegmentTargetExists reports whether the target is part of the segment.
+func (c *Client) SegmentTargetExists(ctx context.Context, segmentID, targetID string) (bool, error) {
+	switch {
+	case segmentID == "":
+		return false, errors.New("segment ID must be not empty")
+	case targetID == "":
+		return false, errors.New("target ID must be not empty")
+	}
+
+	p := path.Join("segments", url.PathEscape(segmentID), "target", url.PathEscape(targetID))
+
+	var resp Response
+	err := c.makeRequest(ctx, http.MethodGet, p, nil, nil, &resp)
+	var apiErr Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SegmentTarget is a user or channel of a segment.
+type SegmentTarget struct {
+	SegmentID string     `json:"segment_id"`
+	TargetID  string     `json:"target_id"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// QuerySegmentTargetsRequest represents the options for the QuerySegmentTargets request.
+type QuerySegmentTargetsRequest struct {
+	// Filter on fields such as 'target_id' or 'created_at'.
+	Filter map[string]any `json:"filter,omitempty"`
+	Sort   []*SortOption  `json:"sort,omitempty"`
+
+	// Limit the number of targets returned, up to 10,000.
+	Limit int `json:"limit,omitempty"`
+	// Pagination parameter. Pass the 'next' value from a previous response to continue from that point.
+	Next string `json:"next,omitempty"`
+}
+
+type QuerySegmentTargetsResponse struct {
+	Targets []*SegmentTarget `json:"targets"`
+	// Next is to be used as the 'next' parameter to get the next page.
+	Next *string `json:"next,omitempty"`
+	Response
+}
+
+// QuerySegmentTargets returns the targets of a segment, sorted by target ID.
+func (c *Client) QuerySegmentTargets(ctx context.Context, segmentID string, q *QuerySegmentTargetsRequest) (*QuerySegmentTargetsResponse, error) {
+	if segmentID == "" {
+		return nil, errors.New("segment ID must be not empty")
+	}
+	if q == nil {
+		q = &QuerySegmentTargetsRequest{}
+	}
+
+	p := path.Join("segments", url.PathEscape(segmentID), "targets", "query")
+
+	var resp QuerySegmentTargetsResponse
+	err := c.makeRequest(ctx, http.MethodPost, p, nil, q, &resp)
+	return &resp, err
+}
@@ -0,0 +1,131 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Campaigns(t *testing.T) {
+	ctx := context.Background()
+
+	bodies := make(map[string][]map[string]interface{})
+	statuses := []string{"scheduled", "in_progress", "completed"}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			require.NoError(t, json.Unmarshal(b, &body))
+		}
+		key := r.Method + " " + r.URL.Path
+		bodies[key] = append(bodies[key], body)
+
+		switch key {
+		case "POST /campaigns", "POST /campaigns/welcome/start":
+			_, _ = w.Write([]byte(`{"campaign": {"id": "welcome", "status": "draft", "sender_id": "admin"}}`))
+		case "GET /campaigns/welcome":
+			status := statuses[0]
+			statuses = statuses[1:]
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"campaign": map[string]interface{}{
+				"id": "welcome", "status": status,
+				"stats": map[string]interface{}{"stats_progress": 100, "stats_messages_sent": 3, "stats_users_sent": 3},
+			}})
+		case "GET /campaigns/stopped":
+			_, _ = w.Write([]byte(`{"campaign": {"id": "stopped", "status": "stopped"}}`))
+		case "POST /segments":
+			_, _ = w.Write([]byte(`{"segment": {"id": "usa", "type": "user", "filter": {"country": "US"}, "size": 0}}`))
+		case "GET /segments/usa/target/jane":
+			_, _ = w.Write([]byte(`{}`))
+		case "GET /segments/usa/target/bob":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code": 16, "message": "not found", "StatusCode": 404}`))
+		case "POST /segments/usa/targets/query":
+			_, _ = w.Write([]byte(`{"targets": [{"segment_id": "usa", "target_id": "jane"}], "next": "n1"}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+
+	seg, err := c.CreateSegment(ctx, &Segment{Type: SegmentTypeUser, Name: "USA", Filter: map[string]any{"country": "US"}})
+	require.NoError(t, err)
+	require.Equal(t, "usa", seg.Segment.ID)
+	require.Equal(t, map[string]interface{}{"type": "user", "name": "USA", "filter": map[string]interface{}{"country": "US"}}, bodies["POST /segments"][0])
+
+	targets := make([]string, maxSegmentTargetsPerRequest+1)
+	for i := range targets {
+		targets[i] = "user"
+	}
+	_, err = c.AddSegmentTargets(ctx, "usa", targets...)
+	require.NoError(t, err)
+	require.Len(t, bodies["POST /segments/usa/addtargets"], 2)
+	require.Len(t, bodies["POST /segments/usa/addtargets"][1]["target_ids"], 1)
+
+	_, err = c.RemoveSegmentTargets(ctx, "usa", "bob")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"bob"}, bodies["POST /segments/usa/deletetargets"][0]["target_ids"])
+
+	exists, err := c.SegmentTargetExists(ctx, "usa", "jane")
+	require.NoError(t, err)
+	require.True(t, exists)
+	exists, err = c.SegmentTargetExists(ctx, "usa", "bob")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	page, err := c.QuerySegmentTargets(ctx, "usa", &QuerySegmentTargetsRequest{Limit: 10000})
+	require.NoError(t, err)
+	require.Equal(t, "jane", page.Targets[0].TargetID)
+	require.Equal(t, "n1", *page.Next)
+
+	campaign := &Campaign{
+		ID:              "welcome",
+		SegmentIDs:      []string{"usa"},
+		SenderID:        "admin",
+		SenderMode:      CampaignSenderExclude,
+		MessageTemplate: &CampaignMessageTemplate{Text: "Hi {{ receiver.name }}"},
+		ChannelTemplate: &CampaignChannelTemplate{Type: "messaging", ID: "{{ receiver.id }}-{{ sender.id }}"},
+		CreateChannels:  true,
+		Status:          CampaignStatusCompleted,
+	}
+	_, err = c.CreateCampaign(ctx, campaign)
+	require.NoError(t, err)
+	body := bodies["POST /campaigns"][0]
+	require.Equal(t, "exclude", body["sender_mode"])
+	require.Equal(t, true, body["create_channels"])
+	require.NotContains(t, body, "status")
+
+	at := time.Date(2030, 1, 1, 9, 0, 0, 0, time.FixedZone("CET", 3600))
+	_, err = c.ScheduleCampaign(ctx, "welcome", at, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"scheduled_for": "2030-01-01T08:00:00Z"}, bodies["POST /campaigns/welcome/start"][0])
+
+	var polled []CampaignStatus
+	done, err := c.WaitForCampaign(ctx, "welcome", &WaitForCampaignOptions{
+		InitialInterval: time.Millisecond,
+		OnPoll:          func(c *Campaign) { polled = append(polled, c.Status) },
+	})
+	require.NoError(t, err)
+	require.Equal(t, []CampaignStatus{CampaignStatusScheduled, CampaignStatusInProgress, CampaignStatusCompleted}, polled)
+	require.Equal(t, 3, done.Stats.MessagesSent)
+	require.Equal(t, float64(100), done.Stats.ProgressPercent)
+
+	_, err = c.WaitForCampaign(ctx, "stopped", nil)
+	require.ErrorIs(t, err, ErrCampaignStopped)
+
+	for _, invalid := range []*Campaign{
+		{MessageTemplate: &CampaignMessageTemplate{Text: "hi"}},
+		{SenderID: "admin"},
+		{SenderID: "admin", MessageTemplate: &CampaignMessageTemplate{}, UserIDs: []string{"a"}, SegmentIDs: []string{"b"}},
+		{SenderID: "admin", MessageTemplate: &CampaignMessageTemplate{}, CreateChannels: true},
+	} {
+		_, err = c.CreateCampaign(ctx, invalid)
+		require.Error(t, err)
+	}
+	_, err = c.CreateSegment(ctx, &Segment{Type: SegmentTypeChannel, AllUsers: true})
+	require.Error(t, err)
+	_, err = c.CreateSegment(ctx, &Segment{Type: SegmentTypeUser, AllUsers: true, Filter: map[string]any{}})
+	require.Error(t, err)
+}
@@ -0,0 +1,504 @@
+package stream_chat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ChannelArchiveVersion is the version of the channel archive format written by ArchiveAndTruncate.
+const ChannelArchiveVersion = 1
+
+const (
+	channelArchiveDataFile     = "messages.jsonl"
+	channelArchiveManifestFile = "manifest.json"
+	defaultArchivePageSize     = 100
+)
+
+// ErrChannelArchiveCorrupted is returned when the content of an archive doesn't match its manifest.
+var ErrChannelArchiveCorrupted = errors.New("channel archive corrupted")
+
+// ArchiveStorage stores channel archives, for example on disk or in an object store.
+// Names are slash separated paths.
+type ArchiveStorage interface {
+	// Create returns a writer replacing the object name. The object must be complete
+	// once the writer is closed without error.
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+	// Open returns a reader of the object name.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// DirArchiveStorage is an ArchiveStorage keeping the archives in a directory.
+type DirArchiveStorage struct {
+	dir string
+}
+
+// NewDirArchiveStorage returns a storage writing the archives in dir, which is created if needed.
+func NewDirArchiveStorage(dir string) (*DirArchiveStorage, error) {
+	if dir == "" {
+		return nil, errors.New("directory must not be empty")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirArchiveStorage{dir: dir}, nil
+}
+
+func (s *DirArchiveStorage) path(name string) (string, error) {
+	p := filepath.FromSlash(name)
+	if !filepath.IsLocal(p) {
+		return "", fmt.Errorf("invalid archive object name %q", name)
+	}
+	return filepath.Join(s.dir, p), nil
+}
+
+// Create writes the object to a temporary file which is renamed when the writer is closed.
+func (s *DirArchiveStorage) Create(_ context.Context, name string) (io.WriteCloser, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &dirArchiveWriter{File: tmp, path: p}, nil
+}
+
+func (s *DirArchiveStorage) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+type dirArchiveWriter struct {
+	*os.File
+	path string
+}
+
+func (w *dirArchiveWriter) Close() error {
+	defer os.Remove(w.File.Name())
+
+	if err := w.File.Sync(); err != nil {
+		_ = w.File.Close()
+		return err
+	}
+	if err := w.File.Close(); err != nil {
+		return err
+	}
+	return os.Rename(w.File.Name(), w.path)
+}
+
+// ChannelArchiveManifest describes a channel archive. The archived messages are stored
+// in the import file format, next to the manifest.
+type ChannelArchiveManifest struct {
+	Version   int       `json:"version"`
+	CID       string    `json:"cid"`
+	CreatedAt time.Time `json:"created_at"`
+	// Before is the cut-off of the archive: messages created before it are archived,
+	// and the channel is truncated at this time.
+	Before time.Time `json:"before"`
+
+	// SHA256 and Size are the checksum and size of the import file.
+	SHA256 string                 `json:"sha256"`
+	Size   int64                  `json:"size"`
+	Counts map[ImportItemType]int `json:"counts"`
+
+	// UserIDs are the users referenced by the archive. They are not part of the
+	// archive and must exist when it is restored.
+	UserIDs []string `json:"user_ids"`
+	// ExternalMessageIDs are messages quoted by the archive which are not part of it.
+	ExternalMessageIDs []string `json:"external_message_ids,omitempty"`
+}
+
+// ArchiveChannelOptions configures Channel.ArchiveAndTruncate.
+type ArchiveChannelOptions struct {
+	// Name is the name of the archive in the storage.
+	// Defaults to "<channel type>/<channel ID>/<cut-off time>".
+	Name string
+	// Before is the cut-off of the archive. Defaults to now.
+	Before time.Time
+	// PageSize is the number of items read per request. Defaults to 100.
+	PageSize int
+	// SkipReactions leaves out the reactions. The archive fails on a message with more
+	// than 1000 reactions, which can't be read through the API.
+	SkipReactions bool
+
+	// SkipTruncate only writes and verifies the archive.
+	SkipTruncate bool
+	// HardDelete, SkipPush, Message and UserID are passed to Truncate.
+	HardDelete bool
+	SkipPush   bool
+	Message    *Message
+	UserID     string
+}
+
+// ArchiveAndTruncate moves the messages of the channel created before the cut-off to the
+// storage, then truncates the channel at the cut-off. The channel, its members, messages,
+// thread replies and reactions are written in the import format together with a manifest
+// holding the checksum of the file. The archive is read back and verified before the
+// channel is truncated, so nothing is removed unless the archive is complete.
+// Ephemeral and error messages are not archived.
+func (ch *Channel) ArchiveAndTruncate(ctx context.Context, storage ArchiveStorage, opts *ArchiveChannelOptions) (*ChannelArchiveManifest, error) {
+	if storage == nil {
+		return nil, errors.New("storage must be not nil")
+	}
+	if opts == nil {
+		opts = &ArchiveChannelOptions{}
+	}
+	o := *opts
+	if o.Before.IsZero() {
+		o.Before = time.Now()
+	}
+	o.Before = o.Before.UTC()
+	if o.PageSize <= 0 {
+		o.PageSize = defaultArchivePageSize
+	}
+	if o.Name == "" {
+		o.Name = path.Join(ch.Type, ch.ID, o.Before.Format("20060102T150405Z"))
+	}
+
+	manifest, err := ch.writeArchive(ctx, storage, &o)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := VerifyChannelArchive(ctx, storage, o.Name); err != nil {
+		return manifest, err
+	}
+	if o.SkipTruncate {
+		return manifest, nil
+	}
+
+	options := []TruncateOption{TruncateWithTruncatedAt(&o.Before)}
+	if o.HardDelete {
+		options = append(options, TruncateWithHardDelete())
+	}
+	if o.SkipPush {
+		options = append(options, TruncateWithSkipPush())
+	}
+	if o.Message != nil {
+		options = append(options, TruncateWithMessage(o.Message))
+	}
+	if o.UserID != "" {
+		options = append(options, TruncateWithUserID(o.UserID))
+	}
+	if _, err := ch.Truncate(ctx, options...); err != nil {
+		return manifest, fmt.Errorf("truncate channel: %w", err)
+	}
+	return manifest, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += int64(len(b))
+	return len(b), nil
+}
+
+type channelArchiver struct {
+	ch   *Channel
+	opts *ArchiveChannelOptions
+	iw   *ImportWriter
+
+	users     map[string]bool
+	messages  map[string]bool
+	quoted    map[string]bool
+	reactions []*Reaction
+	// reactionTimes are the creation times of the reacted messages, used for reactions without one.
+	reactionTimes []time.Time
+}
+
+func (ch *Channel) writeArchive(ctx context.Context, storage ArchiveStorage, o *ArchiveChannelOptions) (*ChannelArchiveManifest, error) {
+	if _, err := ch.Query(ctx, &QueryRequest{State: true}); err != nil {
+		return nil, fmt.Errorf("query channel: %w", err)
+	}
+	members, err := ch.queryAllMembers(ctx, o.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := storage.Create(ctx, path.Join(o.Name, channelArchiveDataFile))
+	if err != nil {
+		return nil, fmt.Errorf("create archive: %w", err)
+	}
+	sum, size := sha256.New(), &countingWriter{}
+	a := &channelArchiver{
+		ch:       ch,
+		opts:     o,
+		iw:       NewImportWriter(io.MultiWriter(w, sum, size)),
+		users:    make(map[string]bool),
+		messages: make(map[string]bool),
+		quoted:   make(map[string]bool),
+	}
+
+	err = a.write(ctx, members)
+	if err == nil {
+		err = a.iw.Flush()
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("write archive: %w", err)
+	}
+
+	manifest := &ChannelArchiveManifest{
+		Version:   ChannelArchiveVersion,
+		CID:       ch.cid(),
+		CreatedAt: time.Now().UTC(),
+		Before:    o.Before,
+		SHA256:    hex.EncodeToString(sum.Sum(nil)),
+		Size:      size.n,
+		Counts:    a.iw.Counts(),
+		UserIDs:   sortedKeys(a.users),
+	}
+	for id := range a.quoted {
+		if !a.messages[id] {
+			manifest.ExternalMessageIDs = append(manifest.ExternalMessageIDs, id)
+		}
+	}
+	manifest.ExternalMessageIDs = sortedStrings(manifest.ExternalMessageIDs)
+
+	// the manifest is written last: an archive without manifest is incomplete
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	mw, err := storage.Create(ctx, path.Join(o.Name, channelArchiveManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("create archive manifest: %w", err)
+	}
+	_, err = mw.Write(b)
+	if closeErr := mw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("write archive manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func (a *channelArchiver) addUser(id string) {
+	if id != "" {
+		a.users[id] = true
+	}
+}
+
+func (a *channelArchiver) write(ctx context.Context, members []*ChannelMember) error {
+	if a.ch.CreatedBy != nil {
+		a.addUser(a.ch.CreatedBy.ID)
+	}
+	if err := a.iw.WriteChannel(a.ch); err != nil {
+		return err
+	}
+	cid := a.ch.cid()
+	for _, m := range members {
+		a.addUser(memberUserID(m))
+		if err := a.iw.WriteMember(cid, m); err != nil {
+			return err
+		}
+	}
+
+	var cursor time.Time
+	for {
+		resp, err := a.ch.Query(ctx, &QueryRequest{State: true, Messages: messagesFrom(cursor, a.opts.PageSize)})
+		if err != nil {
+			return fmt.Errorf("query messages: %w", err)
+		}
+
+		advanced, done := false, false
+		for _, m := range resp.Messages {
+			if !messageTime(m).Before(a.opts.Before) {
+				done = true
+				break
+			}
+			if messageTime(m).After(cursor) {
+				advanced = true
+				cursor = messageTime(m)
+			}
+			if m.ParentID != "" || a.messages[m.ID] {
+				continue
+			}
+			if err := a.writeMessage(ctx, m); err != nil {
+				return err
+			}
+			if m.ReplyCount > 0 {
+				if err := a.writeReplies(ctx, m.ID); err != nil {
+					return err
+				}
+			}
+		}
+		if done || len(resp.Messages) < a.opts.PageSize {
+			break
+		}
+		if !advanced {
+			// the rest of the messages would be truncated without being archived
+			return errMessagePageNotAdvanced(cursor, a.opts.PageSize)
+		}
+	}
+
+	// the import format requires all messages before the reactions
+	for i, r := range a.reactions {
+		createdAt := time.Time{}
+		if _, ok := r.ExtraData["created_at"]; !ok {
+			createdAt = a.reactionTimes[i]
+		}
+		if err := a.iw.WriteReaction(r, createdAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *channelArchiver) writeReplies(ctx context.Context, parentID string) error {
+	return eachReply(ctx, a.ch, parentID, a.opts.PageSize, func(m *Message) error {
+		if a.messages[m.ID] || !messageTime(m).Before(a.opts.Before) {
+			return nil
+		}
+		return a.writeMessage(ctx, m)
+	})
+}
+
+func (a *channelArchiver) writeMessage(ctx context.Context, m *Message) error {
+	if m.Type == MessageTypeEphemeral || m.Type == MessageTypeError {
+		return nil
+	}
+
+	if m.User != nil {
+		a.addUser(m.User.ID)
+	}
+	a.addUser(m.UserID)
+	for _, u := range m.MentionedUsers {
+		a.addUser(u.ID)
+	}
+	if m.PinnedBy != nil {
+		a.addUser(m.PinnedBy.ID)
+	}
+	if m.QuotedMessageID != "" {
+		a.quoted[m.QuotedMessageID] = true
+	}
+
+	if err := a.iw.WriteMessage(a.ch.cid(), m); err != nil {
+		return err
+	}
+	a.messages[m.ID] = true
+
+	if a.opts.SkipReactions || len(m.ReactionCounts) == 0 {
+		return nil
+	}
+	for offset := 0; ; offset += a.opts.PageSize {
+		if offset > maxReactionsOffset {
+			return errTooManyReactions(m.ID)
+		}
+		params := map[string][]string{"limit": {strconv.Itoa(a.opts.PageSize)}, "offset": {strconv.Itoa(offset)}}
+		resp, err := a.ch.client.GetReactions(ctx, m.ID, params)
+		if err != nil {
+			return fmt.Errorf("get reactions of %s: %w", m.ID, err)
+		}
+		for _, r := range resp.Reactions {
+			r.MessageID = m.ID
+			a.addUser(r.UserID)
+			a.reactions = append(a.reactions, r)
+			a.reactionTimes = append(a.reactionTimes, messageTime(m))
+		}
+		if len(resp.Reactions) < a.opts.PageSize {
+			return nil
+		}
+	}
+}
+
+// ReadChannelArchiveManifest reads the manifest of the archive name.
+func ReadChannelArchiveManifest(ctx context.Context, storage ArchiveStorage, name string) (*ChannelArchiveManifest, error) {
+	r, err := storage.Open(ctx, path.Join(name, channelArchiveManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("open archive manifest: %w", err)
+	}
+	defer r.Close()
+
+	var manifest ChannelArchiveManifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("cannot decode archive manifest: %w", err)
+	}
+	if manifest.Version != ChannelArchiveVersion {
+		return nil, fmt.Errorf("unsupported channel archive version %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// VerifyChannelArchive reads the archive name back and checks its size, checksum and item
+// counts against the manifest, and that it is a valid import file. Mismatches are reported
+// with ErrChannelArchiveCorrupted.
+func VerifyChannelArchive(ctx context.Context, storage ArchiveStorage, name string) (*ChannelArchiveManifest, error) {
+	manifest, err := ReadChannelArchiveManifest(ctx, storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := storage.Open(ctx, path.Join(name, channelArchiveDataFile))
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer r.Close()
+
+	sum, size := sha256.New(), &countingWriter{}
+	tee := io.TeeReader(r, io.MultiWriter(sum, size))
+	report, err := ValidateImport(tee, &ImportValidationOptions{
+		ExistingUserIDs:    manifest.UserIDs,
+		ExistingMessageIDs: manifest.ExternalMessageIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+
+	switch {
+	case size.n != manifest.Size:
+		return nil, fmt.Errorf("%w: size is %d, expected %d", ErrChannelArchiveCorrupted, size.n, manifest.Size)
+	case hex.EncodeToString(sum.Sum(nil)) != manifest.SHA256:
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrChannelArchiveCorrupted)
+	case !report.Valid():
+		return nil, fmt.Errorf("%w: %w", ErrChannelArchiveCorrupted, report.Err())
+	}
+	for typ, n := range manifest.Counts {
+		if report.Counts[typ] != n {
+			return nil, fmt.Errorf("%w: %d %s items, expected %d", ErrChannelArchiveCorrupted, report.Counts[typ], typ, n)
+		}
+	}
+	return manifest, nil
+}
+
+// RestoreChannelArchive verifies the archive name and imports it with RunImport.
+// The users listed in the manifest must exist in the app.
+func (c *Client) RestoreChannelArchive(ctx context.Context, storage ArchiveStorage, name string, mode ImportMode, options ...RunImportOption) (*ImportSummary, error) {
+	manifest, err := VerifyChannelArchive(ctx, storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := storage.Open(ctx, path.Join(name, channelArchiveDataFile))
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer r.Close()
+
+	options = append([]RunImportOption{WithImportSize(manifest.Size), WithImportFilename(path.Base(name) + ".json")}, options...)
+	return c.RunImport(ctx, r, mode, options...)
+}
@@ -0,0 +1,188 @@
+package stream_chat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannel_ArchiveAndTruncate(t *testing.T) {
+	ctx := context.Background()
+
+	messages := []map[string]interface{}{
+		{"id": "m1", "text": "hello", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:00:00Z",
+			"reply_count": 3, "reaction_counts": map[string]int{"like": 1}},
+		{"id": "m2", "text": "hi", "user": map[string]interface{}{"id": "bob"}, "created_at": "2024-01-01T10:01:00Z", "quoted_message_id": "old",
+			"pinned": true, "pinned_at": "2024-01-01T10:05:00Z", "pinned_by": map[string]interface{}{"id": "jane"}},
+		{"id": "m3", "type": "ephemeral", "text": "/giphy", "user": map[string]interface{}{"id": "bob"}, "created_at": "2024-01-01T10:02:00Z"},
+		{"id": "m4", "text": "recent", "user": map[string]interface{}{"id": "bob"}, "created_at": "2024-02-01T10:00:00Z"},
+	}
+	var truncates []map[string]interface{}
+	imports := &fakeImportServer{t: t, states: []string{"completed"}}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/channels/messaging/general/query":
+			var q QueryRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			var page []map[string]interface{}
+			for _, m := range messages {
+				createdAt, _ := time.Parse(time.RFC3339, m["created_at"].(string))
+				if q.Messages != nil && len(page) < q.Messages.Limit && !createdAt.Before(*q.Messages.CreatedAtAfterEq) {
+					page = append(page, m)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"channel":  map[string]interface{}{"type": "messaging", "id": "general", "cid": "messaging:general", "created_by": map[string]interface{}{"id": "jane"}, "name": "General"},
+				"messages": page,
+			})
+		case "/members":
+			members := []map[string]interface{}{
+				{"user_id": "jane", "created_at": "2023-12-01T10:00:00Z"},
+				{"user_id": "bob", "created_at": "2023-12-02T10:00:00Z"},
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"members": pageMembers(t, r, members)})
+		case "/messages/m1/replies":
+			// a thread longer than a page: the replies before the latest page must be archived too
+			replies := []map[string]interface{}{
+				{"id": "r0", "parent_id": "m1", "text": "first", "user": map[string]interface{}{"id": "carl"}, "created_at": "2024-01-01T10:02:30Z"},
+				{"id": "r1", "parent_id": "m1", "text": "thread", "user": map[string]interface{}{"id": "carl"}, "created_at": "2024-01-01T10:03:00Z"},
+				{"id": "r2", "parent_id": "m1", "text": "late reply", "user": map[string]interface{}{"id": "carl"}, "created_at": "2024-02-01T11:00:00Z"},
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageReplies(r.URL.Query(), replies)})
+		case "/messages/m1/reactions":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"reactions": []map[string]interface{}{{"type": "like", "user_id": "dave"}}})
+		case "/channels/messaging/general/truncate":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			truncates = append(truncates, body)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			imports.ServeHTTP(w, r)
+		}
+	}))
+	ch := c.Channel("messaging", "general")
+
+	dir := t.TempDir()
+	storage, err := NewDirArchiveStorage(dir)
+	require.NoError(t, err)
+	before := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	manifest, err := ch.ArchiveAndTruncate(ctx, storage, &ArchiveChannelOptions{Before: before, PageSize: 2, HardDelete: true})
+	require.NoError(t, err)
+	require.Equal(t, "messaging:general", manifest.CID)
+	require.Equal(t, map[ImportItemType]int{ImportItemChannel: 1, ImportItemMember: 2, ImportItemMessage: 4, ImportItemReaction: 1}, manifest.Counts)
+	require.Equal(t, []string{"bob", "carl", "dave", "jane"}, manifest.UserIDs)
+	require.Equal(t, []string{"old"}, manifest.ExternalMessageIDs)
+
+	f, err := os.Open(filepath.Join(dir, "messaging", "general", "20240115T000000Z", "messages.jsonl"))
+	require.NoError(t, err)
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var item importItem
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &item))
+		if item.Type == ImportItemMessage {
+			ids = append(ids, item.Data["id"].(string))
+		}
+		if item.Type == ImportItemReaction {
+			require.Equal(t, "2024-01-01T10:00:00Z", item.Data["created_at"])
+		}
+	}
+	require.NoError(t, f.Close())
+	require.Equal(t, []string{"m1", "r0", "r1", "m2"}, ids)
+
+	require.Len(t, truncates, 1)
+	require.Equal(t, "2024-01-15T00:00:00Z", truncates[0]["truncated_at"])
+	require.Equal(t, true, truncates[0]["hard_delete"])
+
+	name := "messaging/general/20240115T000000Z"
+	var uploaded string
+	summary, err := c.RestoreChannelArchive(ctx, storage, name, InsertMode,
+		WithImportPolling(WaitForTaskOptions{InitialInterval: time.Millisecond}),
+		WithImportUploader(func(_ context.Context, _ string, body io.Reader, size int64) error {
+			b, err := io.ReadAll(body)
+			uploaded = string(b)
+			require.Equal(t, manifest.Size, size)
+			return err
+		}))
+	require.NoError(t, err)
+	require.Equal(t, ImportStateCompleted, summary.Task.State)
+	require.Equal(t, int64(len(uploaded)), manifest.Size)
+
+	// tampering with the archive is detected before it is imported
+	data := filepath.Join(dir, "messaging", "general", "20240115T000000Z", "messages.jsonl")
+	b, err := os.ReadFile(data)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(data, []byte(strings.Replace(string(b), "hello", "HELLO", 1)), 0o600))
+	_, err = VerifyChannelArchive(ctx, storage, name)
+	require.ErrorIs(t, err, ErrChannelArchiveCorrupted)
+	_, err = c.RestoreChannelArchive(ctx, storage, name, InsertMode)
+	require.ErrorIs(t, err, ErrChannelArchiveCorrupted)
+
+	_, err = storage.Create(ctx, "../outside")
+	require.Error(t, err)
+
+	// messages which can't be paged through are not truncated
+	messages = []map[string]interface{}{
+		{"id": "s1", "text": "a", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:00:00Z"},
+		{"id": "s2", "text": "b", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:00:00Z"},
+		{"id": "s3", "text": "c", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:00:00Z"},
+	}
+	storage, err = NewDirArchiveStorage(t.TempDir())
+	require.NoError(t, err)
+	_, err = ch.ArchiveAndTruncate(ctx, storage, &ArchiveChannelOptions{Before: before, PageSize: 2})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than 2 messages were created at 2024-01-01T10:00:00Z")
+	require.Len(t, truncates, 1)
+}
+
+func TestChannel_ArchiveAndTruncate_TooManyReactions(t *testing.T) {
+	var truncates int
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/channels/messaging/general/query":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"channel": map[string]interface{}{"type": "messaging", "id": "general", "cid": "messaging:general"},
+				"messages": []map[string]interface{}{
+					{"id": "m1", "text": "hello", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:00:00Z",
+						"reaction_counts": map[string]int{"like": 2000}},
+				},
+			})
+		case "/members":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"members": pageMembers(t, r, nil)})
+		case "/messages/m1/reactions":
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			if offset > maxReactionsOffset {
+				t.Errorf("reactions offset %d is over the API limit", offset)
+			}
+			reactions := make([]map[string]interface{}, 300)
+			for i := range reactions {
+				reactions[i] = map[string]interface{}{"type": "like", "user_id": "bob"}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"reactions": reactions})
+		case "/channels/messaging/general/truncate":
+			truncates++
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+
+	storage, err := NewDirArchiveStorage(t.TempDir())
+	require.NoError(t, err)
+	_, err = c.Channel("messaging", "general").ArchiveAndTruncate(context.Background(), storage,
+		&ArchiveChannelOptions{Before: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), PageSize: 300})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "message m1 has more than 1000 reactions")
+	require.Zero(t, truncates)
+}
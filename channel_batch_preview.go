@@ -0,0 +1,393 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	defaultBatchPreviewPageSize = 30
+	batchPreviewMemberLimit     = 100
+)
+
+// ErrChannelBatchNotConfirmed is returned by ChannelBatchUpdater.Execute when the preview
+// was not confirmed.
+var ErrChannelBatchNotConfirmed = errors.New("channel batch update was not confirmed")
+
+// errBatchPreviewTruncated stops reading channels once MaxChannels were previewed.
+var errBatchPreviewTruncated = errors.New("channel batch preview truncated")
+
+// ChannelBatchPreviewOptions configures ChannelBatchUpdater.Preview.
+type ChannelBatchPreviewOptions struct {
+	// PageSize is the number of channels read per QueryChannels request. Defaults to 30.
+	PageSize int
+	// MaxChannels stops the preview after this many channels, 0 means no limit.
+	MaxChannels int
+}
+
+// ChannelBatchEffect is the expected effect of a batch update on a single channel.
+type ChannelBatchEffect struct {
+	CID string `json:"cid"`
+	// Changes describe the changes the update makes, one per member or field.
+	Changes []string `json:"changes,omitempty"`
+}
+
+// ChannelBatchPreview lists the channels a batch update applies to and its effect on each
+// of them, computed from the current state of the channels before anything is written.
+type ChannelBatchPreview struct {
+	Operation ChannelBatchOperation `json:"operation"`
+	Channels  []*ChannelBatchEffect `json:"channels"`
+	// Truncated is true if more channels match than ChannelBatchPreviewOptions.MaxChannels.
+	Truncated bool `json:"truncated"`
+}
+
+// CIDs returns the CIDs of all the channels matching the filter.
+func (p *ChannelBatchPreview) CIDs() []string {
+	cids := make([]string, 0, len(p.Channels))
+	for _, c := range p.Channels {
+		cids = append(cids, c.CID)
+	}
+	return cids
+}
+
+// Affected returns the CIDs of the channels which the update changes.
+func (p *ChannelBatchPreview) Affected() []string {
+	var cids []string
+	for _, c := range p.Channels {
+		if len(c.Changes) > 0 {
+			cids = append(cids, c.CID)
+		}
+	}
+	return cids
+}
+
+// String formats the preview for humans, one line per channel and change.
+func (p *ChannelBatchPreview) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s on %d channels, %d affected", p.Operation, len(p.Channels), len(p.Affected()))
+	if p.Truncated {
+		sb.WriteString(" (truncated, more channels match)")
+	}
+	sb.WriteString("\n")
+	for _, c := range p.Channels {
+		if len(c.Changes) == 0 {
+			fmt.Fprintf(&sb, "  %s: no changes\n", c.CID)
+			continue
+		}
+		fmt.Fprintf(&sb, "  %s:\n", c.CID)
+		for _, change := range c.Changes {
+			fmt.Fprintf(&sb, "    %s\n", change)
+		}
+	}
+	return sb.String()
+}
+
+// Preview resolves the filter of a batch update to the matching channels with QueryChannels
+// and computes the effect of the operation on each of them. Nothing is changed.
+func (u *ChannelBatchUpdater) Preview(ctx context.Context, options *ChannelsBatchOptions, opts *ChannelBatchPreviewOptions) (*ChannelBatchPreview, error) {
+	if options == nil {
+		return nil, errors.New("options must not be nil")
+	}
+	if opts == nil {
+		opts = &ChannelBatchPreviewOptions{}
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultBatchPreviewPageSize
+	}
+
+	members, err := batchMembers(options.Members)
+	if err != nil {
+		return nil, err
+	}
+	filter := batchFiltersToQuery(options.Filter)
+	if len(filter) == 0 {
+		return nil, errors.New("filter must not be empty")
+	}
+
+	preview := &ChannelBatchPreview{Operation: options.Operation}
+	memberLimit := batchPreviewMemberLimit
+	q := QueryOption{Filter: filter, Limit: pageSize, MemberLimit: &memberLimit}
+	err = u.client.queryChannelsInOrder(ctx, q, func(ch *Channel) error {
+		if opts.MaxChannels > 0 && len(preview.Channels) == opts.MaxChannels {
+			preview.Truncated = true
+			return errBatchPreviewTruncated
+		}
+		effect, err := u.previewChannel(ctx, ch, options, members)
+		if err != nil {
+			return fmt.Errorf("channel %s: %w", ch.cid(), err)
+		}
+		preview.Channels = append(preview.Channels, effect)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchPreviewTruncated) {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// batchFiltersToQuery returns the QueryChannels filter equivalent to filter. Lists of
+// values are matched with $in, other values are used as they are.
+func batchFiltersToQuery(filter ChannelsBatchFilters) map[string]interface{} {
+	q := make(map[string]interface{})
+	for field, v := range map[string]interface{}{"cid": filter.CIDs, "type": filter.Types} {
+		switch vv := v.(type) {
+		case nil:
+		case []string:
+			q[field] = map[string]interface{}{"$in": vv}
+		case []interface{}:
+			q[field] = map[string]interface{}{"$in": vv}
+		default:
+			q[field] = vv
+		}
+	}
+	return q
+}
+
+func batchMembers(members interface{}) ([]ChannelBatchMemberRequest, error) {
+	if members == nil {
+		return nil, nil
+	}
+	if m, ok := members.([]ChannelBatchMemberRequest); ok {
+		return m, nil
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+	var out []ChannelBatchMemberRequest
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("members: %w", err)
+	}
+	return out, nil
+}
+
+func (u *ChannelBatchUpdater) previewChannel(ctx context.Context, ch *Channel, options *ChannelsBatchOptions, members []ChannelBatchMemberRequest) (*ChannelBatchEffect, error) {
+	effect := &ChannelBatchEffect{CID: ch.cid()}
+	if options.Operation == BatchUpdateOperationUpdateData {
+		effect.Changes = channelDataChanges(ch, options.Data)
+		return effect, nil
+	}
+
+	current, err := u.currentMembers(ctx, ch, members)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range members {
+		cur := current[m.UserID]
+		var change string
+		switch options.Operation {
+		case BatchUpdateOperationAddMembers:
+			if cur == nil {
+				change = "add member " + m.UserID
+			}
+		case BatchUpdateOperationInviteMembers:
+			if cur == nil {
+				change = "invite " + m.UserID
+			}
+		case BatchUpdateOperationRemoveMembers:
+			if cur != nil {
+				change = "remove member " + m.UserID
+			}
+		case BatchUpdateOperationAddModerators:
+			if cur == nil || memberChannelRole(cur) != channelModeratorRole {
+				change = "promote " + m.UserID + " to moderator"
+			}
+		case BatchUpdateOperationDemoteModerators:
+			if cur != nil && memberChannelRole(cur) == channelModeratorRole {
+				change = "demote moderator " + m.UserID
+			}
+		case BatchUpdateOperationAssignRoles:
+			if cur != nil && memberChannelRole(cur) != m.ChannelRole {
+				change = fmt.Sprintf("assign role %s to %s (was %s)", m.ChannelRole, m.UserID, memberChannelRole(cur))
+			}
+		case BatchUpdateOperationArchive:
+			if cur != nil && cur.ArchivedAt == nil {
+				change = "archive for " + m.UserID
+			}
+		case BatchUpdateOperationUnarchive:
+			if cur != nil && cur.ArchivedAt != nil {
+				change = "unarchive for " + m.UserID
+			}
+		case BatchUpdateOperationHide, BatchUpdateOperationShow:
+			// the hidden state of other users is not returned, so the effect is assumed
+			if cur != nil {
+				change = string(options.Operation) + " for " + m.UserID
+			}
+		default:
+			return nil, fmt.Errorf("unknown operation %q", options.Operation)
+		}
+		if change != "" {
+			effect.Changes = append(effect.Changes, change)
+		}
+	}
+	return effect, nil
+}
+
+// currentMembers returns the requested users which are members of ch, keyed by user ID.
+// Members which were not returned by QueryChannels are read with QueryMembers.
+func (u *ChannelBatchUpdater) currentMembers(ctx context.Context, ch *Channel, members []ChannelBatchMemberRequest) (map[string]*ChannelMember, error) {
+	current := make(map[string]*ChannelMember)
+	if len(members) == 0 {
+		return current, nil
+	}
+
+	list := ch.Members
+	if len(ch.Members) < ch.MemberCount {
+		ids := make([]string, 0, len(members))
+		for _, m := range members {
+			ids = append(ids, m.UserID)
+		}
+		list = nil
+		for _, batch := range batches(ids, batchPreviewMemberLimit) {
+			q := &QueryOption{Filter: map[string]interface{}{"id": map[string]interface{}{"$in": batch}}, Limit: len(batch)}
+			resp, err := u.client.Channel(ch.Type, ch.ID).QueryMembers(ctx, q)
+			if err != nil {
+				return nil, fmt.Errorf("query members: %w", err)
+			}
+			list = append(list, resp.Members...)
+		}
+	}
+	for _, m := range list {
+		current[memberUserID(m)] = m
+	}
+	return current, nil
+}
+
+// channelDataChanges describes the fields of data that differ from ch.
+func channelDataChanges(ch *Channel, data *ChannelDataUpdate) []string {
+	if data == nil {
+		return nil
+	}
+
+	var changes []string
+	set := func(field string, from, to interface{}) {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, (&FieldChange{Field: field, From: from, To: to}).String())
+		}
+	}
+	if data.Frozen != nil {
+		set("frozen", ch.Frozen, *data.Frozen)
+	}
+	if data.Disabled != nil {
+		set("disabled", ch.Disabled, *data.Disabled)
+	}
+	if data.Team != "" {
+		set("team", ch.Team, data.Team)
+	}
+	for _, key := range sortedKeys(data.Custom) {
+		set(key, ch.ExtraData[key], data.Custom[key])
+	}
+	for _, key := range sortedKeys(data.ConfigOverrides) {
+		changes = append(changes, fmt.Sprintf("config_overrides.%s = %s", key, formatFieldValue(data.ConfigOverrides[key])))
+	}
+	if data.AutoTranslationEnabled != nil {
+		changes = append(changes, fmt.Sprintf("auto_translation_enabled = %t", *data.AutoTranslationEnabled))
+	}
+	if data.AutoTranslationLanguage != "" {
+		changes = append(changes, fmt.Sprintf("auto_translation_language = %q", data.AutoTranslationLanguage))
+	}
+	return changes
+}
+
+// ChannelBatchConfirmFunc is called with the preview of a batch update and returns whether to run it.
+type ChannelBatchConfirmFunc func(*ChannelBatchPreview) bool
+
+// ChannelBatchChannelResult is the outcome of a batch update for a single channel.
+type ChannelBatchChannelResult struct {
+	CID   string `json:"cid"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// ChannelBatchExecution is the result of ChannelBatchUpdater.Execute.
+type ChannelBatchExecution struct {
+	Preview *ChannelBatchPreview         `json:"preview"`
+	TaskID  string                       `json:"task_id"`
+	Task    *TaskResponse                `json:"task"`
+	Results []*ChannelBatchChannelResult `json:"results"`
+}
+
+// Failed returns the results of the channels the update failed for.
+func (e *ChannelBatchExecution) Failed() []*ChannelBatchChannelResult {
+	var failed []*ChannelBatchChannelResult
+	for _, r := range e.Results {
+		if !r.OK {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// ChannelBatchExecuteOptions configures ChannelBatchUpdater.Execute.
+type ChannelBatchExecuteOptions struct {
+	Preview *ChannelBatchPreviewOptions
+	Wait    *WaitForTaskOptions
+}
+
+// Execute previews a batch update, asks confirm whether to run it and, if confirmed, runs
+// it on exactly the channels of the preview, waits for the task and reports the outcome per
+// channel. ErrChannelBatchNotConfirmed is returned with the preview if confirm returns false.
+// Truncated previews can't be executed.
+func (u *ChannelBatchUpdater) Execute(ctx context.Context, options *ChannelsBatchOptions, confirm ChannelBatchConfirmFunc, opts *ChannelBatchExecuteOptions) (*ChannelBatchExecution, error) {
+	if confirm == nil {
+		return nil, errors.New("confirm must not be nil")
+	}
+	if opts == nil {
+		opts = &ChannelBatchExecuteOptions{}
+	}
+
+	preview, err := u.Preview(ctx, options, opts.Preview)
+	if err != nil {
+		return nil, err
+	}
+	exec := &ChannelBatchExecution{Preview: preview}
+	switch {
+	case preview.Truncated:
+		return exec, errors.New("preview is truncated, the update can't be confirmed")
+	case len(preview.Channels) == 0:
+		return exec, nil
+	case !confirm(preview):
+		return exec, ErrChannelBatchNotConfirmed
+	}
+
+	// the update is restricted to the previewed channels, so channels created since are not changed
+	pinned := *options
+	pinned.Filter = ChannelsBatchFilters{CIDs: map[string]interface{}{"$in": preview.CIDs()}}
+	resp, err := u.client.UpdateChannelsBatch(ctx, &pinned)
+	if err != nil {
+		return exec, err
+	}
+	exec.TaskID = resp.TaskID
+
+	task, err := u.client.WaitForTask(ctx, resp.TaskID, opts.Wait)
+	exec.Task = task
+	if err != nil {
+		return exec, err
+	}
+
+	res, err := task.ChannelsBatchResult()
+	if err != nil {
+		return exec, err
+	}
+	failed := make(map[string]string)
+	for _, f := range res.FailedChannels {
+		for _, cid := range f.CIDs {
+			failed[cid] = f.Reason
+		}
+	}
+	for _, cid := range preview.CIDs() {
+		reason, ok := failed[cid]
+		exec.Results = append(exec.Results, &ChannelBatchChannelResult{CID: cid, OK: !ok, Error: reason})
+		delete(failed, cid)
+	}
+	for _, cid := range sortedKeys(failed) {
+		exec.Results = append(exec.Results, &ChannelBatchChannelResult{CID: cid, Error: failed[cid]})
+	}
+	return exec, nil
+}
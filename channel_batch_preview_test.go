@@ -0,0 +1,220 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChannelBatchServer struct {
+	t             *testing.T
+	queries       []map[string]interface{}
+	memberQueries []map[string]interface{}
+	batches       []map[string]interface{}
+}
+
+func (s *fakeChannelBatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/channels":
+		var q map[string]interface{}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&q))
+		s.queries = append(s.queries, q)
+
+		channels := []map[string]interface{}{
+			{
+				"channel": map[string]interface{}{"type": "messaging", "id": "a", "cid": "messaging:a", "member_count": 2, "frozen": false, "color": "red",
+					"created_at": "2024-01-01T00:00:00Z"},
+				"members": []map[string]interface{}{{"user_id": "jane", "channel_role": "channel_moderator"}, {"user_id": "bob"}},
+			},
+			{
+				"channel": map[string]interface{}{"type": "messaging", "id": "b", "cid": "messaging:b", "member_count": 300, "frozen": true,
+					"created_at": "2024-01-02T00:00:00Z"},
+				"members": []map[string]interface{}{{"user_id": "sam"}},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"channels": pageChannels(s.t, q, channels)})
+	case r.Method == http.MethodGet && r.URL.Path == "/members":
+		var q map[string]interface{}
+		require.NoError(s.t, json.Unmarshal([]byte(r.URL.Query().Get("payload")), &q))
+		s.memberQueries = append(s.memberQueries, q)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"members": []map[string]interface{}{{"user_id": "bob"}}})
+	case r.Method == http.MethodPut && r.URL.Path == "/channels/batch":
+		var body map[string]interface{}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		s.batches = append(s.batches, body)
+		_, _ = w.Write([]byte(`{"task_id": "t1"}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/tasks/t1":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"task_id": "t1", "status": "completed", "result": map[string]interface{}{
+			"operation": "addMembers", "success_channels_count": 1,
+			"failed_channels": []map[string]interface{}{{"reason": "channel is frozen", "cids": []string{"messaging:b"}}},
+		}})
+	default:
+		s.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}
+}
+
+// pageChannels returns the page of channels, sorted by creation time, selected by the
+// limit and the created_at $gte cursor of a QueryChannels request.
+func pageChannels(t *testing.T, q map[string]interface{}, channels []map[string]interface{}) []map[string]interface{} {
+	require.Nil(t, q["offset"])
+	require.Equal(t, []interface{}{map[string]interface{}{"field": "created_at", "direction": float64(1)}}, q["sort"])
+
+	created := func(ch map[string]interface{}) string {
+		return ch["channel"].(map[string]interface{})["created_at"].(string)
+	}
+	sorted := slices.Clone(channels)
+	slices.SortStableFunc(sorted, func(a, b map[string]interface{}) int { return strings.Compare(created(a), created(b)) })
+
+	from := ""
+	filter, _ := q["filter_conditions"].(map[string]interface{})
+	if ops, ok := filter["created_at"].(map[string]interface{}); ok {
+		from, _ = ops["$gte"].(string)
+	}
+	var page []map[string]interface{}
+	for _, ch := range sorted {
+		if created(ch) >= from && len(page) < int(q["limit"].(float64)) {
+			page = append(page, ch)
+		}
+	}
+	return page
+}
+
+func TestChannelBatchUpdater_Preview(t *testing.T) {
+	ctx := context.Background()
+	srv := &fakeChannelBatchServer{t: t}
+	u := newTestClient(t, srv).ChannelBatchUpdater()
+
+	preview, err := u.Preview(ctx, &ChannelsBatchOptions{
+		Operation: BatchUpdateOperationAddMembers,
+		Filter:    ChannelsBatchFilters{Types: []string{"messaging"}},
+		Members:   []ChannelBatchMemberRequest{{UserID: "bob"}, {UserID: "sam"}},
+	}, &ChannelBatchPreviewOptions{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, srv.queries, 2)
+	require.Equal(t, map[string]interface{}{"type": map[string]interface{}{"$in": []interface{}{"messaging"}}}, srv.queries[0]["filter_conditions"])
+	require.Equal(t, map[string]interface{}{
+		"type":       map[string]interface{}{"$in": []interface{}{"messaging"}},
+		"created_at": map[string]interface{}{"$gte": "2024-01-02T00:00:00Z"},
+	}, srv.queries[1]["filter_conditions"])
+	require.Equal(t, `addMembers on 2 channels, 2 affected
+  messaging:a:
+    add member sam
+  messaging:b:
+    add member sam
+`, preview.String())
+	require.Equal(t, []string{"messaging:a", "messaging:b"}, preview.Affected())
+
+	preview, err = u.Preview(ctx, &ChannelsBatchOptions{
+		Operation: BatchUpdateOperationDemoteModerators,
+		Filter:    ChannelsBatchFilters{CIDs: []string{"messaging:a", "messaging:b"}},
+		Members:   []ChannelBatchMemberRequest{{UserID: "jane"}},
+	}, &ChannelBatchPreviewOptions{MaxChannels: 1})
+	require.NoError(t, err)
+	require.True(t, preview.Truncated)
+	require.Equal(t, "demoteModerators on 1 channels, 1 affected (truncated, more channels match)\n  messaging:a:\n    demote moderator jane\n", preview.String())
+
+	frozen := true
+	preview, err = u.Preview(ctx, &ChannelsBatchOptions{
+		Operation: BatchUpdateOperationUpdateData,
+		Filter:    ChannelsBatchFilters{Types: "messaging"},
+		Data:      &ChannelDataUpdate{Frozen: &frozen, Custom: map[string]interface{}{"color": "red"}},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"frozen: false => true"}, preview.Channels[0].Changes)
+	require.Equal(t, []string{`color: null => "red"`}, preview.Channels[1].Changes)
+
+	_, err = u.Preview(ctx, &ChannelsBatchOptions{Operation: BatchUpdateOperationHide}, nil)
+	require.Error(t, err)
+
+	// members of large channels are looked up in batches
+	srv.memberQueries = nil
+	many := make([]ChannelBatchMemberRequest, 150)
+	for i := range many {
+		many[i].UserID = fmt.Sprintf("user-%d", i)
+	}
+	_, err = u.Preview(ctx, &ChannelsBatchOptions{
+		Operation: BatchUpdateOperationRemoveMembers,
+		Filter:    ChannelsBatchFilters{CIDs: []string{"messaging:b"}},
+		Members:   many,
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, srv.memberQueries, 2)
+	require.Equal(t, float64(100), srv.memberQueries[0]["limit"])
+	require.Equal(t, float64(50), srv.memberQueries[1]["limit"])
+}
+
+func TestClient_QueryChannelsInOrder(t *testing.T) {
+	ctx := context.Background()
+	channels := []map[string]interface{}{
+		{"channel": map[string]interface{}{"type": "messaging", "id": "c", "created_at": "2024-01-02T00:00:00Z"}},
+		{"channel": map[string]interface{}{"type": "messaging", "id": "a", "created_at": "2024-01-01T00:00:00Z"}},
+		{"channel": map[string]interface{}{"type": "messaging", "id": "b", "created_at": "2024-01-01T00:00:00Z"}},
+		{"channel": map[string]interface{}{"type": "messaging", "id": "d", "created_at": "2024-01-03T00:00:00Z"}},
+	}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"channels": pageChannels(t, q, channels)})
+	}))
+
+	var cids []string
+	collect := func(ch *Channel) error {
+		cids = append(cids, ch.cid())
+		return nil
+	}
+	err := c.queryChannelsInOrder(ctx, QueryOption{Filter: map[string]interface{}{}, Limit: 3}, collect)
+	require.NoError(t, err)
+	require.Equal(t, []string{"messaging:a", "messaging:b", "messaging:c", "messaging:d"}, cids)
+
+	// a full page of channels created at the same time can't be paged past
+	cids = nil
+	err = c.queryChannelsInOrder(ctx, QueryOption{Filter: map[string]interface{}{}, Limit: 2}, collect)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than 2 channels were created at 2024-01-01T00:00:00Z")
+	require.Equal(t, []string{"messaging:a", "messaging:b"}, cids)
+
+	require.Equal(t, map[string]interface{}{"$and": []interface{}{
+		map[string]interface{}{"created_at": "2024-01-01T00:00:00Z"},
+		map[string]interface{}{"created_at": map[string]interface{}{"$gte": "2024-01-02T00:00:00Z"}},
+	}}, filterCreatedFrom(map[string]interface{}{"created_at": "2024-01-01T00:00:00Z"}, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestChannelBatchUpdater_Execute(t *testing.T) {
+	ctx := context.Background()
+	srv := &fakeChannelBatchServer{t: t}
+	u := newTestClient(t, srv).ChannelBatchUpdater()
+
+	options := &ChannelsBatchOptions{
+		Operation: BatchUpdateOperationAddMembers,
+		Filter:    ChannelsBatchFilters{Types: "messaging"},
+		Members:   []ChannelBatchMemberRequest{{UserID: "sam"}},
+	}
+	opts := &ChannelBatchExecuteOptions{Wait: &WaitForTaskOptions{InitialInterval: time.Millisecond}}
+
+	exec, err := u.Execute(ctx, options, func(*ChannelBatchPreview) bool { return false }, opts)
+	require.ErrorIs(t, err, ErrChannelBatchNotConfirmed)
+	require.Len(t, exec.Preview.Channels, 2)
+	require.Empty(t, srv.batches)
+
+	var confirmed *ChannelBatchPreview
+	exec, err = u.Execute(ctx, options, func(p *ChannelBatchPreview) bool {
+		confirmed = p
+		return true
+	}, opts)
+	require.NoError(t, err)
+	require.Same(t, confirmed, exec.Preview)
+	require.Equal(t, "t1", exec.TaskID)
+	require.Equal(t, map[string]interface{}{"cids": map[string]interface{}{"$in": []interface{}{"messaging:a", "messaging:b"}}}, srv.batches[0]["filter"])
+	require.Equal(t, []*ChannelBatchChannelResult{
+		{CID: "messaging:a", OK: true},
+		{CID: "messaging:b", Error: "channel is frozen"},
+	}, exec.Results)
+	require.Len(t, exec.Failed(), 1)
+}
@@ -0,0 +1,257 @@
+package stream_chat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	defaultSyncMembersBatchSize = 100
+	defaultSyncMembersPageSize  = 100
+
+	channelMemberRole    = "channel_member"
+	channelModeratorRole = "channel_moderator"
+)
+
+// SyncMembersOptions configures Channel.SyncMembers.
+type SyncMembersOptions struct {
+	// BatchSize is the maximum number of users per request. Defaults to 100.
+	BatchSize int
+	// PageSize is the number of members read per QueryMembers request. Defaults to 100.
+	PageSize int
+	// KeepExtra keeps the current members which are not in the desired set.
+	KeepExtra bool
+	// HideHistory hides the channel history from the added members.
+	HideHistory bool
+	// DryRun computes the changes without applying them.
+	DryRun bool
+
+	// AddedMessage, RemovedMessage and RoleChangedMessage are optional system messages
+	// sent with each batch of added, removed and promoted or demoted members.
+	AddedMessage       *Message
+	RemovedMessage     *Message
+	RoleChangedMessage *Message
+}
+
+func (o SyncMembersOptions) withDefaults() SyncMembersOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultSyncMembersBatchSize
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = defaultSyncMembersPageSize
+	}
+	return o
+}
+
+// MemberRoleChange is the change of the channel role of a member.
+type MemberRoleChange struct {
+	UserID string `json:"user_id"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// MemberSyncChanges are the member changes of Channel.SyncMembers.
+type MemberSyncChanges struct {
+	Added       []string            `json:"added"`
+	Removed     []string            `json:"removed"`
+	RoleChanges []*MemberRoleChange `json:"role_changes"`
+}
+
+// SyncMembersReport is the result of Channel.SyncMembers. The embedded changes are the ones
+// which were applied, or the planned ones in a dry run.
+type SyncMembersReport struct {
+	MemberSyncChanges
+	// Planned is all the changes needed to make the members match the desired set.
+	Planned MemberSyncChanges `json:"planned"`
+	// Unchanged is the number of desired members which already had the desired role.
+	Unchanged int `json:"unchanged"`
+	// Requests is the number of update requests sent.
+	Requests int  `json:"requests"`
+	DryRun   bool `json:"dry_run"`
+}
+
+// SyncMembers makes the members of the channel match desired. The current members are read
+// with QueryMembers and the differences are applied in batches: new members are added first,
+// then roles are changed and finally the members which are not desired are removed.
+// The channel role of a desired member defaults to channel_member. It stops at the first
+// error and returns the report of the batches applied so far.
+func (ch *Channel) SyncMembers(ctx context.Context, desired []*ChannelMember, opts *SyncMembersOptions) (*SyncMembersReport, error) {
+	if opts == nil {
+		opts = &SyncMembersOptions{}
+	}
+	o := opts.withDefaults()
+
+	want := make(map[string]string, len(desired))
+	for i, m := range desired {
+		if m == nil || memberUserID(m) == "" {
+			return nil, fmt.Errorf("desired member #%d: user ID must be not empty", i+1)
+		}
+		id := memberUserID(m)
+		if _, ok := want[id]; ok {
+			return nil, fmt.Errorf("desired member %q: duplicated", id)
+		}
+		want[id] = memberChannelRole(m)
+	}
+
+	current, err := ch.queryAllMembers(ctx, o.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	have := make(map[string]string, len(current))
+	for _, m := range current {
+		have[memberUserID(m)] = memberChannelRole(m)
+	}
+
+	report := &SyncMembersReport{DryRun: o.DryRun}
+	planned := &report.Planned
+	var added []*ChannelMember
+	for _, m := range desired {
+		id := memberUserID(m)
+		from, ok := have[id]
+		switch {
+		case !ok:
+			added = append(added, m)
+			planned.Added = append(planned.Added, id)
+		case from != want[id]:
+			planned.RoleChanges = append(planned.RoleChanges, &MemberRoleChange{UserID: id, From: from, To: want[id]})
+		default:
+			report.Unchanged++
+		}
+	}
+	if !o.KeepExtra {
+		for id := range have {
+			if _, ok := want[id]; !ok {
+				planned.Removed = append(planned.Removed, id)
+			}
+		}
+		sort.Strings(planned.Removed)
+	}
+
+	if o.DryRun {
+		report.MemberSyncChanges = report.Planned
+		return report, nil
+	}
+	return report, ch.applyMemberSync(ctx, report, added, want, o)
+}
+
+// queryAllMembers pages through the members by the time they joined, as the offset of
+// QueryMembers is limited.
+func (ch *Channel) queryAllMembers(ctx context.Context, pageSize int) ([]*ChannelMember, error) {
+	var members []*ChannelMember
+	var cursor time.Time
+	// seen are the members created at cursor, which are returned again by the next page
+	seen := make(map[string]bool)
+	for {
+		q := &QueryOption{Filter: map[string]interface{}{}, Limit: pageSize}
+		if !cursor.IsZero() {
+			q.Filter = filterCreatedFrom(q.Filter, cursor)
+		}
+		resp, err := ch.QueryMembers(ctx, q, &SortOption{Field: "created_at", Direction: 1})
+		if err != nil {
+			return nil, fmt.Errorf("query members: %w", err)
+		}
+
+		advanced := false
+		for _, m := range resp.Members {
+			id := memberUserID(m)
+			if seen[id] {
+				continue
+			}
+			if !m.CreatedAt.Equal(cursor) {
+				cursor = m.CreatedAt
+				clear(seen)
+			}
+			seen[id] = true
+			advanced = true
+			members = append(members, m)
+		}
+		if len(resp.Members) < pageSize {
+			return members, nil
+		}
+		if !advanced {
+			return nil, fmt.Errorf("more than %d members were added at %s, use a larger page size",
+				pageSize, cursor.UTC().Format(time.RFC3339Nano))
+		}
+	}
+}
+
+func (ch *Channel) applyMemberSync(ctx context.Context, report *SyncMembersReport, added []*ChannelMember, want map[string]string, o SyncMembersOptions) error {
+	for _, batch := range batches(added, o.BatchSize) {
+		options := []AddMembersOptions{AddMembersWithMessage(o.AddedMessage)}
+		if o.HideHistory {
+			options = append(options, AddMembersWithHideHistory())
+		}
+		var assignments []*RoleAssignment
+		for _, m := range batch {
+			if role := want[memberUserID(m)]; role != channelMemberRole {
+				assignments = append(assignments, &RoleAssignment{UserID: memberUserID(m), ChannelRole: role})
+			}
+		}
+		if len(assignments) > 0 {
+			options = append(options, AddMembersWithRolesAssignment(assignments))
+		}
+
+		report.Requests++
+		if _, err := ch.AddChannelMembers(ctx, batch, options...); err != nil {
+			return fmt.Errorf("add members: %w", err)
+		}
+		for _, m := range batch {
+			report.Added = append(report.Added, memberUserID(m))
+		}
+	}
+
+	// moderators are promoted and demoted with the dedicated endpoints, other roles are assigned
+	var promoted, demoted []string
+	var assignments []*RoleAssignment
+	changes := make(map[string]*MemberRoleChange, len(report.Planned.RoleChanges))
+	for _, c := range report.Planned.RoleChanges {
+		changes[c.UserID] = c
+		switch {
+		case c.To == channelModeratorRole && c.From == channelMemberRole:
+			promoted = append(promoted, c.UserID)
+		case c.To == channelMemberRole && c.From == channelModeratorRole:
+			demoted = append(demoted, c.UserID)
+		default:
+			assignments = append(assignments, &RoleAssignment{UserID: c.UserID, ChannelRole: c.To})
+		}
+	}
+
+	for _, batch := range batches(promoted, o.BatchSize) {
+		report.Requests++
+		if _, err := ch.AddModeratorsWithMessage(ctx, batch, o.RoleChangedMessage); err != nil {
+			return fmt.Errorf("add moderators: %w", err)
+		}
+		for _, id := range batch {
+			report.RoleChanges = append(report.RoleChanges, changes[id])
+		}
+	}
+	for _, batch := range batches(assignments, o.BatchSize) {
+		report.Requests++
+		if _, err := ch.AssignRole(ctx, batch, o.RoleChangedMessage); err != nil {
+			return fmt.Errorf("assign roles: %w", err)
+		}
+		for _, a := range batch {
+			report.RoleChanges = append(report.RoleChanges, changes[a.UserID])
+		}
+	}
+	for _, batch := range batches(demoted, o.BatchSize) {
+		report.Requests++
+		if _, err := ch.DemoteModeratorsWithMessage(ctx, batch, o.RoleChangedMessage); err != nil {
+			return fmt.Errorf("demote moderators: %w", err)
+		}
+		for _, id := range batch {
+			report.RoleChanges = append(report.RoleChanges, changes[id])
+		}
+	}
+
+	for _, batch := range batches(report.Planned.Removed, o.BatchSize) {
+		report.Requests++
+		if _, err := ch.RemoveMembers(ctx, batch, o.RemovedMessage); err != nil {
+			return fmt.Errorf("remove members: %w", err)
+		}
+		report.Removed = append(report.Removed, batch...)
+	}
+	return nil
+}
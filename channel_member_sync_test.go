@@ -0,0 +1,159 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pageMembers serves QueryMembers like the API when the members are paged by the time
+// they were added.
+func pageMembers(t *testing.T, r *http.Request, members []map[string]interface{}) []map[string]interface{} {
+	var q map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(r.URL.Query().Get("payload")), &q))
+	require.Equal(t, float64(0), q["offset"])
+	require.Equal(t, []interface{}{map[string]interface{}{"field": "created_at", "direction": float64(1)}}, q["sort"])
+
+	created := func(m map[string]interface{}) string {
+		s, _ := m["created_at"].(string)
+		return s
+	}
+	sorted := slices.Clone(members)
+	slices.SortStableFunc(sorted, func(a, b map[string]interface{}) int { return strings.Compare(created(a), created(b)) })
+
+	from := ""
+	filter, _ := q["filter_conditions"].(map[string]interface{})
+	if ops, ok := filter["created_at"].(map[string]interface{}); ok {
+		from, _ = ops["$gte"].(string)
+	}
+	var page []map[string]interface{}
+	for _, m := range sorted {
+		if created(m) >= from && len(page) < int(q["limit"].(float64)) {
+			page = append(page, m)
+		}
+	}
+	return page
+}
+
+func TestChannel_SyncMembers(t *testing.T) {
+	ctx := context.Background()
+
+	current := []map[string]interface{}{
+		{"user_id": "keep", "channel_role": "channel_member", "created_at": "2024-01-01T10:00:00Z"},
+		{"user_id": "promote", "channel_role": "channel_member", "created_at": "2024-01-01T10:01:00Z"},
+		// added at the same time, so they span two pages
+		{"user_id": "demote", "channel_role": "channel_moderator", "created_at": "2024-01-01T10:02:00Z"},
+		{"user_id": "custom", "channel_role": "channel_member", "created_at": "2024-01-01T10:02:00Z"},
+	}
+	for i := 0; i < 3; i++ {
+		current = append(current, map[string]interface{}{
+			"user_id": fmt.Sprintf("old%d", i), "channel_role": "channel_member", "created_at": fmt.Sprintf("2024-01-01T10:1%d:00Z", i),
+		})
+	}
+
+	var queries int
+	var updates []map[string]interface{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/members" {
+			queries++
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"members": pageMembers(t, r, current)})
+			return
+		}
+
+		require.Equal(t, "/channels/messaging/team", r.URL.Path)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		updates = append(updates, body)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	ch := c.Channel("messaging", "team")
+
+	desired := []*ChannelMember{
+		{UserID: "keep"},
+		{UserID: "promote", ChannelRole: "channel_moderator"},
+		{UserID: "demote"},
+		{UserID: "custom", ChannelRole: "support_agent"},
+		{User: &User{ID: "new1"}},
+		{UserID: "new2", IsModerator: true},
+	}
+	opts := &SyncMembersOptions{BatchSize: 2, PageSize: 3, DryRun: true}
+
+	report, err := ch.SyncMembers(ctx, desired, opts)
+	require.NoError(t, err)
+	require.Equal(t, 4, queries)
+	require.Empty(t, updates)
+	require.Equal(t, []string{"new1", "new2"}, report.Added)
+	require.Equal(t, []string{"old0", "old1", "old2"}, report.Removed)
+	require.Equal(t, []*MemberRoleChange{
+		{UserID: "promote", From: "channel_member", To: "channel_moderator"},
+		{UserID: "demote", From: "channel_moderator", To: "channel_member"},
+		{UserID: "custom", From: "channel_member", To: "support_agent"},
+	}, report.RoleChanges)
+	require.Equal(t, 1, report.Unchanged)
+	require.Equal(t, report.Planned, report.MemberSyncChanges)
+
+	opts.DryRun = false
+	opts.RemovedMessage = &Message{Text: "removed by sync"}
+	report, err = ch.SyncMembers(ctx, desired, opts)
+	require.NoError(t, err)
+	require.Equal(t, 6, report.Requests)
+	require.Len(t, updates, 6)
+	require.Equal(t, []string{"new1", "new2"}, report.Added)
+	require.Equal(t, []string{"old0", "old1", "old2"}, report.Removed)
+	require.Len(t, report.RoleChanges, 3)
+
+	require.Len(t, updates[0]["add_members"], 2)
+	require.Equal(t, []interface{}{map[string]interface{}{"user_id": "new2", "channel_role": "channel_moderator"}}, updates[0]["assign_roles"])
+	require.Equal(t, []interface{}{"promote"}, updates[1]["add_moderators"])
+	require.Equal(t, []interface{}{map[string]interface{}{"user_id": "custom", "channel_role": "support_agent"}}, updates[2]["assign_roles"])
+	require.Equal(t, []interface{}{"demote"}, updates[3]["demote_moderators"])
+	require.Equal(t, []interface{}{"old0", "old1"}, updates[4]["remove_members"])
+	require.Equal(t, []interface{}{"old2"}, updates[5]["remove_members"])
+	require.Equal(t, "removed by sync", updates[5]["message"].(map[string]interface{})["text"])
+
+	_, err = ch.SyncMembers(ctx, []*ChannelMember{{UserID: "a"}, {UserID: "a"}}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `desired member "a": duplicated`)
+}
+
+func TestChannel_SyncMembers_ReportsAppliedChanges(t *testing.T) {
+	current := []map[string]interface{}{
+		{"user_id": "old0", "channel_role": "channel_member"},
+		{"user_id": "old1", "channel_role": "channel_member"},
+		{"user_id": "old2", "channel_role": "channel_member"},
+	}
+	var removes int
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/members" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"members": pageMembers(t, r, current)})
+			return
+		}
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if _, ok := body["remove_members"]; ok {
+			removes++
+			if removes == 2 {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"message":"boom"}`))
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+
+	report, err := c.Channel("messaging", "team").SyncMembers(context.Background(),
+		[]*ChannelMember{{UserID: "new"}}, &SyncMembersOptions{BatchSize: 2, PageSize: 10})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "remove members")
+	require.Equal(t, 3, report.Requests)
+	require.Equal(t, []string{"new"}, report.Added)
+	require.Equal(t, []string{"old0", "old1"}, report.Removed)
+	require.Equal(t, []string{"old0", "old1", "old2"}, report.Planned.Removed)
+}
@@ -0,0 +1,519 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultChannelMigrationPageSize = 100
+
+// ChannelMigrationProgress is the progress of the migration of one channel.
+type ChannelMigrationProgress struct {
+	Created       bool `json:"created"`
+	MembersCopied bool `json:"members_copied"`
+	// Cursor is the creation time of the last top-level message copied with its replies.
+	Cursor   time.Time `json:"cursor,omitempty"`
+	Messages int       `json:"messages"`
+	Done     bool      `json:"done"`
+}
+
+// ChannelMigrationState is the persisted state of a ChannelMigrator, used to resume
+// an interrupted migration.
+type ChannelMigrationState struct {
+	// Users are the IDs of the users copied to the destination.
+	Users    map[string]bool                      `json:"users"`
+	Channels map[string]*ChannelMigrationProgress `json:"channels"`
+	// MessageIDs maps the source message IDs to the destination message IDs.
+	MessageIDs map[string]string `json:"message_ids"`
+	// SkippedMessages are the IDs of the source messages which are not copied.
+	SkippedMessages map[string]bool `json:"skipped_messages,omitempty"`
+}
+
+func newChannelMigrationState() *ChannelMigrationState {
+	return &ChannelMigrationState{
+		Users:           make(map[string]bool),
+		Channels:        make(map[string]*ChannelMigrationProgress),
+		MessageIDs:      make(map[string]string),
+		SkippedMessages: make(map[string]bool),
+	}
+}
+
+func (s *ChannelMigrationState) init() {
+	if s.Users == nil {
+		s.Users = make(map[string]bool)
+	}
+	if s.Channels == nil {
+		s.Channels = make(map[string]*ChannelMigrationProgress)
+	}
+	if s.MessageIDs == nil {
+		s.MessageIDs = make(map[string]string)
+	}
+	if s.SkippedMessages == nil {
+		s.SkippedMessages = make(map[string]bool)
+	}
+}
+
+// ChannelMigrationStore persists the state of a ChannelMigrator.
+type ChannelMigrationStore interface {
+	// Load returns the saved state, or an empty state if nothing was saved yet.
+	Load(ctx context.Context) (*ChannelMigrationState, error)
+	Save(ctx context.Context, state *ChannelMigrationState) error
+}
+
+// MemoryChannelMigrationStore is a ChannelMigrationStore that keeps the state in memory.
+type MemoryChannelMigrationStore struct {
+	mu    sync.Mutex
+	state []byte
+}
+
+func (s *MemoryChannelMigrationStore) Load(_ context.Context) (*ChannelMigrationState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := newChannelMigrationState()
+	if s.state == nil {
+		return state, nil
+	}
+	if err := json.Unmarshal(s.state, state); err != nil {
+		return nil, err
+	}
+	state.init()
+	return state, nil
+}
+
+func (s *MemoryChannelMigrationStore) Save(_ context.Context, state *ChannelMigrationState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = b
+	return nil
+}
+
+// FileChannelMigrationStore is a ChannelMigrationStore backed by a JSON file,
+// rewritten atomically on every save.
+type FileChannelMigrationStore struct {
+	path string
+}
+
+// NewFileChannelMigrationStore returns a store persisting the state to path.
+// The file is created on first save.
+func NewFileChannelMigrationStore(path string) (*FileChannelMigrationStore, error) {
+	if path == "" {
+		return nil, errors.New("path must not be empty")
+	}
+	return &FileChannelMigrationStore{path: path}, nil
+}
+
+func (s *FileChannelMigrationStore) Load(_ context.Context) (*ChannelMigrationState, error) {
+	state := newChannelMigrationState()
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("cannot decode migration state %s: %w", s.path, err)
+	}
+	state.init()
+	return state, nil
+}
+
+func (s *FileChannelMigrationStore) Save(_ context.Context, state *ChannelMigrationState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+// ChannelMigrationOptions configures a ChannelMigrator.
+type ChannelMigrationOptions struct {
+	// Store persists the progress so an interrupted migration can be resumed by running it
+	// again with the same store. Defaults to a MemoryChannelMigrationStore.
+	Store ChannelMigrationStore
+	// PageSize is the number of items read per request. Defaults to 100.
+	PageSize int
+	// MessageID returns the destination ID of a source message. Defaults to the source ID.
+	// It must be deterministic so that resumed migrations don't duplicate messages.
+	MessageID func(sourceID string) string
+	// SkipReactions leaves out the reactions. The migration fails on a message with more
+	// than 1000 reactions, which can't be read through the API.
+	SkipReactions bool
+}
+
+// ChannelMigrationReport is the result of ChannelMigrator.Migrate. It counts what was
+// copied by this run, not by earlier interrupted runs.
+type ChannelMigrationReport struct {
+	Channels  int `json:"channels"`
+	Users     int `json:"users"`
+	Messages  int `json:"messages"`
+	Reactions int `json:"reactions"`
+	// Skipped is the number of deleted, ephemeral and error messages, which are not copied.
+	Skipped int `json:"skipped"`
+}
+
+// ChannelMigrator copies channels with their members, messages and reactions from one
+// app to another.
+type ChannelMigrator struct {
+	source, destination *Client
+	opts                ChannelMigrationOptions
+
+	state  *ChannelMigrationState
+	report *ChannelMigrationReport
+}
+
+// NewChannelMigrator returns a migrator copying channels from source to destination.
+func NewChannelMigrator(source, destination *Client, opts *ChannelMigrationOptions) (*ChannelMigrator, error) {
+	if source == nil || destination == nil {
+		return nil, errors.New("source and destination clients must be not nil")
+	}
+
+	m := &ChannelMigrator{source: source, destination: destination}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Store == nil {
+		m.opts.Store = &MemoryChannelMigrationStore{}
+	}
+	if m.opts.PageSize <= 0 {
+		m.opts.PageSize = defaultChannelMigrationPageSize
+	}
+	if m.opts.MessageID == nil {
+		m.opts.MessageID = func(id string) string { return id }
+	}
+	return m, nil
+}
+
+// Migrate copies the channels with the given CIDs. For each channel it copies the users
+// it references, creates the channel with its custom data, adds the members with their
+// roles, then copies the messages oldest first with their thread replies and reactions.
+// Messages keep their creation time; parent and quoted message IDs are remapped to the
+// destination IDs. Frozen and disabled flags are applied once the messages are copied.
+//
+// The progress is saved after every top-level message, so running Migrate again with the
+// same store resumes where it stopped. Channels which were fully copied are skipped.
+func (m *ChannelMigrator) Migrate(ctx context.Context, cids ...string) (*ChannelMigrationReport, error) {
+	for _, cid := range cids {
+		if parts := strings.SplitN(cid, ":", 2); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid channel CID %q", cid)
+		}
+	}
+
+	state, err := m.opts.Store.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load migration state: %w", err)
+	}
+	m.state = state
+	m.report = &ChannelMigrationReport{}
+
+	for _, cid := range cids {
+		if err := m.migrateChannel(ctx, cid); err != nil {
+			return m.report, fmt.Errorf("channel %s: %w", cid, err)
+		}
+	}
+	return m.report, nil
+}
+
+func (m *ChannelMigrator) save(ctx context.Context) error {
+	if err := m.opts.Store.Save(ctx, m.state); err != nil {
+		return fmt.Errorf("save migration state: %w", err)
+	}
+	return nil
+}
+
+func (m *ChannelMigrator) migrateChannel(ctx context.Context, cid string) error {
+	progress := m.state.Channels[cid]
+	if progress == nil {
+		progress = &ChannelMigrationProgress{}
+		m.state.Channels[cid] = progress
+	}
+	if progress.Done {
+		return nil
+	}
+
+	parts := strings.SplitN(cid, ":", 2)
+	src := m.source.Channel(parts[0], parts[1])
+	dst := m.destination.Channel(parts[0], parts[1])
+
+	if _, err := src.Query(ctx, &QueryRequest{State: true}); err != nil {
+		return fmt.Errorf("query source channel: %w", err)
+	}
+	creator := ""
+	if src.CreatedBy != nil {
+		creator = src.CreatedBy.ID
+	}
+	if creator == "" {
+		return errors.New("source channel has no creator")
+	}
+
+	if !progress.Created {
+		if err := m.copyUsers(ctx, []string{creator}); err != nil {
+			return err
+		}
+		data := &ChannelRequest{Team: src.Team, ExtraData: make(map[string]interface{}, len(src.ExtraData))}
+		for k, v := range src.ExtraData {
+			data.ExtraData[k] = v
+		}
+		if _, err := m.destination.CreateChannel(ctx, dst.Type, dst.ID, creator, data); err != nil {
+			return fmt.Errorf("create channel: %w", err)
+		}
+		progress.Created = true
+		if err := m.save(ctx); err != nil {
+			return err
+		}
+	}
+
+	if !progress.MembersCopied {
+		members, err := src.queryAllMembers(ctx, m.opts.PageSize)
+		if err != nil {
+			return err
+		}
+		if err := m.copyUsers(ctx, channelMemberIDs(members)); err != nil {
+			return err
+		}
+		if _, err := dst.SyncMembers(ctx, members, &SyncMembersOptions{KeepExtra: true, BatchSize: m.opts.PageSize, PageSize: m.opts.PageSize}); err != nil {
+			return fmt.Errorf("copy members: %w", err)
+		}
+		progress.MembersCopied = true
+		if err := m.save(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := m.copyMessages(ctx, src, dst, creator, progress); err != nil {
+		return err
+	}
+
+	if src.Frozen || src.Disabled {
+		set := map[string]interface{}{}
+		if src.Frozen {
+			set["frozen"] = true
+		}
+		if src.Disabled {
+			set["disabled"] = true
+		}
+		if _, err := dst.PartialUpdate(ctx, PartialUpdate{Set: set}); err != nil {
+			return fmt.Errorf("update channel flags: %w", err)
+		}
+	}
+
+	progress.Done = true
+	m.report.Channels++
+	return m.save(ctx)
+}
+
+func (m *ChannelMigrator) copyMessages(ctx context.Context, src, dst *Channel, creator string, progress *ChannelMigrationProgress) error {
+	pageSize := m.opts.PageSize
+	for {
+		resp, err := src.Query(ctx, &QueryRequest{State: true, Messages: messagesFrom(progress.Cursor, pageSize)})
+		if err != nil {
+			return fmt.Errorf("query messages: %w", err)
+		}
+
+		advanced := false
+		for _, msg := range resp.Messages {
+			if msg.ParentID != "" && !msg.ShowInChannel {
+				continue
+			}
+			if _, ok := m.state.MessageIDs[msg.ID]; !ok {
+				if err := m.copyMessage(ctx, dst, msg, creator); err != nil {
+					return err
+				}
+			}
+			if msg.ReplyCount > 0 && msg.ParentID == "" {
+				if err := m.copyReplies(ctx, src, dst, msg.ID, creator); err != nil {
+					return err
+				}
+			}
+			if messageTime(msg).After(progress.Cursor) {
+				advanced = true
+			}
+			progress.Cursor = messageTime(msg)
+			progress.Messages = len(m.state.MessageIDs)
+			if err := m.save(ctx); err != nil {
+				return err
+			}
+		}
+		if len(resp.Messages) < pageSize {
+			return nil
+		}
+		if !advanced {
+			return errMessagePageNotAdvanced(progress.Cursor, pageSize)
+		}
+	}
+}
+
+func (m *ChannelMigrator) copyReplies(ctx context.Context, src, dst *Channel, parentID, creator string) error {
+	return eachReply(ctx, src, parentID, m.opts.PageSize, func(msg *Message) error {
+		if _, ok := m.state.MessageIDs[msg.ID]; ok {
+			return nil
+		}
+		return m.copyMessage(ctx, dst, msg, creator)
+	})
+}
+
+// copyMessage sends msg to dst with its reactions and records its destination ID. Replies
+// to skipped messages, such as deleted thread parents, are skipped as well.
+func (m *ChannelMigrator) copyMessage(ctx context.Context, dst *Channel, msg *Message, creator string) error {
+	if m.state.SkippedMessages[msg.ID] {
+		return nil
+	}
+	if msg.DeletedAt != nil || msg.Type == MessageTypeEphemeral || msg.Type == MessageTypeError ||
+		msg.ParentID != "" && m.state.SkippedMessages[msg.ParentID] {
+		m.state.SkippedMessages[msg.ID] = true
+		m.report.Skipped++
+		return nil
+	}
+
+	userID := msg.UserID
+	if msg.User != nil {
+		userID = msg.User.ID
+	}
+	if userID == "" {
+		userID = creator
+	}
+	userIDs := []string{userID}
+	for _, u := range msg.MentionedUsers {
+		userIDs = append(userIDs, u.ID)
+	}
+	if err := m.copyUsers(ctx, userIDs); err != nil {
+		return err
+	}
+
+	id := m.opts.MessageID(msg.ID)
+	out := &Message{
+		ID:             id,
+		Text:           msg.Text,
+		Attachments:    msg.Attachments,
+		MentionedUsers: msg.MentionedUsers,
+		ShowInChannel:  msg.ShowInChannel,
+		Pinned:         msg.Pinned,
+		ExtraData:      make(map[string]interface{}, len(msg.ExtraData)+1),
+	}
+	if msg.Type == MessageTypeSystem {
+		out.Type = MessageTypeSystem
+	}
+	for k, v := range msg.ExtraData {
+		out.ExtraData[k] = v
+	}
+	if msg.CreatedAt != nil {
+		out.ExtraData["created_at"] = msg.CreatedAt
+	}
+	if msg.ParentID != "" {
+		parentID, ok := m.state.MessageIDs[msg.ParentID]
+		if !ok {
+			return fmt.Errorf("message %s: parent %s was not copied", msg.ID, msg.ParentID)
+		}
+		out.ParentID = parentID
+	}
+	// quotes of messages which were not copied, such as deleted ones, are dropped
+	out.QuotedMessageID = m.state.MessageIDs[msg.QuotedMessageID]
+
+	if _, err := dst.SendMessage(ctx, out, userID, MessageSkipPush); err != nil {
+		// the message may have been sent before the migration was interrupted
+		var apiErr Error
+		if !errors.As(err, &apiErr) || isTransientError(err) {
+			return fmt.Errorf("send message %s: %w", msg.ID, err)
+		}
+		if _, getErr := m.destination.GetMessage(ctx, id); getErr != nil {
+			return fmt.Errorf("send message %s: %w", msg.ID, err)
+		}
+	}
+
+	if !m.opts.SkipReactions && len(msg.ReactionCounts) > 0 {
+		if err := m.copyReactions(ctx, msg.ID, id); err != nil {
+			return err
+		}
+	}
+
+	m.state.MessageIDs[msg.ID] = id
+	m.report.Messages++
+	return nil
+}
+
+func (m *ChannelMigrator) copyReactions(ctx context.Context, sourceID, destinationID string) error {
+	for offset := 0; ; offset += m.opts.PageSize {
+		if offset > maxReactionsOffset {
+			return errTooManyReactions(sourceID)
+		}
+		params := map[string][]string{"limit": {strconv.Itoa(m.opts.PageSize)}, "offset": {strconv.Itoa(offset)}}
+		resp, err := m.source.GetReactions(ctx, sourceID, params)
+		if err != nil {
+			return fmt.Errorf("get reactions of %s: %w", sourceID, err)
+		}
+
+		userIDs := make([]string, 0, len(resp.Reactions))
+		for _, r := range resp.Reactions {
+			userIDs = append(userIDs, r.UserID)
+		}
+		if err := m.copyUsers(ctx, userIDs); err != nil {
+			return err
+		}
+		for _, r := range resp.Reactions {
+			reaction := &Reaction{Type: r.Type, ExtraData: r.ExtraData}
+			if _, err := m.destination.SendReaction(ctx, reaction, destinationID, r.UserID); err != nil {
+				return fmt.Errorf("send reaction to %s: %w", destinationID, err)
+			}
+			m.report.Reactions++
+		}
+		if len(resp.Reactions) < m.opts.PageSize {
+			return nil
+		}
+	}
+}
+
+// copyUsers upserts the users which were not copied yet to the destination. Users missing
+// from the source, such as deleted users, are created with their ID only.
+func (m *ChannelMigrator) copyUsers(ctx context.Context, ids []string) error {
+	var missing []string
+	for _, id := range ids {
+		if id != "" && !m.state.Users[id] {
+			missing = append(missing, id)
+		}
+	}
+	missing = dedupeSorted(missing)
+
+	for _, batch := range batches(missing, m.opts.PageSize) {
+		resp, err := m.source.QueryUsers(ctx, &QueryUsersOptions{
+			QueryOption:             QueryOption{Filter: map[string]interface{}{"id": map[string]interface{}{"$in": batch}}, Limit: len(batch)},
+			IncludeDeactivatedUsers: true,
+		})
+		if err != nil {
+			return fmt.Errorf("query users: %w", err)
+		}
+		found := make(map[string]*User, len(resp.Users))
+		for _, u := range resp.Users {
+			found[u.ID] = u
+		}
+		users := make([]*User, 0, len(batch))
+		for _, id := range batch {
+			u, ok := found[id]
+			if !ok {
+				u = &User{ID: id}
+			}
+			users = append(users, u)
+		}
+
+		if _, err := m.destination.UpsertUsers(ctx, users...); err != nil {
+			return fmt.Errorf("upsert users: %w", err)
+		}
+		for _, id := range batch {
+			m.state.Users[id] = true
+		}
+		m.report.Users += len(batch)
+	}
+	return nil
+}
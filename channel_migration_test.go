@@ -0,0 +1,307 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChatApp is a minimal in-memory app serving the endpoints used by ChannelMigrator.
+type fakeChatApp struct {
+	t        *testing.T
+	users    map[string]map[string]interface{}
+	channel  map[string]interface{}
+	members  []map[string]interface{}
+	messages []map[string]interface{}
+	// reactions are keyed by message ID
+	reactions map[string][]map[string]interface{}
+
+	sends      map[string]int
+	failSendOf string
+	updates    []map[string]interface{}
+}
+
+func newFakeChatApp(t *testing.T) *fakeChatApp {
+	return &fakeChatApp{
+		t:         t,
+		users:     make(map[string]map[string]interface{}),
+		reactions: make(map[string][]map[string]interface{}),
+		sends:     make(map[string]int),
+	}
+}
+
+func (a *fakeChatApp) decode(r *http.Request) map[string]interface{} {
+	var body map[string]interface{}
+	if r.Method == http.MethodGet {
+		require.NoError(a.t, json.Unmarshal([]byte(r.URL.Query().Get("payload")), &body))
+	} else {
+		require.NoError(a.t, json.NewDecoder(r.Body).Decode(&body))
+	}
+	return body
+}
+
+func (a *fakeChatApp) message(id string) map[string]interface{} {
+	for _, m := range a.messages {
+		if m["id"] == id {
+			return m
+		}
+	}
+	return nil
+}
+
+func (a *fakeChatApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(v interface{}) { _ = json.NewEncoder(w).Encode(v) }
+	p := r.URL.Path
+	switch {
+	case r.Method == http.MethodGet && p == "/users":
+		var users []map[string]interface{}
+		in := a.decode(r)["filter_conditions"].(map[string]interface{})["id"].(map[string]interface{})["$in"].([]interface{})
+		for _, id := range in {
+			if u, ok := a.users[id.(string)]; ok {
+				users = append(users, u)
+			}
+		}
+		reply(map[string]interface{}{"users": users})
+	case r.Method == http.MethodPost && p == "/users":
+		for id, u := range a.decode(r)["users"].(map[string]interface{}) {
+			a.users[id] = u.(map[string]interface{})
+		}
+		reply(map[string]interface{}{})
+	case r.Method == http.MethodPost && p == "/channels/messaging/general/query":
+		q := a.decode(r)
+		if data, ok := q["data"].(map[string]interface{}); ok && a.channel == nil {
+			a.channel = map[string]interface{}{"type": "messaging", "id": "general", "cid": "messaging:general"}
+			for k, v := range data {
+				a.channel[k] = v
+			}
+		}
+		var messages []map[string]interface{}
+		page, _ := q["messages"].(map[string]interface{})
+		limit := 25
+		if page != nil && page["limit"] != nil {
+			limit = int(page["limit"].(float64))
+		}
+		for _, m := range a.messages {
+			if m["parent_id"] != nil || len(messages) == limit {
+				continue
+			}
+			if after, ok := page["created_at_after_or_equal"].(string); ok && m["created_at"].(string) < after {
+				continue
+			}
+			messages = append(messages, m)
+		}
+		reply(map[string]interface{}{"channel": a.channel, "messages": messages})
+	case r.Method == http.MethodGet && p == "/members":
+		reply(map[string]interface{}{"members": pageMembers(a.t, r, a.members)})
+	case r.Method == http.MethodPost && p == "/channels/messaging/general":
+		body := a.decode(r)
+		a.updates = append(a.updates, body)
+		if added, ok := body["add_members"].([]interface{}); ok {
+			for _, m := range added {
+				a.members = append(a.members, m.(map[string]interface{}))
+			}
+		}
+		reply(map[string]interface{}{})
+	case r.Method == http.MethodPatch && p == "/channels/messaging/general":
+		for k, v := range a.decode(r)["set"].(map[string]interface{}) {
+			a.channel[k] = v
+		}
+		reply(map[string]interface{}{})
+	case r.Method == http.MethodPost && p == "/channels/messaging/general/message":
+		m := a.decode(r)["message"].(map[string]interface{})
+		id := m["id"].(string)
+		a.sends[id]++
+		if id == a.failSendOf {
+			a.failSendOf = ""
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"code": 0, "message": "unavailable", "StatusCode": 503}`))
+			return
+		}
+		a.messages = append(a.messages, m)
+		reply(map[string]interface{}{"message": m})
+	case r.Method == http.MethodGet && strings.HasSuffix(p, "/replies"):
+		parentID := strings.Split(p, "/")[2]
+		var replies []map[string]interface{}
+		for _, m := range a.messages {
+			if m["parent_id"] == parentID {
+				replies = append(replies, m)
+			}
+		}
+		reply(map[string]interface{}{"messages": pageReplies(r.URL.Query(), replies)})
+	case r.Method == http.MethodGet && strings.HasSuffix(p, "/reactions"):
+		id := strings.Split(p, "/")[2]
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if offset > maxReactionsOffset {
+			a.t.Errorf("reactions offset %d is over the API limit", offset)
+		}
+		reactions := a.reactions[id]
+		end := min(offset+limit, len(reactions))
+		reply(map[string]interface{}{"reactions": reactions[min(offset, end):end]})
+	case r.Method == http.MethodPost && strings.HasSuffix(p, "/reaction"):
+		id := strings.Split(p, "/")[2]
+		a.reactions[id] = append(a.reactions[id], a.decode(r)["reaction"].(map[string]interface{}))
+		reply(map[string]interface{}{})
+	default:
+		a.t.Errorf("unexpected request %s %s", r.Method, p)
+	}
+}
+
+func TestChannelMigrator_Migrate(t *testing.T) {
+	ctx := context.Background()
+
+	source := newFakeChatApp(t)
+	source.users["jane"] = map[string]interface{}{"id": "jane", "name": "Jane"}
+	source.users["bob"] = map[string]interface{}{"id": "bob", "name": "Bob"}
+	source.channel = map[string]interface{}{
+		"type": "messaging", "id": "general", "cid": "messaging:general", "name": "General", "frozen": true,
+		"created_by": map[string]interface{}{"id": "jane"},
+	}
+	source.members = []map[string]interface{}{
+		{"user_id": "jane", "channel_role": "channel_moderator", "created_at": "2023-12-01T10:00:00Z"},
+		{"user_id": "bob", "channel_role": "channel_member", "created_at": "2023-12-02T10:00:00Z"},
+	}
+	source.messages = []map[string]interface{}{
+		{"id": "m1", "text": "hello", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:00:00Z",
+			"reply_count": 1, "reaction_counts": map[string]int{"like": 1}},
+		{"id": "m2", "text": "hi", "user": map[string]interface{}{"id": "bob"}, "created_at": "2024-01-01T10:01:00Z", "quoted_message_id": "m1"},
+		{"id": "m3", "text": "", "user": map[string]interface{}{"id": "bob"}, "created_at": "2024-01-01T10:02:00Z", "deleted_at": "2024-01-01T10:05:00Z",
+			"reply_count": 1},
+		{"id": "r1", "text": "thread", "user": map[string]interface{}{"id": "carl"}, "created_at": "2024-01-01T10:03:00Z", "parent_id": "m1"},
+		// the parent of r2 is deleted, so r2 can't be threaded in the destination
+		{"id": "r2", "text": "orphan", "user": map[string]interface{}{"id": "dave"}, "created_at": "2024-01-01T10:04:00Z", "parent_id": "m3"},
+	}
+	source.reactions["m1"] = []map[string]interface{}{{"type": "like", "user_id": "bob", "message_id": "m1"}}
+
+	destination := newFakeChatApp(t)
+	destination.failSendOf = "new-m2"
+
+	store, err := NewFileChannelMigrationStore(filepath.Join(t.TempDir(), "migration.json"))
+	require.NoError(t, err)
+	m, err := NewChannelMigrator(newTestClient(t, source), newTestClient(t, destination), &ChannelMigrationOptions{
+		Store:     store,
+		PageSize:  2,
+		MessageID: func(id string) string { return "new-" + id },
+	})
+	require.NoError(t, err)
+
+	report, err := m.Migrate(ctx, "messaging:general")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "send message m2")
+	require.Equal(t, 2, report.Messages)
+	require.Equal(t, 3, report.Users)
+
+	state, err := store.Load(ctx)
+	require.NoError(t, err)
+	require.True(t, state.Channels["messaging:general"].MembersCopied)
+	require.Equal(t, map[string]string{"m1": "new-m1", "r1": "new-r1"}, state.MessageIDs)
+
+	report, err = m.Migrate(ctx, "messaging:general")
+	require.NoError(t, err)
+	require.Equal(t, &ChannelMigrationReport{Channels: 1, Messages: 1, Skipped: 2}, report)
+	state, err = store.Load(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"m3": true, "r2": true}, state.SkippedMessages)
+
+	require.Equal(t, map[string]int{"new-m1": 1, "new-r1": 1, "new-m2": 2}, destination.sends)
+	var ids []string
+	for _, msg := range destination.messages {
+		ids = append(ids, msg["id"].(string))
+	}
+	require.Equal(t, []string{"new-m1", "new-r1", "new-m2"}, ids)
+
+	require.Equal(t, "new-m1", destination.message("new-r1")["parent_id"])
+	require.Equal(t, "new-m1", destination.message("new-m2")["quoted_message_id"])
+	createdAt, err := time.Parse(time.RFC3339, destination.message("new-m2")["created_at"].(string))
+	require.NoError(t, err)
+	require.True(t, createdAt.Equal(time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC)))
+
+	require.Equal(t, "General", destination.channel["name"])
+	require.Equal(t, true, destination.channel["frozen"])
+	require.Equal(t, []interface{}{map[string]interface{}{"user_id": "jane", "channel_role": "channel_moderator"}}, destination.updates[0]["assign_roles"])
+	require.Len(t, destination.members, 2)
+	require.Equal(t, "bob", destination.reactions["new-m1"][0]["user_id"])
+
+	var users []string
+	for id := range destination.users {
+		users = append(users, id)
+	}
+	sort.Strings(users)
+	require.Equal(t, []string{"bob", "carl", "jane"}, users)
+	require.Equal(t, "Jane", destination.users["jane"]["name"])
+
+	report, err = m.Migrate(ctx, "messaging:general")
+	require.NoError(t, err)
+	require.Equal(t, &ChannelMigrationReport{}, report)
+
+	_, err = m.Migrate(ctx, "general")
+	require.Error(t, err)
+}
+
+func TestChannelMigrator_SameCreationTime(t *testing.T) {
+	source := newFakeChatApp(t)
+	source.users["jane"] = map[string]interface{}{"id": "jane"}
+	source.channel = map[string]interface{}{"type": "messaging", "id": "general", "cid": "messaging:general", "created_by": map[string]interface{}{"id": "jane"}}
+	for _, id := range []string{"m1", "m2", "m3"} {
+		source.messages = append(source.messages, map[string]interface{}{"id": id, "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:00:00Z"})
+	}
+
+	m, err := NewChannelMigrator(newTestClient(t, source), newTestClient(t, newFakeChatApp(t)), &ChannelMigrationOptions{PageSize: 2})
+	require.NoError(t, err)
+	_, err = m.Migrate(context.Background(), "messaging:general")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than 2 messages were created at 2024-01-01T10:00:00Z")
+}
+
+func TestChannelMigrator_ManyReplies(t *testing.T) {
+	source := newFakeChatApp(t)
+	source.channel = map[string]interface{}{
+		"type": "messaging", "id": "general", "cid": "messaging:general", "created_by": map[string]interface{}{"id": "jane"},
+	}
+	source.messages = []map[string]interface{}{
+		{"id": "m1", "text": "hello", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:00:00Z", "reply_count": 3},
+		{"id": "r1", "text": "one", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:01:00Z", "parent_id": "m1"},
+		{"id": "r2", "text": "two", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:02:00Z", "parent_id": "m1"},
+		{"id": "r3", "text": "three", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:03:00Z", "parent_id": "m1"},
+	}
+	destination := newFakeChatApp(t)
+
+	m, err := NewChannelMigrator(newTestClient(t, source), newTestClient(t, destination), &ChannelMigrationOptions{PageSize: 2})
+	require.NoError(t, err)
+	_, err = m.Migrate(context.Background(), "messaging:general")
+	require.NoError(t, err)
+
+	var ids []string
+	for _, msg := range destination.messages {
+		ids = append(ids, msg["id"].(string))
+	}
+	require.Equal(t, []string{"m1", "r1", "r2", "r3"}, ids)
+}
+
+func TestChannelMigrator_TooManyReactions(t *testing.T) {
+	source := newFakeChatApp(t)
+	source.channel = map[string]interface{}{
+		"type": "messaging", "id": "general", "cid": "messaging:general", "created_by": map[string]interface{}{"id": "jane"},
+	}
+	source.messages = []map[string]interface{}{
+		{"id": "m1", "text": "hello", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T10:00:00Z",
+			"reaction_counts": map[string]int{"like": 1300}},
+	}
+	for i := 0; i < 1300; i++ {
+		source.reactions["m1"] = append(source.reactions["m1"], map[string]interface{}{"type": "like", "user_id": "jane", "message_id": "m1"})
+	}
+
+	m, err := NewChannelMigrator(newTestClient(t, source), newTestClient(t, newFakeChatApp(t)), &ChannelMigrationOptions{PageSize: 300})
+	require.NoError(t, err)
+	_, err = m.Migrate(context.Background(), "messaging:general")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "message m1 has more than 1000 reactions")
+}
@@ -0,0 +1,510 @@
+package stream_chat
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// ChannelStateOptions configures a ChannelState.
+type ChannelStateOptions struct {
+	// MaxMessages is the number of most recent channel messages kept in memory. Zero keeps all of them.
+	MaxMessages int
+}
+
+// ChannelState is an in-memory copy of a channel kept up to date by applying events, for example
+// the ones received by a webhook. It is safe for concurrent use. Values returned by its methods are
+// snapshots that are not modified by later events and must not be modified by the caller.
+type ChannelState struct {
+	mu   sync.RWMutex
+	opts ChannelStateOptions
+
+	cid      string
+	channel  *Channel
+	deleted  bool
+	messages []*Message
+	replies  map[string][]*Message
+	members  []*ChannelMember
+	reads    []*ChannelRead
+	watchers int
+}
+
+// NewChannelState creates a state from a channel loaded with Query or RefreshState.
+func NewChannelState(ch *Channel, opts *ChannelStateOptions) *ChannelState {
+	s := &ChannelState{}
+	if opts != nil {
+		s.opts = *opts
+	}
+	s.Load(ch)
+	return s
+}
+
+// LoadState queries the channel state and returns a ChannelState initialized with it.
+// If q is nil, the default state of RefreshState is loaded.
+func (ch *Channel) LoadState(ctx context.Context, q *QueryRequest, opts *ChannelStateOptions) (*ChannelState, error) {
+	if q == nil {
+		q = &QueryRequest{State: true}
+	}
+	if _, err := ch.Query(ctx, q); err != nil {
+		return nil, err
+	}
+	return NewChannelState(ch, opts), nil
+}
+
+// Load replaces the whole state with the channel, for example after querying it again
+// to recover from missed events.
+func (s *ChannelState) Load(ch *Channel) {
+	meta := *ch
+	meta.Messages, meta.Members, meta.Read, meta.PinnedMessages, meta.Watchers = nil, nil, nil, nil, nil
+
+	messages := make([]*Message, 0, len(ch.Messages))
+	for _, m := range ch.Messages {
+		messages = insertMessage(messages, cloneMessage(m))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cid = ch.cid()
+	s.channel = &meta
+	s.deleted = false
+	s.messages = s.trim(messages)
+	s.replies = make(map[string][]*Message)
+	s.members = append([]*ChannelMember(nil), ch.Members...)
+	s.reads = append([]*ChannelRead(nil), ch.Read...)
+	s.watchers = ch.WatcherCount
+}
+
+// Apply updates the state with an event. It returns false if the event is for another
+// channel or of a type that doesn't change the channel state.
+func (s *ChannelState) Apply(e *Event) bool {
+	if e == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cid := e.CID
+	if cid == "" && e.Channel != nil {
+		cid = e.Channel.cid()
+	}
+	if cid != s.cid {
+		return false
+	}
+
+	switch e.Type {
+	case EventMessageNew, EventNotificationNewMessage:
+		return s.addMessage(e)
+	case EventMessageUpdated, EventReactionNew, EventReactionUpdated, EventReactionDeleted:
+		if e.Message == nil {
+			return false
+		}
+		return s.replaceMessage(e.Message)
+	case EventMessageDeleted:
+		if e.Message == nil {
+			return false
+		}
+		if hard, _ := e.ExtraData["hard_delete"].(bool); hard {
+			return s.removeMessage(e.Message)
+		}
+		return s.replaceMessage(e.Message)
+	case EventMessageRead, EventNotificationMarkRead:
+		return s.markRead(e)
+	case EventMemberAdded, EventMemberUpdated, EventNotificationAddedToChannel,
+		EventNotificationInvited, EventNotificationInviteAccepted:
+		return s.upsertMember(e)
+	case EventMemberRemoved, EventNotificationRemovedFromChannel:
+		return s.removeMember(e)
+	case EventChannelUpdated:
+		return s.updateChannel(e.Channel)
+	case EventChannelTruncated:
+		return s.truncate(e)
+	case EventChannelDeleted:
+		s.updateChannel(e.Channel)
+		s.deleted = true
+		s.messages, s.replies = nil, make(map[string][]*Message)
+		return true
+	case EventUserWatchingStart, EventUserWatchingStop:
+		s.watchers = e.WatcherCount
+		return true
+	}
+	return false
+}
+
+// Snapshot returns a copy of the channel with its current messages, pinned messages, members, reads
+// and watcher count.
+func (s *ChannelState) Snapshot() *Channel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ch := *s.channel
+	ch.Messages = append([]*Message(nil), s.messages...)
+	ch.Members = append([]*ChannelMember(nil), s.members...)
+	ch.Read = append([]*ChannelRead(nil), s.reads...)
+	ch.WatcherCount = s.watchers
+	ch.MemberCount = s.channel.MemberCount
+	for _, m := range s.messages {
+		if m.Pinned {
+			ch.PinnedMessages = append(ch.PinnedMessages, m)
+		}
+	}
+	return &ch
+}
+
+// Deleted reports whether a channel.deleted event was applied.
+func (s *ChannelState) Deleted() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.deleted
+}
+
+// Messages returns the channel messages, oldest first.
+func (s *ChannelState) Messages() []*Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*Message(nil), s.messages...)
+}
+
+// Message returns the channel message or thread reply with the given ID, or nil if unknown.
+func (s *ChannelState) Message(id string) *Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := indexMessage(s.messages, id); i >= 0 {
+		return s.messages[i]
+	}
+	for _, replies := range s.replies {
+		if i := indexMessage(replies, id); i >= 0 {
+			return replies[i]
+		}
+	}
+	return nil
+}
+
+// Replies returns the thread replies to parentID received as events, oldest first.
+// Replies are not part of the channel query, so replies sent before the state was loaded are missing.
+func (s *ChannelState) Replies(parentID string) []*Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*Message(nil), s.replies[parentID]...)
+}
+
+// Members returns the channel members.
+func (s *ChannelState) Members() []*ChannelMember {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*ChannelMember(nil), s.members...)
+}
+
+// Member returns the member with the given user ID, or nil if the user is not a member.
+func (s *ChannelState) Member(userID string) *ChannelMember {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := indexMember(s.members, userID); i >= 0 {
+		return s.members[i]
+	}
+	return nil
+}
+
+// Read returns the read state of the user, or nil if unknown.
+func (s *ChannelState) Read(userID string) *ChannelRead {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := indexRead(s.reads, userID); i >= 0 {
+		return s.reads[i]
+	}
+	return nil
+}
+
+// WatcherCount returns the number of users watching the channel.
+func (s *ChannelState) WatcherCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.watchers
+}
+
+func (s *ChannelState) addMessage(e *Event) bool {
+	if e.Message == nil {
+		return false
+	}
+	msg := cloneMessage(e.Message)
+	if msg.CreatedAt == nil && !e.CreatedAt.IsZero() {
+		createdAt := e.CreatedAt
+		msg.CreatedAt = &createdAt
+	}
+
+	if msg.ParentID != "" {
+		replies := s.replies[msg.ParentID]
+		if i := indexMessage(replies, msg.ID); i >= 0 {
+			replies[i] = msg
+		} else {
+			s.replies[msg.ParentID] = insertMessage(replies, msg)
+			s.incrementReplyCount(msg)
+		}
+	}
+	if msg.ParentID == "" || msg.ShowInChannel {
+		if i := indexMessage(s.messages, msg.ID); i >= 0 {
+			s.messages[i] = msg
+		} else {
+			s.messages = s.trim(insertMessage(s.messages, msg))
+			s.countNewMessage(msg)
+		}
+	}
+	if e.WatcherCount > 0 {
+		s.watchers = e.WatcherCount
+	}
+	return true
+}
+
+// incrementReplyCount updates the reply count of the parent of a new reply.
+func (s *ChannelState) incrementReplyCount(reply *Message) {
+	i := indexMessage(s.messages, reply.ParentID)
+	if i < 0 {
+		return
+	}
+	parent := cloneMessage(s.messages[i])
+	parent.ReplyCount++
+	s.messages[i] = parent
+}
+
+// countNewMessage updates the channel and read counters for a new channel message.
+func (s *ChannelState) countNewMessage(msg *Message) {
+	meta := *s.channel
+	if msg.CreatedAt != nil && msg.CreatedAt.After(meta.LastMessageAt) {
+		meta.LastMessageAt = *msg.CreatedAt
+	}
+	if meta.MessageCount != nil {
+		count := *meta.MessageCount + 1
+		meta.MessageCount = &count
+	}
+	s.channel = &meta
+
+	author := msg.UserID
+	if msg.User != nil {
+		author = msg.User.ID
+	}
+	for i, r := range s.reads {
+		if r.User == nil {
+			continue
+		}
+		read := *r
+		if r.User.ID == author {
+			read.UnreadMessages = 0
+			if msg.CreatedAt != nil {
+				read.LastRead = *msg.CreatedAt
+			}
+		} else if !msg.Silent && msg.Type != MessageTypeSystem {
+			read.UnreadMessages++
+		}
+		s.reads[i] = &read
+	}
+}
+
+func (s *ChannelState) replaceMessage(msg *Message) bool {
+	msg = cloneMessage(msg)
+	replaced := false
+	if i := indexMessage(s.messages, msg.ID); i >= 0 {
+		s.messages[i] = msg
+		replaced = true
+	}
+	if replies := s.replies[msg.ParentID]; msg.ParentID != "" {
+		if i := indexMessage(replies, msg.ID); i >= 0 {
+			replies[i] = msg
+			replaced = true
+		}
+	}
+	return replaced
+}
+
+func (s *ChannelState) removeMessage(msg *Message) bool {
+	removed := false
+	if i := indexMessage(s.messages, msg.ID); i >= 0 {
+		s.messages = append(s.messages[:i:i], s.messages[i+1:]...)
+		removed = true
+	}
+	if replies := s.replies[msg.ParentID]; msg.ParentID != "" {
+		if i := indexMessage(replies, msg.ID); i >= 0 {
+			s.replies[msg.ParentID] = append(replies[:i:i], replies[i+1:]...)
+			removed = true
+		}
+	}
+	delete(s.replies, msg.ID)
+	return removed
+}
+
+func (s *ChannelState) markRead(e *Event) bool {
+	user := e.User
+	if user == nil && e.UserID != "" {
+		user = &User{ID: e.UserID}
+	}
+	if user == nil {
+		return false
+	}
+
+	read := &ChannelRead{User: user, LastRead: e.CreatedAt}
+	if i := indexRead(s.reads, user.ID); i >= 0 {
+		read.LastDeliveredAt = s.reads[i].LastDeliveredAt
+		read.LastDeliveredMessageID = s.reads[i].LastDeliveredMessageID
+		s.reads[i] = read
+	} else {
+		s.reads = append(s.reads, read)
+	}
+	return true
+}
+
+func (s *ChannelState) upsertMember(e *Event) bool {
+	member := e.Member
+	if member == nil {
+		return false
+	}
+	userID := memberUserID(member)
+
+	if i := indexMember(s.members, userID); i >= 0 {
+		s.members[i] = member
+		return true
+	}
+	s.members = append(s.members, member)
+
+	// other events may concern members outside the loaded page, only an
+	// added member changes the count
+	if e.Type == EventMemberAdded {
+		meta := *s.channel
+		meta.MemberCount++
+		s.channel = &meta
+	}
+	return true
+}
+
+func (s *ChannelState) removeMember(e *Event) bool {
+	userID := e.UserID
+	if e.Member != nil {
+		userID = memberUserID(e.Member)
+	} else if userID == "" && e.User != nil {
+		userID = e.User.ID
+	}
+
+	i := indexMember(s.members, userID)
+	if i < 0 {
+		return false
+	}
+	s.members = append(s.members[:i:i], s.members[i+1:]...)
+
+	meta := *s.channel
+	if meta.MemberCount > 0 {
+		meta.MemberCount--
+	}
+	s.channel = &meta
+	return true
+}
+
+func (s *ChannelState) updateChannel(ch *Channel) bool {
+	if ch == nil {
+		return false
+	}
+	meta := *ch
+	meta.Messages, meta.Members, meta.Read, meta.PinnedMessages, meta.Watchers = nil, nil, nil, nil, nil
+	meta.client = s.channel.client
+	s.channel = &meta
+	return true
+}
+
+// truncate removes the messages sent before the truncation time.
+func (s *ChannelState) truncate(e *Event) bool {
+	s.updateChannel(e.Channel)
+
+	// the truncation time of an earlier truncation must not be used for this one
+	truncatedAt := e.CreatedAt
+	if e.Channel != nil && e.Channel.TruncatedAt != nil {
+		truncatedAt = *e.Channel.TruncatedAt
+	}
+	s.channel.TruncatedAt = &truncatedAt
+
+	kept := s.messages[:0:0]
+	for _, m := range s.messages {
+		if m.CreatedAt != nil && m.CreatedAt.After(truncatedAt) {
+			kept = append(kept, m)
+		} else {
+			delete(s.replies, m.ID)
+		}
+	}
+	s.messages = kept
+
+	for i, r := range s.reads {
+		read := *r
+		read.UnreadMessages = 0
+		s.reads[i] = &read
+	}
+	return true
+}
+
+func (s *ChannelState) trim(messages []*Message) []*Message {
+	if s.opts.MaxMessages > 0 && len(messages) > s.opts.MaxMessages {
+		return append([]*Message(nil), messages[len(messages)-s.opts.MaxMessages:]...)
+	}
+	return messages
+}
+
+// cloneMessage copies a message so the state never shares values with events or callers.
+func cloneMessage(m *Message) *Message {
+	c := *m
+	if m.ReactionCounts != nil {
+		c.ReactionCounts = make(map[string]int, len(m.ReactionCounts))
+		for k, v := range m.ReactionCounts {
+			c.ReactionCounts[k] = v
+		}
+	}
+	if m.ReactionScores != nil {
+		c.ReactionScores = make(map[string]int, len(m.ReactionScores))
+		for k, v := range m.ReactionScores {
+			c.ReactionScores[k] = v
+		}
+	}
+	c.LatestReactions = append([]*Reaction(nil), m.LatestReactions...)
+	c.OwnReactions = append([]*Reaction(nil), m.OwnReactions...)
+	return &c
+}
+
+// insertMessage inserts m keeping messages sorted by creation time. Messages without
+// a creation time are appended.
+func insertMessage(messages []*Message, m *Message) []*Message {
+	if m.CreatedAt == nil {
+		return append(messages, m)
+	}
+	i := sort.Search(len(messages), func(i int) bool {
+		return messageTime(messages[i]).After(*m.CreatedAt)
+	})
+	messages = append(messages, nil)
+	copy(messages[i+1:], messages[i:])
+	messages[i] = m
+	return messages
+}
+
+func indexMessage(messages []*Message, id string) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexMember(members []*ChannelMember, userID string) int {
+	for i, m := range members {
+		if memberUserID(m) == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexRead(reads []*ChannelRead, userID string) int {
+	for i, r := range reads {
+		if r.User != nil && r.User.ID == userID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,198 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChannelState(t *testing.T, opts *ChannelStateOptions) (*ChannelState, time.Time) {
+	t.Helper()
+
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	at := func(min int) *time.Time {
+		ts := base.Add(time.Duration(min) * time.Minute)
+		return &ts
+	}
+	count := 2
+
+	ch := &Channel{
+		Type:         "messaging",
+		ID:           "general",
+		MemberCount:  2,
+		MessageCount: &count,
+		WatcherCount: 1,
+		Members:      []*ChannelMember{{UserID: "jane"}, {UserID: "bob"}},
+		Messages: []*Message{
+			{ID: "m2", Text: "second", User: &User{ID: "bob"}, CreatedAt: at(2)},
+			{ID: "m1", Text: "first", User: &User{ID: "jane"}, CreatedAt: at(1)},
+		},
+		Read: []*ChannelRead{
+			{User: &User{ID: "jane"}, LastRead: *at(1)},
+			{User: &User{ID: "bob"}, LastRead: *at(2)},
+		},
+	}
+	return NewChannelState(ch, opts), base
+}
+
+func messageIDs(messages []*Message) []string {
+	ids := make([]string, 0, len(messages))
+	for _, m := range messages {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func TestChannelState_Apply(t *testing.T) {
+	s, base := newTestChannelState(t, nil)
+	cid := "messaging:general"
+	at := func(min int) *time.Time {
+		ts := base.Add(time.Duration(min) * time.Minute)
+		return &ts
+	}
+
+	require.Equal(t, []string{"m1", "m2"}, messageIDs(s.Messages()))
+	before := s.Snapshot()
+
+	require.False(t, s.Apply(&Event{Type: EventMessageNew, CID: "messaging:other", Message: &Message{ID: "x"}}))
+	require.False(t, s.Apply(&Event{Type: EventTypingStart, CID: cid}))
+
+	// new messages
+	require.True(t, s.Apply(&Event{Type: EventMessageNew, CID: cid, WatcherCount: 3,
+		Message: &Message{ID: "m3", Text: "third", User: &User{ID: "jane"}, CreatedAt: at(3)}}))
+	require.True(t, s.Apply(&Event{Type: EventMessageNew, CID: cid,
+		Message: &Message{ID: "r1", Text: "reply", ParentID: "m1", User: &User{ID: "bob"}, CreatedAt: at(4)}}))
+	require.Equal(t, []string{"m1", "m2", "m3"}, messageIDs(s.Messages()))
+	require.Equal(t, []string{"r1"}, messageIDs(s.Replies("m1")))
+	require.Equal(t, 1, s.Message("m1").ReplyCount)
+	require.Equal(t, "reply", s.Message("r1").Text)
+	require.Equal(t, 3, s.WatcherCount())
+	require.Equal(t, 0, s.Read("jane").UnreadMessages)
+	require.Equal(t, 1, s.Read("bob").UnreadMessages)
+
+	// reactions carry the updated message
+	require.True(t, s.Apply(&Event{Type: EventReactionNew, CID: cid,
+		Reaction: &Reaction{MessageID: "m3", UserID: "bob", Type: "like"},
+		Message:  &Message{ID: "m3", Text: "third", CreatedAt: at(3), ReactionCounts: map[string]int{"like": 1}}}))
+	require.Equal(t, 1, s.Message("m3").ReactionCounts["like"])
+
+	// updates and deletes
+	require.True(t, s.Apply(&Event{Type: EventMessageUpdated, CID: cid,
+		Message: &Message{ID: "m2", Text: "edited", CreatedAt: at(2), Pinned: true}}))
+	require.Equal(t, "edited", s.Message("m2").Text)
+	require.True(t, s.Apply(&Event{Type: EventMessageDeleted, CID: cid,
+		Message: &Message{ID: "r1", ParentID: "m1", CreatedAt: at(4)}, ExtraData: map[string]interface{}{"hard_delete": true}}))
+	require.Empty(t, s.Replies("m1"))
+
+	// reads
+	require.True(t, s.Apply(&Event{Type: EventMessageRead, CID: cid, User: &User{ID: "bob"}, CreatedAt: *at(5)}))
+	require.Equal(t, 0, s.Read("bob").UnreadMessages)
+	require.Equal(t, *at(5), s.Read("bob").LastRead)
+
+	// members
+	require.True(t, s.Apply(&Event{Type: EventMemberAdded, CID: cid, Member: &ChannelMember{UserID: "sam", ChannelRole: "channel_member"}}))
+	require.True(t, s.Apply(&Event{Type: EventMemberUpdated, CID: cid, Member: &ChannelMember{UserID: "sam", ChannelRole: "channel_moderator"}}))
+	require.True(t, s.Apply(&Event{Type: EventMemberRemoved, CID: cid, Member: &ChannelMember{UserID: "jane"}}))
+	require.Nil(t, s.Member("jane"))
+	require.Equal(t, "channel_moderator", s.Member("sam").ChannelRole)
+	// an update of a member outside the loaded page does not change the count
+	require.True(t, s.Apply(&Event{Type: EventMemberUpdated, CID: cid, Member: &ChannelMember{UserID: "kim", Banned: true}}))
+	require.True(t, s.Member("kim").Banned)
+
+	snap := s.Snapshot()
+	require.Equal(t, 2, snap.MemberCount)
+	require.Equal(t, 3, *snap.MessageCount)
+	require.Equal(t, *at(3), snap.LastMessageAt)
+	require.Equal(t, []string{"m2"}, messageIDs(snap.PinnedMessages))
+
+	// earlier snapshots are not affected by events
+	require.Len(t, before.Messages, 2)
+	require.Equal(t, "second", before.Messages[1].Text)
+	require.Equal(t, 0, before.Messages[0].ReplyCount)
+	require.Equal(t, 2, *before.MessageCount)
+
+	// channel updates keep the messages
+	require.True(t, s.Apply(&Event{Type: EventChannelUpdated, CID: cid,
+		Channel: &Channel{Type: "messaging", ID: "general", MemberCount: 2, ExtraData: map[string]interface{}{"color": "blue"}}}))
+	require.Equal(t, "blue", s.Snapshot().ExtraData["color"])
+	require.Len(t, s.Messages(), 3)
+
+	// truncation removes messages up to the truncation time
+	require.True(t, s.Apply(&Event{Type: EventChannelTruncated, CID: cid,
+		Channel: &Channel{Type: "messaging", ID: "general", MemberCount: 2, TruncatedAt: at(2)}}))
+	require.Equal(t, []string{"m3"}, messageIDs(s.Messages()))
+
+	// a later truncation without the channel uses the event time, not the earlier truncation
+	require.True(t, s.Apply(&Event{Type: EventMessageNew, CID: cid, Message: &Message{ID: "m4", CreatedAt: at(5)}}))
+	require.True(t, s.Apply(&Event{Type: EventMessageNew, CID: cid, Message: &Message{ID: "m5", CreatedAt: at(7)}}))
+	require.True(t, s.Apply(&Event{Type: EventChannelTruncated, CID: cid, CreatedAt: *at(6)}))
+	require.Equal(t, []string{"m5"}, messageIDs(s.Messages()))
+	require.Equal(t, *at(6), *s.Snapshot().TruncatedAt)
+
+	require.True(t, s.Apply(&Event{Type: EventUserWatchingStop, CID: cid, WatcherCount: 0}))
+	require.Equal(t, 0, s.WatcherCount())
+
+	require.True(t, s.Apply(&Event{Type: EventChannelDeleted, CID: cid, Channel: &Channel{Type: "messaging", ID: "general"}}))
+	require.True(t, s.Deleted())
+	require.Empty(t, s.Messages())
+}
+
+func TestChannelState_MaxMessages(t *testing.T) {
+	s, base := newTestChannelState(t, &ChannelStateOptions{MaxMessages: 2})
+
+	ts := base.Add(time.Hour)
+	s.Apply(&Event{Type: EventMessageNew, CID: "messaging:general", Message: &Message{ID: "m3", CreatedAt: &ts}})
+	require.Equal(t, []string{"m2", "m3"}, messageIDs(s.Messages()))
+}
+
+func TestChannelState_Concurrency(t *testing.T) {
+	s, base := newTestChannelState(t, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ts := base.Add(time.Duration(i*100+j) * time.Second)
+				s.Apply(&Event{Type: EventMessageNew, CID: "messaging:general",
+					Message: &Message{ID: time.Duration(i*100 + j).String(), User: &User{ID: "jane"}, CreatedAt: &ts}})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				snap := s.Snapshot()
+				require.NotNil(t, snap)
+				_ = s.Read("bob")
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, s.Messages(), 202)
+	require.Equal(t, 200, s.Read("bob").UnreadMessages)
+}
+
+func TestChannel_LoadState(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/channels/messaging/general/query", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"channel":  map[string]interface{}{"id": "general", "type": "messaging", "cid": "messaging:general", "member_count": 1},
+			"members":  []map[string]interface{}{{"user_id": "jane"}},
+			"messages": []map[string]interface{}{{"id": "m1", "text": "hi", "created_at": "2024-01-01T10:00:00Z"}},
+			"read":     []map[string]interface{}{{"user": map[string]interface{}{"id": "jane"}, "unread_messages": 1}},
+		})
+	}))
+
+	s, err := c.Channel("messaging", "general").LoadState(context.Background(), nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"m1"}, messageIDs(s.Messages()))
+	require.NotNil(t, s.Member("jane"))
+	require.Equal(t, 1, s.Read("jane").UnreadMessages)
+}
@@ -0,0 +1,464 @@
+package stream_chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChannelTypeSpec is the desired state of the channel types of an app, usually kept in
+// a YAML or JSON file. Only the settings declared in the spec are managed: settings,
+// grant roles and lists that are left out are not changed.
+//
+//	channel_types:
+//	  - name: support
+//	    typing_events: true
+//	    max_message_length: 2000
+//	    commands: [giphy]
+//	    grants:
+//	      channel_member: [read-channel, create-message]
+type ChannelTypeSpec struct {
+	ChannelTypes []*ChannelTypeDefinition `json:"channel_types"`
+	// Prune deletes the channel types which are not in the spec. Built-in channel types are never deleted.
+	Prune bool `json:"prune,omitempty"`
+}
+
+// ChannelTypeDefinition is the desired state of a channel type.
+type ChannelTypeDefinition struct {
+	Name string
+	// Settings are ChannelConfig fields by JSON name, such as "typing_events" or "max_message_length".
+	Settings map[string]interface{}
+	// Commands are the command names. Nil leaves the commands unmanaged.
+	Commands []string
+	// Grants are the permissions granted per role. Roles which are left out are not changed.
+	Grants map[string][]string
+	// Permissions are the legacy channel type permissions. Nil leaves them unmanaged.
+	Permissions []*ChannelTypePermission
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Settings are declared next to the name.
+func (d *ChannelTypeDefinition) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*d = ChannelTypeDefinition{Settings: make(map[string]interface{})}
+	for key, raw := range fields {
+		var err error
+		switch key {
+		case "name":
+			err = json.Unmarshal(raw, &d.Name)
+		case "commands":
+			err = json.Unmarshal(raw, &d.Commands)
+			if err == nil && d.Commands == nil {
+				d.Commands = []string{}
+			}
+		case "grants":
+			err = json.Unmarshal(raw, &d.Grants)
+		case "permissions":
+			err = json.Unmarshal(raw, &d.Permissions)
+			if err == nil && d.Permissions == nil {
+				d.Permissions = []*ChannelTypePermission{}
+			}
+		default:
+			var v interface{}
+			err = json.Unmarshal(raw, &v)
+			d.Settings[key] = v
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d ChannelTypeDefinition) MarshalJSON() ([]byte, error) {
+	m := copyMap(d.Settings)
+	m["name"] = d.Name
+	if d.Commands != nil {
+		m["commands"] = d.Commands
+	}
+	if d.Grants != nil {
+		m["grants"] = d.Grants
+	}
+	if d.Permissions != nil {
+		m["permissions"] = d.Permissions
+	}
+	return json.Marshal(m)
+}
+
+// ParseChannelTypeSpec parses a channel type spec in JSON or YAML and validates it.
+func ParseChannelTypeSpec(data []byte) (*ChannelTypeSpec, error) {
+	data, err := specToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec ChannelTypeSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// LoadChannelTypeSpec reads a channel type spec from a JSON or YAML file.
+func LoadChannelTypeSpec(filename string) (*ChannelTypeSpec, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseChannelTypeSpec(data)
+}
+
+// specToJSON converts YAML documents to JSON, so specs are decoded with the JSON field names.
+func specToJSON(data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return trimmed, nil
+	}
+
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// channelConfigFields are the JSON names of the ChannelConfig settings.
+var channelConfigFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(ChannelConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "name" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// Validate checks the spec for missing and duplicated names and unknown or invalid settings.
+func (s *ChannelTypeSpec) Validate() error {
+	seen := make(map[string]bool, len(s.ChannelTypes))
+	for i, def := range s.ChannelTypes {
+		if def == nil || def.Name == "" {
+			return fmt.Errorf("channel type #%d: name must be not empty", i+1)
+		}
+		if seen[def.Name] {
+			return fmt.Errorf("channel type %q: duplicated", def.Name)
+		}
+		seen[def.Name] = true
+
+		for key := range def.Settings {
+			if !channelConfigFields[key] {
+				return fmt.Errorf("channel type %q: unknown setting %q", def.Name, key)
+			}
+		}
+		if _, err := def.config(ChannelConfig{}); err != nil {
+			return fmt.Errorf("channel type %q: %w", def.Name, err)
+		}
+	}
+	return nil
+}
+
+// config applies the declared settings on top of base.
+func (d *ChannelTypeDefinition) config(base ChannelConfig) (ChannelConfig, error) {
+	data, err := json.Marshal(d.Settings)
+	if err != nil {
+		return base, err
+	}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return base, err
+	}
+	base.Name = d.Name
+	return base, nil
+}
+
+// FieldChange is the change of a single field found when comparing a desired state with the current one.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+func (f FieldChange) String() string {
+	return fmt.Sprintf("%s: %s => %s", f.Field, formatFieldValue(f.From), formatFieldValue(f.To))
+}
+
+func formatFieldValue(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// ChangeAction is the kind of change of a plan.
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+var changeSymbols = map[ChangeAction]string{ChangeCreate: "+", ChangeUpdate: "~", ChangeDelete: "-"}
+
+// ChannelTypeChange is a change of a single channel type.
+type ChannelTypeChange struct {
+	Action ChangeAction   `json:"action"`
+	Name   string         `json:"name"`
+	Fields []*FieldChange `json:"fields,omitempty"`
+
+	definition *ChannelTypeDefinition
+	update     map[string]interface{}
+}
+
+// ChannelTypePlan is the list of changes needed to reach a ChannelTypeSpec.
+type ChannelTypePlan struct {
+	Changes []*ChannelTypeChange `json:"changes"`
+}
+
+// Empty reports whether the channel types already match the spec.
+func (p *ChannelTypePlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String formats the plan for humans, one line per channel type and changed field.
+func (p *ChannelTypePlan) String() string {
+	if p.Empty() {
+		return "No changes. Channel types match the spec.\n"
+	}
+
+	var sb strings.Builder
+	for _, c := range p.Changes {
+		fmt.Fprintf(&sb, "%s %s channel type %q\n", changeSymbols[c.Action], c.Action, c.Name)
+		for _, f := range c.Fields {
+			if c.Action == ChangeCreate {
+				fmt.Fprintf(&sb, "    %s = %s\n", f.Field, formatFieldValue(f.To))
+				continue
+			}
+			fmt.Fprintf(&sb, "    %s\n", f)
+		}
+	}
+	return sb.String()
+}
+
+// PlanChannelTypes compares the spec with the channel types returned by ListChannelTypes
+// and returns the changes needed to apply it. Nothing is changed.
+func (c *Client) PlanChannelTypes(ctx context.Context, spec *ChannelTypeSpec) (*ChannelTypePlan, error) {
+	if spec == nil {
+		return nil, errors.New("channel type spec is nil")
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.ListChannelTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return planChannelTypes(spec, resp.ChannelTypes)
+}
+
+func planChannelTypes(spec *ChannelTypeSpec, current map[string]*ChannelType) (*ChannelTypePlan, error) {
+	plan := &ChannelTypePlan{}
+	desired := make(map[string]bool, len(spec.ChannelTypes))
+
+	for _, def := range spec.ChannelTypes {
+		desired[def.Name] = true
+
+		ct, ok := current[def.Name]
+		if !ok || ct == nil {
+			change := &ChannelTypeChange{Action: ChangeCreate, Name: def.Name, definition: def}
+			for _, key := range sortedKeys(def.Settings) {
+				change.Fields = append(change.Fields, &FieldChange{Field: key, To: def.Settings[key]})
+			}
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+
+		change, err := diffChannelType(def, ct)
+		if err != nil {
+			return nil, fmt.Errorf("channel type %q: %w", def.Name, err)
+		}
+		if change != nil {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	if spec.Prune {
+		var names []string
+		for name := range current {
+			if !desired[name] && !isBuiltinChannelType(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			plan.Changes = append(plan.Changes, &ChannelTypeChange{Action: ChangeDelete, Name: name})
+		}
+	}
+	return plan, nil
+}
+
+func isBuiltinChannelType(name string) bool {
+	for _, t := range defaultChannelTypes {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// diffChannelType returns the update needed for ct to match def, or nil if it matches.
+func diffChannelType(def *ChannelTypeDefinition, ct *ChannelType) (*ChannelTypeChange, error) {
+	change := &ChannelTypeChange{Action: ChangeUpdate, Name: def.Name, update: make(map[string]interface{})}
+
+	want, err := def.config(ChannelConfig{})
+	if err != nil {
+		return nil, err
+	}
+	wantFields, err := toJSONMap(want)
+	if err != nil {
+		return nil, err
+	}
+	haveFields, err := toJSONMap(ct.ChannelConfig)
+	if err != nil {
+		return nil, err
+	}
+	// settings omitted when empty, such as user_message_reminders, are missing from the
+	// encoded configs when turned off, so the spec values are used for them
+	specFields, err := toJSONMap(def.Settings)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range sortedKeys(def.Settings) {
+		want, ok := wantFields[key]
+		if !ok {
+			want = specFields[key]
+		}
+		have, ok := haveFields[key]
+		if !ok && isZeroJSONValue(want) {
+			continue
+		}
+		if !reflect.DeepEqual(want, have) {
+			change.Fields = append(change.Fields, &FieldChange{Field: key, From: have, To: want})
+			change.update[key] = want
+		}
+	}
+
+	if def.Commands != nil {
+		have := make([]string, 0, len(ct.Commands))
+		for _, cmd := range ct.Commands {
+			have = append(have, cmd.Name)
+		}
+		if !sameStringSet(have, def.Commands) {
+			change.Fields = append(change.Fields, &FieldChange{Field: "commands", From: sortedStrings(have), To: sortedStrings(def.Commands)})
+			change.update["commands"] = def.Commands
+		}
+	}
+
+	grants := make(map[string][]string)
+	for _, role := range sortedKeys(def.Grants) {
+		have, want := ct.Grants[role], def.Grants[role]
+		if !sameStringSet(have, want) {
+			change.Fields = append(change.Fields, &FieldChange{Field: "grants." + role, From: sortedStrings(have), To: sortedStrings(want)})
+			grants[role] = want
+		}
+	}
+	if len(grants) > 0 {
+		change.update["grants"] = grants
+	}
+
+	if def.Permissions != nil && !samePermissions(ct.Permissions, def.Permissions) {
+		change.Fields = append(change.Fields, &FieldChange{Field: "permissions", From: ct.Permissions, To: def.Permissions})
+		change.update["permissions"] = def.Permissions
+	}
+
+	if len(change.Fields) == 0 {
+		return nil, nil
+	}
+	return change, nil
+}
+
+// ApplyChannelTypePlan applies the changes of a plan in order and stops at the first error.
+func (c *Client) ApplyChannelTypePlan(ctx context.Context, plan *ChannelTypePlan) error {
+	if plan == nil {
+		return errors.New("channel type plan is nil")
+	}
+
+	for _, change := range plan.Changes {
+		var err error
+		switch change.Action {
+		case ChangeCreate:
+			err = c.createChannelTypeFromDefinition(ctx, change.definition)
+		case ChangeUpdate:
+			_, err = c.UpdateChannelType(ctx, change.Name, change.update)
+		case ChangeDelete:
+			_, err = c.DeleteChannelType(ctx, change.Name)
+		default:
+			err = fmt.Errorf("unknown action %q", change.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("%s channel type %q: %w", change.Action, change.Name, err)
+		}
+	}
+	return nil
+}
+
+// ApplyChannelTypes plans the spec and applies the changes. The plan is returned
+// even if applying it failed.
+func (c *Client) ApplyChannelTypes(ctx context.Context, spec *ChannelTypeSpec) (*ChannelTypePlan, error) {
+	plan, err := c.PlanChannelTypes(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	return plan, c.ApplyChannelTypePlan(ctx, plan)
+}
+
+func (c *Client) createChannelTypeFromDefinition(ctx context.Context, def *ChannelTypeDefinition) error {
+	if def == nil {
+		return errors.New("channel type definition is nil")
+	}
+
+	cfg, err := def.config(DefaultChannelConfig)
+	if err != nil {
+		return err
+	}
+	ct := &ChannelType{ChannelConfig: cfg, Grants: def.Grants, Permissions: def.Permissions}
+	req := ct.toRequest()
+	// toRequest enables all commands when there are none, but an empty list disables them
+	if def.Commands != nil {
+		req.Commands = def.Commands
+	}
+
+	var resp ChannelTypeResponse
+	return c.makeRequest(ctx, http.MethodPost, "channeltypes", nil, req, &resp)
+}
+
+func samePermissions(a, b []*ChannelTypePermission) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(x, y)
+}
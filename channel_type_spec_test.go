@@ -0,0 +1,184 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const channelTypeSpecYAML = `
+prune: true
+channel_types:
+  - name: messaging
+    typing_events: true
+    max_message_length: 2000
+    partition_ttl: 24h
+    commands: [giphy, imgur]
+    grants:
+      channel_member: [read-channel, create-message]
+  - name: support
+    reactions: false
+    commands: []
+`
+
+type fakeChannelTypeServer struct {
+	mu       sync.Mutex
+	t        *testing.T
+	types    map[string]interface{}
+	requests []string
+	bodies   []map[string]interface{}
+}
+
+func (s *fakeChannelTypeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		var body map[string]interface{}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		s.bodies = append(s.bodies, body)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/channeltypes":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"channel_types": s.types})
+	case r.Method == http.MethodPost:
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"name": "support", "commands": []string{}})
+	default:
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+func newFakeChannelTypeServer(t *testing.T) *fakeChannelTypeServer {
+	return &fakeChannelTypeServer{t: t, types: map[string]interface{}{
+		"messaging": map[string]interface{}{
+			"name": "messaging", "typing_events": true, "max_message_length": 5000, "partition_ttl": "24h0m0s",
+			"commands": []map[string]interface{}{{"name": "imgur"}, {"name": "giphy"}},
+			"grants":   map[string][]string{"channel_member": {"read-channel"}, "admin": {"delete-channel"}},
+		},
+		"livestream": map[string]interface{}{"name": "livestream"},
+		"legacy":     map[string]interface{}{"name": "legacy"},
+	}}
+}
+
+func TestParseChannelTypeSpec(t *testing.T) {
+	spec, err := ParseChannelTypeSpec([]byte(channelTypeSpecYAML))
+	require.NoError(t, err)
+	require.True(t, spec.Prune)
+	require.Len(t, spec.ChannelTypes, 2)
+	require.Equal(t, "messaging", spec.ChannelTypes[0].Name)
+	require.Equal(t, map[string]interface{}{"typing_events": true, "max_message_length": float64(2000), "partition_ttl": "24h"}, spec.ChannelTypes[0].Settings)
+	require.Equal(t, []string{"giphy", "imgur"}, spec.ChannelTypes[0].Commands)
+	require.NotNil(t, spec.ChannelTypes[1].Commands)
+	require.Nil(t, spec.ChannelTypes[1].Grants)
+
+	// the JSON form round trips
+	data, err := json.Marshal(spec)
+	require.NoError(t, err)
+	spec2, err := ParseChannelTypeSpec(data)
+	require.NoError(t, err)
+	require.Equal(t, spec, spec2)
+
+	for _, tc := range []struct {
+		spec string
+		err  string
+	}{
+		{`{"channel_types": [{"typing_events": true}]}`, "channel type #1: name must be not empty"},
+		{`{"channel_types": [{"name": "a"}, {"name": "a"}]}`, `channel type "a": duplicated`},
+		{`{"channel_types": [{"name": "a", "typing": true}]}`, `channel type "a": unknown setting "typing"`},
+		{"channel_types:\n  - name: a\n    max_message_length: long", `channel type "a": json: cannot unmarshal string`},
+		{"channel_types: [", "yaml:"},
+	} {
+		_, err := ParseChannelTypeSpec([]byte(tc.spec))
+		require.Error(t, err, tc.spec)
+		require.Contains(t, err.Error(), tc.err)
+	}
+}
+
+func TestClient_PlanAndApplyChannelTypes(t *testing.T) {
+	ctx := context.Background()
+	spec, err := ParseChannelTypeSpec([]byte(channelTypeSpecYAML))
+	require.NoError(t, err)
+
+	srv := newFakeChannelTypeServer(t)
+	c := newTestClient(t, srv)
+
+	plan, err := c.PlanChannelTypes(ctx, spec)
+	require.NoError(t, err)
+	require.Equal(t, []string{"GET /channeltypes"}, srv.requests)
+	require.Equal(t, `~ update channel type "messaging"
+    max_message_length: 5000 => 2000
+    grants.channel_member: ["read-channel"] => ["create-message","read-channel"]
++ create channel type "support"
+    reactions = false
+- delete channel type "legacy"
+`, plan.String())
+
+	require.NoError(t, c.ApplyChannelTypePlan(ctx, plan))
+	require.Equal(t, []string{
+		"GET /channeltypes",
+		"PUT /channeltypes/messaging",
+		"POST /channeltypes",
+		"DELETE /channeltypes/legacy",
+	}, srv.requests)
+
+	require.Equal(t, map[string]interface{}{
+		"max_message_length": float64(2000),
+		"grants":             map[string]interface{}{"channel_member": []interface{}{"read-channel", "create-message"}},
+	}, srv.bodies[0])
+
+	created := srv.bodies[1]
+	require.Equal(t, "support", created["name"])
+	require.Equal(t, false, created["reactions"])
+	require.Equal(t, true, created["push_notifications"])
+	// commands: [] creates the type without commands
+	require.Equal(t, []interface{}{}, created["commands"])
+
+	t.Run("default commands", func(t *testing.T) {
+		srv := newFakeChannelTypeServer(t)
+		c := newTestClient(t, srv)
+		spec, err := ParseChannelTypeSpec([]byte(`{"channel_types": [{"name": "messaging", "typing_events": true,
+			"commands": ["giphy", "imgur"]}, {"name": "support"}]}`))
+		require.NoError(t, err)
+
+		_, err = c.ApplyChannelTypes(ctx, spec)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"all"}, srv.bodies[len(srv.bodies)-1]["commands"])
+	})
+
+	t.Run("settings turned off", func(t *testing.T) {
+		srv := newFakeChannelTypeServer(t)
+		srv.types["messaging"].(map[string]interface{})["user_message_reminders"] = true
+		srv.types["messaging"].(map[string]interface{})["partition_size"] = 10
+		c := newTestClient(t, srv)
+		spec, err := ParseChannelTypeSpec([]byte(`{"channel_types": [{"name": "messaging", "typing_events": true,
+			"user_message_reminders": false, "partition_size": 0, "skip_last_msg_update_for_system_msgs": false}]}`))
+		require.NoError(t, err)
+
+		plan, err := c.PlanChannelTypes(ctx, spec)
+		require.NoError(t, err)
+		require.Equal(t, `~ update channel type "messaging"
+    partition_size: 10 => 0
+    user_message_reminders: true => false
+`, plan.String())
+
+		require.NoError(t, c.ApplyChannelTypePlan(ctx, plan))
+		require.Equal(t, map[string]interface{}{"user_message_reminders": false, "partition_size": float64(0)}, srv.bodies[0])
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		spec, err := ParseChannelTypeSpec([]byte(`{"channel_types": [{"name": "messaging", "typing_events": true, "commands": ["giphy", "imgur"]}]}`))
+		require.NoError(t, err)
+
+		plan, err := c.PlanChannelTypes(ctx, spec)
+		require.NoError(t, err)
+		require.True(t, plan.Empty())
+		require.True(t, strings.HasPrefix(plan.String(), "No changes."))
+	})
+}
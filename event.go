@@ -26,6 +26,8 @@ const (
 
 	// EventReactionNew is fired when a message reaction is added.
 	EventReactionNew EventType = "reaction.new"
+	// EventReactionUpdated is fired when a message reaction is updated.
+	EventReactionUpdated EventType = "reaction.updated"
 	// EventReactionDeleted is fired when a message reaction deleted.
 	EventReactionDeleted EventType = "reaction.deleted"
 
@@ -72,6 +74,19 @@ const (
 	EventUserUpdated         EventType = "user.updated"
 
 	EventUserUnreadMessageReminder EventType = "user.unread_message_reminder"
+
+	// EventPollUpdated and family are fired when a poll or its votes change.
+	EventPollUpdated     EventType = "poll.updated"
+	EventPollClosed      EventType = "poll.closed"
+	EventPollDeleted     EventType = "poll.deleted"
+	EventPollVoteCasted  EventType = "poll.vote_casted"
+	EventPollVoteChanged EventType = "poll.vote_changed"
+	EventPollVoteRemoved EventType = "poll.vote_removed"
+
+	// EventCampaignStarted and EventCampaignCompleted are sent to webhooks
+	// when a campaign starts and completes.
+	EventCampaignStarted   EventType = "campaign.started"
+	EventCampaignCompleted EventType = "campaign.completed"
 )
 
 // Event is received from a webhook, or sent with the SendEvent function.
@@ -88,6 +103,9 @@ type Event struct {
 	OwnUser      *User            `json:"me,omitempty"`
 	WatcherCount int              `json:"watcher_count,omitempty"`
 	DeletedForMe bool             `json:"deleted_for_me,omitempty"`
+	Poll         *Poll            `json:"poll,omitempty"`
+	PollVote     *PollVote        `json:"poll_vote,omitempty"`
+	Campaign     *Campaign        `json:"campaign,omitempty"`
 
 	ExtraData map[string]interface{} `json:"-"`
 
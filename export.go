@@ -0,0 +1,606 @@
+package stream_chat
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// ExportRecordType is the kind of entity carried by an ExportRecord.
+type ExportRecordType string
+
+const (
+	ExportRecordChannel  ExportRecordType = "channel"
+	ExportRecordMember   ExportRecordType = "member"
+	ExportRecordMessage  ExportRecordType = "message"
+	ExportRecordReaction ExportRecordType = "reaction"
+	ExportRecordUser     ExportRecordType = "user"
+)
+
+// ExportRecord is a single entity read from a channel or user export.
+// Exactly one of the entity fields is set for known record types;
+// records of other types (e.g. calls in user exports) only carry Raw.
+type ExportRecord struct {
+	Type ExportRecordType
+	// ChannelCID is the channel the record belongs to, when it is known.
+	ChannelCID string
+
+	Channel  *Channel
+	Member   *ChannelMember
+	Message  *Message
+	Reaction *Reaction
+	User     *User
+
+	// Raw is the undecoded JSON of the entity.
+	Raw json.RawMessage
+}
+
+// ExportFetcher opens the export file located at url.
+type ExportFetcher func(ctx context.Context, url string) (io.ReadCloser, error)
+
+type exportOptions struct {
+	fetcher ExportFetcher
+}
+
+type ExportOption func(*exportOptions)
+
+// WithExportFetcher replaces the HTTP download of export files, for example
+// to read them from disk or serve them from a test server.
+func WithExportFetcher(fetcher ExportFetcher) ExportOption {
+	return func(o *exportOptions) {
+		o.fetcher = fetcher
+	}
+}
+
+// OpenExport downloads the export produced by a completed ExportChannels or ExportUsers
+// task and returns a reader that decodes it incrementally. The reader must be closed.
+func (c *Client) OpenExport(ctx context.Context, task *TaskResponse, options ...ExportOption) (*ExportReader, error) {
+	if task == nil {
+		return nil, errors.New("task must not be nil")
+	}
+	if task.Status != TaskStatusCompleted {
+		return nil, fmt.Errorf("task %s is %s, not completed", task.TaskID, task.Status)
+	}
+
+	res, err := task.ExportResult()
+	if err != nil {
+		return nil, err
+	}
+	return c.OpenExportURL(ctx, res.URL, options...)
+}
+
+// OpenExportURL downloads the export file at exportURL and returns a reader that decodes
+// it incrementally. The reader must be closed.
+func (c *Client) OpenExportURL(ctx context.Context, exportURL string, options ...ExportOption) (*ExportReader, error) {
+	if exportURL == "" {
+		return nil, errors.New("export URL must not be empty")
+	}
+
+	opts := exportOptions{fetcher: c.fetchExport}
+	for _, fn := range options {
+		fn(&opts)
+	}
+
+	body, err := opts.fetcher(ctx, exportURL)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewExportReader(body)
+	if err != nil {
+		_ = body.Close()
+		return nil, err
+	}
+	r.closer = body
+	return r, nil
+}
+
+// fetchExport downloads an export file. Export URLs are pre-signed, so no
+// API credentials are sent, and the client timeout is not applied since
+// exports can be large.
+func (c *Client) fetchExport(ctx context.Context, exportURL string) (io.ReadCloser, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, exportURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	hc := &http.Client{Transport: c.HTTP.Transport}
+	resp, err := hc.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("cannot download export: unexpected status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+type exportFormat int
+
+const (
+	exportFormatDocument exportFormat = iota // v1, a single JSON document
+	exportFormatLines                        // v2 and user exports, one entity per line
+)
+
+var exportLinePrefix = regexp.MustCompile(`^\s*\{\s*"type"\s*:`)
+
+// ExportReader decodes channel and user exports one entity at a time, without
+// loading the whole export in memory. Both the v1 document format and the
+// line-separated v2 format are supported, optionally gzip compressed.
+type ExportReader struct {
+	format exportFormat
+	lines  *bufio.Reader
+	dec    *json.Decoder
+	closer io.Closer
+
+	// state of the v1 document walk
+	stack   []exportFrame
+	pending []*ExportRecord
+	done    bool
+}
+
+type exportFrame struct {
+	key     string // array being iterated: channels, members, messages, reactions, users
+	channel *exportChannelState
+}
+
+type exportChannelState struct {
+	fields  map[string]json.RawMessage
+	channel *Channel
+}
+
+// NewExportReader returns a reader decoding the export read from r.
+func NewExportReader(r io.Reader) (*ExportReader, error) {
+	br := bufio.NewReader(r)
+
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+
+	if exportLinePrefix.Match(head) {
+		return &ExportReader{format: exportFormatLines, lines: br}, nil
+	}
+	return &ExportReader{format: exportFormatDocument, dec: json.NewDecoder(br)}, nil
+}
+
+// Close closes the underlying export download, if any.
+func (r *ExportReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Next returns the next record of the export, or io.EOF when all records were read.
+//
+// In v1 exports, channel fields that follow the member and message arrays of a channel
+// are only known after its channel record was returned. Such late fields are applied to
+// the returned Channel in place, so it reaches its final state once the next channel (or
+// io.EOF) is read; callers that persist channel records immediately may miss them.
+func (r *ExportReader) Next() (*ExportRecord, error) {
+	if r.format == exportFormatLines {
+		return r.nextLine()
+	}
+	return r.nextDocumentRecord()
+}
+
+// ForEach calls fn for every remaining record of the export.
+func (r *ExportReader) ForEach(fn func(*ExportRecord) error) error {
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *ExportReader) nextLine() (*ExportRecord, error) {
+	for {
+		line, err := r.lines.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		var item map[string]json.RawMessage
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("cannot decode export line: %w", err)
+		}
+
+		var typ string
+		if err := json.Unmarshal(item["type"], &typ); err != nil {
+			return nil, fmt.Errorf("export line has no valid type: %w", err)
+		}
+
+		data, ok := item["data"]
+		if !ok {
+			data, ok = item[typ]
+		}
+		if !ok {
+			data = line
+		}
+		return newExportRecord(ExportRecordType(typ), data, "")
+	}
+}
+
+func newExportRecord(typ ExportRecordType, data json.RawMessage, channelCID string) (*ExportRecord, error) {
+	rec := &ExportRecord{Type: typ, ChannelCID: channelCID, Raw: data}
+
+	var err error
+	switch typ {
+	case ExportRecordChannel:
+		rec.Channel = &Channel{}
+		err = json.Unmarshal(data, rec.Channel)
+		if err == nil {
+			rec.ChannelCID = rec.Channel.cid()
+		}
+	case ExportRecordMember:
+		rec.Member = &ChannelMember{}
+		err = json.Unmarshal(data, rec.Member)
+		if err == nil && rec.ChannelCID == "" {
+			rec.ChannelCID = cidFromExtraData(rec.Member.ExtraData)
+		}
+	case ExportRecordMessage:
+		rec.Message = &Message{}
+		err = json.Unmarshal(data, rec.Message)
+		if err == nil && rec.ChannelCID == "" {
+			rec.ChannelCID = rec.Message.CID
+			if rec.ChannelCID == "" {
+				rec.ChannelCID = cidFromExtraData(rec.Message.ExtraData)
+			}
+		}
+	case ExportRecordReaction:
+		rec.Reaction = &Reaction{}
+		err = json.Unmarshal(data, rec.Reaction)
+		if err == nil && rec.ChannelCID == "" {
+			rec.ChannelCID = cidFromExtraData(rec.Reaction.ExtraData)
+		}
+	case ExportRecordUser:
+		rec.User = &User{}
+		err = json.Unmarshal(data, rec.User)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode export %s: %w", typ, err)
+	}
+	return rec, nil
+}
+
+func cidFromExtraData(m map[string]interface{}) string {
+	for _, key := range []string{"cid", "channel_cid"} {
+		if cid, ok := m[key].(string); ok && cid != "" {
+			return cid
+		}
+	}
+	chType, _ := m["channel_type"].(string)
+	chID, _ := m["channel_id"].(string)
+	if chType != "" && chID != "" {
+		return chType + ":" + chID
+	}
+	return ""
+}
+
+// nextDocumentRecord walks the v1 export document:
+//
+//	{"channels": [{"channel": {...}, "members": [...], "messages": [...]}], "users": [...]}
+//
+// Channel fields may also be inlined in the channel element instead of nested under "channel".
+// Member, message and reaction arrays are streamed element by element.
+func (r *ExportReader) nextDocumentRecord() (*ExportRecord, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return nil, io.EOF
+		}
+		if err := r.step(); err != nil {
+			return nil, err
+		}
+	}
+
+	rec := r.pending[0]
+	r.pending = r.pending[1:]
+	return rec, nil
+}
+
+func (r *ExportReader) step() error {
+	if r.stack == nil {
+		if err := expectDelim(r.dec, '{'); err != nil {
+			return err
+		}
+		r.stack = []exportFrame{{key: ""}}
+	}
+
+	top := &r.stack[len(r.stack)-1]
+
+	switch top.key {
+	case "":
+		// top level object
+		if !r.dec.More() {
+			r.done = true
+			return nil
+		}
+		key, err := readKey(r.dec)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "channels", "users":
+			if err := expectDelim(r.dec, '['); err != nil {
+				return err
+			}
+			r.stack = append(r.stack, exportFrame{key: key})
+		default:
+			var skip json.RawMessage
+			return r.dec.Decode(&skip)
+		}
+
+	case "channels":
+		if !r.dec.More() {
+			return r.pop(']')
+		}
+		if err := expectDelim(r.dec, '{'); err != nil {
+			return err
+		}
+		r.stack = append(r.stack, exportFrame{
+			key:     "channel",
+			channel: &exportChannelState{fields: make(map[string]json.RawMessage)},
+		})
+
+	case "channel":
+		st := top.channel
+		if !r.dec.More() {
+			if err := r.flushChannel(st); err != nil {
+				return err
+			}
+			return r.pop('}')
+		}
+		key, err := readKey(r.dec)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "members", "messages", "reactions":
+			if err := r.flushChannel(st); err != nil {
+				return err
+			}
+			tok, err := r.dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok == nil { // null array
+				return nil
+			}
+			if d, ok := tok.(json.Delim); !ok || d != '[' {
+				return fmt.Errorf("export: expected array for %q", key)
+			}
+			r.stack = append(r.stack, exportFrame{key: key, channel: st})
+		case "channel":
+			var nested map[string]json.RawMessage
+			if err := r.dec.Decode(&nested); err != nil {
+				return err
+			}
+			for k, v := range nested {
+				st.fields[k] = v
+			}
+			return r.applyLateFields(st)
+		default:
+			var v json.RawMessage
+			if err := r.dec.Decode(&v); err != nil {
+				return err
+			}
+			st.fields[key] = v
+			return r.applyLateFields(st)
+		}
+
+	case "members", "messages", "reactions", "users":
+		if !r.dec.More() {
+			return r.pop(']')
+		}
+		var raw json.RawMessage
+		if err := r.dec.Decode(&raw); err != nil {
+			return err
+		}
+		typ := map[string]ExportRecordType{
+			"members":   ExportRecordMember,
+			"messages":  ExportRecordMessage,
+			"reactions": ExportRecordReaction,
+			"users":     ExportRecordUser,
+		}[top.key]
+
+		var cid string
+		if top.channel != nil && top.channel.channel != nil {
+			cid = top.channel.channel.cid()
+		}
+		rec, err := newExportRecord(typ, raw, cid)
+		if err != nil {
+			return err
+		}
+		r.pending = append(r.pending, rec)
+	}
+	return nil
+}
+
+// flushChannel emits the channel record once, before its members and messages.
+func (r *ExportReader) flushChannel(st *exportChannelState) error {
+	if st.channel != nil || len(st.fields) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(st.fields)
+	if err != nil {
+		return err
+	}
+	rec, err := newExportRecord(ExportRecordChannel, data, "")
+	if err != nil {
+		return err
+	}
+	st.channel = rec.Channel
+	r.pending = append(r.pending, rec)
+	return nil
+}
+
+// applyLateFields updates an already emitted channel with fields that appear
+// after its member or message arrays. The Channel returned by Next is mutated
+// in place, see Next.
+func (r *ExportReader) applyLateFields(st *exportChannelState) error {
+	if st.channel == nil {
+		return nil
+	}
+	data, err := json.Marshal(st.fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, st.channel)
+}
+
+func (r *ExportReader) pop(delim json.Delim) error {
+	if err := expectDelim(r.dec, delim); err != nil {
+		return err
+	}
+	r.stack = r.stack[:len(r.stack)-1]
+	if len(r.stack) == 0 {
+		r.done = true
+	}
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("export: expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+func readKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("export: expected object key, got %v", tok)
+	}
+	return key, nil
+}
+
+type exportLine struct {
+	Type ExportRecordType `json:"type"`
+	Data json.RawMessage  `json:"data"`
+}
+
+// WriteJSONL writes the remaining records as JSON lines of the form
+// {"type": "message", "data": {...}} and returns the number of records written.
+func (r *ExportReader) WriteJSONL(w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	n := 0
+	err := r.ForEach(func(rec *ExportRecord) error {
+		if err := enc.Encode(exportLine{Type: rec.Type, Data: rec.Raw}); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// ExportCSVHeader is the header row written by WriteCSV.
+var ExportCSVHeader = []string{"record_type", "channel_cid", "id", "user_id", "message_id", "type", "text", "created_at", "data"}
+
+// WriteCSV writes the remaining records as CSV rows with the columns of ExportCSVHeader.
+// The data column holds the full JSON of the entity. It returns the number of records written.
+func (r *ExportReader) WriteCSV(w io.Writer) (int, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ExportCSVHeader); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	err := r.ForEach(func(rec *ExportRecord) error {
+		if err := cw.Write(rec.csvRow()); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+
+	cw.Flush()
+	return n, cw.Error()
+}
+
+func (rec *ExportRecord) csvRow() []string {
+	var id, userID, messageID, typ, text, createdAt string
+
+	switch {
+	case rec.Channel != nil:
+		id, typ = rec.Channel.ID, rec.Channel.Type
+		if rec.Channel.CreatedBy != nil {
+			userID = rec.Channel.CreatedBy.ID
+		}
+		createdAt = formatCSVTime(&rec.Channel.CreatedAt)
+	case rec.Member != nil:
+		userID, typ = rec.Member.UserID, rec.Member.ChannelRole
+		if userID == "" && rec.Member.User != nil {
+			userID = rec.Member.User.ID
+		}
+		createdAt = formatCSVTime(&rec.Member.CreatedAt)
+	case rec.Message != nil:
+		id, messageID, typ, text = rec.Message.ID, rec.Message.ParentID, string(rec.Message.Type), rec.Message.Text
+		userID = rec.Message.UserID
+		if userID == "" && rec.Message.User != nil {
+			userID = rec.Message.User.ID
+		}
+		createdAt = formatCSVTime(rec.Message.CreatedAt)
+	case rec.Reaction != nil:
+		userID, messageID, typ = rec.Reaction.UserID, rec.Reaction.MessageID, rec.Reaction.Type
+		if v, ok := rec.Reaction.ExtraData["created_at"].(string); ok {
+			createdAt = v
+		}
+	case rec.User != nil:
+		id, userID, typ, text = rec.User.ID, rec.User.ID, rec.User.Role, rec.User.Name
+		createdAt = formatCSVTime(rec.User.CreatedAt)
+	}
+
+	return []string{string(rec.Type), rec.ChannelCID, id, userID, messageID, typ, text, createdAt, string(rec.Raw)}
+}
+
+func formatCSVTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
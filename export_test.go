@@ -0,0 +1,192 @@
+package stream_chat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const exportV1Doc = `{
+  "channels": [
+    {
+      "channel": {"id": "general", "type": "messaging", "cid": "messaging:general", "color": "blue"},
+      "members": [
+        {"user_id": "jane", "channel_role": "channel_member"},
+        {"user_id": "bob", "channel_role": "channel_moderator"}
+      ],
+      "messages": [
+        {"id": "m1", "text": "hi", "type": "regular", "user": {"id": "jane"}, "created_at": "2024-01-02T03:04:05Z"},
+        {"id": "m2", "text": "hello", "type": "reply", "parent_id": "m1", "user": {"id": "bob"}}
+      ],
+      "reactions": [
+        {"message_id": "m1", "user_id": "bob", "type": "like"}
+      ]
+    },
+    {
+      "id": "random",
+      "type": "team",
+      "messages": null
+    }
+  ],
+  "users": [
+    {"id": "jane", "name": "Jane", "role": "user"}
+  ]
+}`
+
+const exportV2Lines = `{"type":"channel","data":{"id":"general","type":"messaging","cid":"messaging:general"}}
+{"type":"member","data":{"user_id":"jane","channel_type":"messaging","channel_id":"general"}}
+
+{"type":"message","data":{"id":"m1","cid":"messaging:general","text":"hi","user":{"id":"jane"}}}
+{"type":"reaction","data":{"message_id":"m1","user_id":"bob","type":"like","cid":"messaging:general"}}
+{"type":"call","data":{"id":"c1"}}`
+
+func readAllExport(t *testing.T, r *ExportReader) []*ExportRecord {
+	t.Helper()
+
+	var records []*ExportRecord
+	require.NoError(t, r.ForEach(func(rec *ExportRecord) error {
+		records = append(records, rec)
+		return nil
+	}))
+	return records
+}
+
+func TestExportReader_Document(t *testing.T) {
+	r, err := NewExportReader(strings.NewReader(exportV1Doc))
+	require.NoError(t, err)
+
+	records := readAllExport(t, r)
+	require.Len(t, records, 8)
+
+	require.Equal(t, ExportRecordChannel, records[0].Type)
+	require.Equal(t, "messaging:general", records[0].ChannelCID)
+	require.Equal(t, "blue", records[0].Channel.ExtraData["color"])
+
+	require.Equal(t, ExportRecordMember, records[1].Type)
+	require.Equal(t, "jane", records[1].Member.UserID)
+	require.Equal(t, "messaging:general", records[1].ChannelCID)
+
+	require.Equal(t, ExportRecordMessage, records[3].Type)
+	require.Equal(t, "hi", records[3].Message.Text)
+	require.Equal(t, "m1", records[4].Message.ParentID)
+
+	require.Equal(t, ExportRecordReaction, records[5].Type)
+	require.Equal(t, "like", records[5].Reaction.Type)
+
+	require.Equal(t, ExportRecordChannel, records[6].Type)
+	require.Equal(t, "team:random", records[6].ChannelCID)
+
+	require.Equal(t, ExportRecordUser, records[7].Type)
+	require.Equal(t, "Jane", records[7].User.Name)
+
+	_, err = r.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestExportReader_Lines(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(exportV2Lines))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	r, err := NewExportReader(&buf)
+	require.NoError(t, err)
+
+	records := readAllExport(t, r)
+	require.Len(t, records, 5)
+	require.Equal(t, "messaging:general", records[0].ChannelCID)
+	require.Equal(t, "messaging:general", records[1].ChannelCID)
+	require.Equal(t, "messaging:general", records[2].ChannelCID)
+	require.Equal(t, "jane", records[2].Message.User.ID)
+	require.Equal(t, "messaging:general", records[3].ChannelCID)
+
+	require.Equal(t, ExportRecordType("call"), records[4].Type)
+	require.JSONEq(t, `{"id":"c1"}`, string(records[4].Raw))
+}
+
+func TestClient_OpenExport(t *testing.T) {
+	ctx := context.Background()
+	task := &TaskResponse{
+		TaskID: "t1",
+		Status: TaskStatusCompleted,
+		Result: map[string]interface{}{"url": "https://export.example.com/t1.json"},
+	}
+
+	t.Run("with custom fetcher", func(t *testing.T) {
+		c, err := NewClient("key", "secret")
+		require.NoError(t, err)
+
+		fetcher := func(_ context.Context, url string) (io.ReadCloser, error) {
+			require.Equal(t, "https://export.example.com/t1.json", url)
+			return io.NopCloser(strings.NewReader(exportV1Doc)), nil
+		}
+
+		r, err := c.OpenExport(ctx, task, WithExportFetcher(fetcher))
+		require.NoError(t, err)
+		defer r.Close()
+
+		var buf bytes.Buffer
+		n, err := r.WriteJSONL(&buf)
+		require.NoError(t, err)
+		require.Equal(t, 8, n)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 8)
+		var first exportLine
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+		require.Equal(t, ExportRecordChannel, first.Type)
+
+		// the JSONL output can be read back
+		r2, err := NewExportReader(&buf)
+		require.NoError(t, err)
+		require.Len(t, readAllExport(t, r2), 8)
+	})
+
+	t.Run("with http download", func(t *testing.T) {
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Empty(t, r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(exportV2Lines))
+		}))
+
+		r, err := c.OpenExportURL(ctx, c.BaseURL+"/exports/t1.jsonl")
+		require.NoError(t, err)
+		defer r.Close()
+
+		var buf bytes.Buffer
+		n, err := r.WriteCSV(&buf)
+		require.NoError(t, err)
+		require.Equal(t, 5, n)
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 6)
+		require.Equal(t, ExportCSVHeader, rows[0])
+		require.Equal(t, []string{"message", "messaging:general", "m1", "jane", "", "", "hi", ""}, rows[3][:8])
+	})
+
+	t.Run("download failure", func(t *testing.T) {
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+
+		_, err := c.OpenExportURL(ctx, c.BaseURL+"/exports/t1.jsonl")
+		require.Error(t, err)
+	})
+
+	t.Run("task not completed", func(t *testing.T) {
+		c, err := NewClient("key", "secret")
+		require.NoError(t, err)
+
+		_, err = c.OpenExport(ctx, &TaskResponse{TaskID: "t2", Status: TaskStatusRunning})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,570 @@
+package stream_chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filter operators supported by the in-memory evaluator.
+// https://getstream.io/chat/docs/go-golang/query_syntax_operators/
+const (
+	FilterOpEq           = "$eq"
+	FilterOpNe           = "$ne"
+	FilterOpGt           = "$gt"
+	FilterOpGte          = "$gte"
+	FilterOpLt           = "$lt"
+	FilterOpLte          = "$lte"
+	FilterOpIn           = "$in"
+	FilterOpNin          = "$nin"
+	FilterOpExists       = "$exists"
+	FilterOpContains     = "$contains"
+	FilterOpQuery        = "$q"
+	FilterOpAutocomplete = "$autocomplete"
+	FilterOpAnd          = "$and"
+	FilterOpOr           = "$or"
+	FilterOpNor          = "$nor"
+)
+
+// Filter is a query filter compiled for in-memory evaluation. It accepts the same
+// filter conditions as QueryChannels, QueryUsers and Search and can be used to test
+// Channel, User and Message values (including their ExtraData custom fields)
+// without calling the API.
+type Filter struct {
+	root filterNode
+}
+
+// CompileFilter validates the filter conditions and returns a Filter that can be
+// evaluated many times. An error is returned for unsupported operators or
+// malformed operands.
+func CompileFilter(filter map[string]interface{}) (*Filter, error) {
+	normalized, err := normalizeFilterValue(filter)
+	if err != nil {
+		return nil, fmt.Errorf("cannot normalize filter: %w", err)
+	}
+
+	m, _ := normalized.(map[string]interface{})
+	root, err := compileFilterMap(m)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{root: root}, nil
+}
+
+// Match reports whether v matches the filter. v is typically a *Channel, *User
+// or *Message, but any value that can be encoded to a JSON object is accepted.
+func (f *Filter) Match(v interface{}) (bool, error) {
+	doc, err := filterDocument(v)
+	if err != nil {
+		return false, err
+	}
+	return f.root.match(doc), nil
+}
+
+// MatchFilter is a convenience wrapper that compiles the filter and matches it
+// against v. Use CompileFilter when the same filter is evaluated repeatedly.
+func MatchFilter(filter map[string]interface{}, v interface{}) (bool, error) {
+	f, err := CompileFilter(filter)
+	if err != nil {
+		return false, err
+	}
+	return f.Match(v)
+}
+
+// filterDocument converts v into the generic document the filter is evaluated
+// against and adds the computed fields the API exposes for filtering.
+func filterDocument(v interface{}) (map[string]interface{}, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil, errors.New("value to match must not be nil")
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("value must encode to a JSON object: %w", err)
+	}
+	addZeroFilterFields(doc, reflect.ValueOf(v))
+
+	switch t := v.(type) {
+	case *Channel:
+		addChannelFilterFields(doc, t)
+	case Channel:
+		addChannelFilterFields(doc, &t)
+	case *Message:
+		addMessageFilterFields(doc, t)
+	case Message:
+		addMessageFilterFields(doc, &t)
+	}
+	return doc, nil
+}
+
+// addZeroFilterFields restores the bool and number fields of rv that omitempty
+// dropped from doc, so {"online": false} or {"reply_count": 0} matches the zero
+// value like it does on the server. Empty strings are left out because the API
+// treats them as unset fields. Nested structs, including those in slices, are
+// filled in as well.
+func addZeroFilterFields(doc map[string]interface{}, rv reflect.Value) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if name == "" {
+			if sf.Anonymous {
+				addZeroFilterFields(doc, fv)
+			}
+			continue
+		}
+
+		existing, found := doc[name]
+		switch fv.Kind() {
+		case reflect.Bool:
+			if !found {
+				doc[name] = false
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if !found {
+				doc[name] = float64(0)
+			}
+		case reflect.Ptr, reflect.Struct:
+			if m, ok := existing.(map[string]interface{}); ok {
+				addZeroFilterFields(m, fv)
+			}
+		case reflect.Slice, reflect.Array:
+			list, ok := existing.([]interface{})
+			if !ok || len(list) != fv.Len() {
+				continue
+			}
+			for j := range list {
+				if m, ok := list[j].(map[string]interface{}); ok {
+					addZeroFilterFields(m, fv.Index(j))
+				}
+			}
+		}
+	}
+}
+
+func addChannelFilterFields(doc map[string]interface{}, ch *Channel) {
+	doc["cid"] = ch.cid()
+
+	// members is filtered by user ID, not by the member objects.
+	members := make([]interface{}, 0, len(ch.Members))
+	for _, m := range ch.Members {
+		switch {
+		case m == nil:
+		case m.UserID != "":
+			members = append(members, m.UserID)
+		case m.User != nil:
+			members = append(members, m.User.ID)
+		}
+	}
+	doc["members"] = members
+
+	if ch.CreatedBy != nil {
+		doc["created_by_id"] = ch.CreatedBy.ID
+	}
+}
+
+func addMessageFilterFields(doc map[string]interface{}, m *Message) {
+	if m.UserID == "" && m.User != nil {
+		doc["user_id"] = m.User.ID
+	}
+}
+
+// normalizeFilterValue round trips v through JSON so Go values such as ints,
+// []string and time.Time compare the same way as the decoded documents.
+func normalizeFilterValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
+
+type filterNode interface {
+	match(doc map[string]interface{}) bool
+}
+
+type filterAnd []filterNode
+
+func (n filterAnd) match(doc map[string]interface{}) bool {
+	for _, c := range n {
+		if !c.match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+type filterOr []filterNode
+
+func (n filterOr) match(doc map[string]interface{}) bool {
+	for _, c := range n {
+		if c.match(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+type filterNor []filterNode
+
+func (n filterNor) match(doc map[string]interface{}) bool {
+	return !filterOr(n).match(doc)
+}
+
+type filterField struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func compileFilterMap(m map[string]interface{}) (filterNode, error) {
+	// sort keys so errors are reported deterministically
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	nodes := make(filterAnd, 0, len(m))
+	for _, k := range keys {
+		v := m[k]
+		if strings.HasPrefix(k, "$") {
+			n, err := compileLogical(k, v)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+			continue
+		}
+
+		n, err := compileField(k, v)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func compileLogical(op string, v interface{}) (filterNode, error) {
+	switch op {
+	case FilterOpAnd, FilterOpOr, FilterOpNor:
+	default:
+		return nil, fmt.Errorf("unsupported filter operator %q at top level", op)
+	}
+
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s operator requires a non-empty array of filters", op)
+	}
+
+	children := make([]filterNode, 0, len(list))
+	for i, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s operator: element %d is not a filter object", op, i)
+		}
+		n, err := compileFilterMap(m)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+
+	switch op {
+	case FilterOpAnd:
+		return filterAnd(children), nil
+	case FilterOpOr:
+		return filterOr(children), nil
+	default:
+		return filterNor(children), nil
+	}
+}
+
+func compileField(field string, v interface{}) (filterNode, error) {
+	path := strings.Split(field, ".")
+
+	ops, ok := v.(map[string]interface{})
+	if !ok || !isOperatorMap(ops) {
+		// implicit equality, e.g. {"type": "messaging"}
+		return &filterField{path: path, op: FilterOpEq, value: v}, nil
+	}
+
+	nodes := make(filterAnd, 0, len(ops))
+	keys := make([]string, 0, len(ops))
+	for k := range ops {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, op := range keys {
+		operand := ops[op]
+		switch op {
+		case FilterOpEq, FilterOpNe, FilterOpContains:
+		case FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte:
+			switch operand.(type) {
+			case float64, string:
+			default:
+				return nil, fmt.Errorf("field %q: %s operator requires a number, string or time operand", field, op)
+			}
+		case FilterOpIn, FilterOpNin:
+			if _, ok := operand.([]interface{}); !ok {
+				return nil, fmt.Errorf("field %q: %s operator requires an array operand", field, op)
+			}
+		case FilterOpExists:
+			if _, ok := operand.(bool); !ok {
+				return nil, fmt.Errorf("field %q: %s operator requires a boolean operand", field, op)
+			}
+		case FilterOpAutocomplete, FilterOpQuery:
+			if _, ok := operand.(string); !ok {
+				return nil, fmt.Errorf("field %q: %s operator requires a string operand", field, op)
+			}
+		default:
+			return nil, fmt.Errorf("field %q: unsupported filter operator %q", field, op)
+		}
+		nodes = append(nodes, &filterField{path: path, op: op, value: operand})
+	}
+	return nodes, nil
+}
+
+func isOperatorMap(m map[string]interface{}) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for k := range m {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *filterField) match(doc map[string]interface{}) bool {
+	val, found := lookupFilterPath(doc, n.path)
+	if found && val == nil {
+		found = false
+	}
+
+	switch n.op {
+	case FilterOpExists:
+		return found == n.value.(bool) //nolint:forcetypeassert // checked at compile time
+	case FilterOpNe:
+		return !found || !anyFilterValue(val, func(x interface{}) bool { return filterEqual(x, n.value) })
+	case FilterOpNin:
+		return !found || !anyFilterValue(val, func(x interface{}) bool { return filterIn(x, n.value) })
+	}
+
+	if !found {
+		// {"field": null} matches unset fields
+		return n.op == FilterOpEq && n.value == nil
+	}
+
+	switch n.op {
+	case FilterOpEq:
+		if filterEqual(val, n.value) {
+			return true
+		}
+		return anyFilterValue(val, func(x interface{}) bool { return filterEqual(x, n.value) })
+	case FilterOpIn:
+		return anyFilterValue(val, func(x interface{}) bool { return filterIn(x, n.value) })
+	case FilterOpContains:
+		list, ok := val.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, x := range list {
+			if filterEqual(x, n.value) {
+				return true
+			}
+		}
+		return false
+	case FilterOpAutocomplete:
+		return anyFilterValue(val, func(x interface{}) bool { return filterAutocomplete(x, n.value.(string)) }) //nolint:forcetypeassert
+	case FilterOpQuery:
+		return anyFilterValue(val, func(x interface{}) bool { return filterFullText(x, n.value.(string)) }) //nolint:forcetypeassert
+	default:
+		return anyFilterValue(val, func(x interface{}) bool {
+			c, ok := filterCompare(x, n.value)
+			if !ok {
+				return false
+			}
+			switch n.op {
+			case FilterOpGt:
+				return c > 0
+			case FilterOpGte:
+				return c >= 0
+			case FilterOpLt:
+				return c < 0
+			default:
+				return c <= 0
+			}
+		})
+	}
+}
+
+// lookupFilterPath resolves a dotted field path. Arrays along the path are
+// flattened so "attachments.type" yields the type of every attachment.
+func lookupFilterPath(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		next, ok := t[path[0]]
+		if !ok {
+			return nil, false
+		}
+		return lookupFilterPath(next, path[1:])
+	case []interface{}:
+		var out []interface{}
+		for _, item := range t {
+			if r, ok := lookupFilterPath(item, path); ok {
+				if list, ok := r.([]interface{}); ok {
+					out = append(out, list...)
+				} else {
+					out = append(out, r)
+				}
+			}
+		}
+		return out, out != nil
+	default:
+		return nil, false
+	}
+}
+
+// anyFilterValue applies fn to v, or to each element of v if it is an array.
+func anyFilterValue(v interface{}, fn func(interface{}) bool) bool {
+	list, ok := v.([]interface{})
+	if !ok {
+		return fn(v)
+	}
+	for _, x := range list {
+		if fn(x) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterIn(v, list interface{}) bool {
+	items, _ := list.([]interface{})
+	for _, item := range items {
+		if filterEqual(v, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterEqual(a, b interface{}) bool {
+	if c, ok := filterCompare(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// filterCompare compares two scalar values. Strings that both parse as
+// timestamps are compared chronologically.
+func filterCompare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		if tx, err := time.Parse(time.RFC3339Nano, x); err == nil {
+			if ty, err := time.Parse(time.RFC3339Nano, y); err == nil {
+				return tx.Compare(ty), true
+			}
+		}
+		return strings.Compare(x, y), true
+	case bool:
+		y, ok := b.(bool)
+		if !ok || x != y {
+			return 0, false
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// filterAutocomplete matches when any word of v starts with prefix, ignoring case.
+func filterAutocomplete(v interface{}, prefix string) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	prefix = strings.ToLower(prefix)
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterFullText approximates full text search: every word of query must
+// appear as a word of v, ignoring case.
+func filterFullText(v interface{}, query string) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	words := make(map[string]struct{})
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		words[strings.Trim(w, ".,;:!?\"'()")] = struct{}{}
+	}
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return false
+	}
+	for _, term := range terms {
+		if _, ok := words[term]; !ok {
+			return false
+		}
+	}
+	return true
+}
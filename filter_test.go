@@ -0,0 +1,183 @@
+package stream_chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchFilter_Channel(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ch := &Channel{
+		ID:        "general",
+		Type:      "messaging",
+		Team:      "blue",
+		Frozen:    true,
+		CreatedBy: &User{ID: "owner"},
+		CreatedAt: createdAt,
+		Members: []*ChannelMember{
+			{UserID: "alice"},
+			{User: &User{ID: "bob"}},
+		},
+		MemberCount: 2,
+		ExtraData: map[string]interface{}{
+			"color":    "green",
+			"priority": 3,
+			"tags":     []string{"support", "vip"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter map[string]interface{}
+		want   bool
+	}{
+		{"implicit eq", map[string]interface{}{"type": "messaging"}, true},
+		{"implicit eq mismatch", map[string]interface{}{"type": "livestream"}, false},
+		{"cid", map[string]interface{}{"cid": "messaging:general"}, true},
+		{"bool", map[string]interface{}{"frozen": true}, true},
+		{"members in", map[string]interface{}{"members": map[string]interface{}{"$in": []string{"bob", "carol"}}}, true},
+		{"members nin", map[string]interface{}{"members": map[string]interface{}{"$nin": []string{"alice"}}}, false},
+		{"created_by_id", map[string]interface{}{"created_by_id": "owner"}, true},
+		{"nested path", map[string]interface{}{"created_by.id": "owner"}, true},
+		{"custom eq", map[string]interface{}{"color": "green"}, true},
+		{"custom gte", map[string]interface{}{"priority": map[string]interface{}{"$gte": 3}}, true},
+		{"custom gt", map[string]interface{}{"priority": map[string]interface{}{"$gt": 3}}, false},
+		{"custom range", map[string]interface{}{"priority": map[string]interface{}{"$gt": 1, "$lt": 5}}, true},
+		{"custom contains", map[string]interface{}{"tags": map[string]interface{}{"$contains": "vip"}}, true},
+		{"array eq", map[string]interface{}{"tags": "support"}, true},
+		{"exists", map[string]interface{}{"color": map[string]interface{}{"$exists": true}}, true},
+		{"not exists", map[string]interface{}{"size": map[string]interface{}{"$exists": false}}, true},
+		{"ne missing field", map[string]interface{}{"size": map[string]interface{}{"$ne": "xl"}}, true},
+		{"time lt", map[string]interface{}{"created_at": map[string]interface{}{"$lt": createdAt.Add(time.Hour)}}, true},
+		{"time gt", map[string]interface{}{"created_at": map[string]interface{}{"$gt": createdAt}}, false},
+		{"and", map[string]interface{}{"$and": []map[string]interface{}{{"team": "blue"}, {"color": "green"}}}, true},
+		{"or", map[string]interface{}{"$or": []map[string]interface{}{{"team": "red"}, {"color": "green"}}}, true},
+		{"nor", map[string]interface{}{"$nor": []map[string]interface{}{{"team": "red"}, {"color": "green"}}}, false},
+		{"empty filter", map[string]interface{}{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchFilter(tt.filter, ch)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatchFilter_User(t *testing.T) {
+	u := &User{
+		ID:    "jane",
+		Name:  "Jane Doe",
+		Role:  "admin",
+		Teams: []string{"red", "blue"},
+		ExtraData: map[string]interface{}{
+			"country": "NL",
+		},
+	}
+
+	ok, err := MatchFilter(map[string]interface{}{"name": map[string]interface{}{"$autocomplete": "do"}}, u)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = MatchFilter(map[string]interface{}{"teams": map[string]interface{}{"$in": []string{"green", "red"}}}, u)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = MatchFilter(map[string]interface{}{
+		"role":    map[string]interface{}{"$in": []string{"admin", "moderator"}},
+		"country": map[string]interface{}{"$ne": "NL"},
+	}, u)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestMatchFilter_Message(t *testing.T) {
+	m := &Message{
+		ID:   "msg-1",
+		Text: "hello",
+		User: &User{ID: "jane"},
+		Attachments: []*Attachment{
+			{Type: "image"},
+			{Type: "file"},
+		},
+		MentionedUsers: []*User{{ID: "bob"}},
+	}
+
+	ok, err := MatchFilter(map[string]interface{}{"text": map[string]interface{}{"$q": "Hello"}}, m)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = MatchFilter(map[string]interface{}{"user_id": "jane"}, m)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = MatchFilter(map[string]interface{}{"attachments.type": map[string]interface{}{"$in": []string{"file"}}}, m)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = MatchFilter(map[string]interface{}{"mentioned_users.id": map[string]interface{}{"$contains": "alice"}}, m)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestMatchFilter_ZeroValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter map[string]interface{}
+		value  interface{}
+		want   bool
+	}{
+		{"user online false", map[string]interface{}{"online": false}, &User{ID: "a"}, true},
+		{"user invisible false", map[string]interface{}{"invisible": map[string]interface{}{"$eq": false}}, &User{ID: "a"}, true},
+		{"user online true", map[string]interface{}{"online": true}, &User{ID: "a"}, false},
+		{"user online in", map[string]interface{}{"online": map[string]interface{}{"$in": []bool{false}}}, &User{ID: "a"}, true},
+		{"message pinned false", map[string]interface{}{"pinned": false}, &Message{ID: "m"}, true},
+		{"message silent and shadowed false", map[string]interface{}{"silent": false, "shadowed": false}, &Message{ID: "m"}, true},
+		{"message reply count zero", map[string]interface{}{"reply_count": 0}, &Message{ID: "m"}, true},
+		{"message reply count lte", map[string]interface{}{"reply_count": map[string]interface{}{"$lte": 0}}, &Message{ID: "m"}, true},
+		{"message reply count gt", map[string]interface{}{"reply_count": map[string]interface{}{"$gt": 0}}, &Message{ID: "m"}, false},
+		{"message user online false", map[string]interface{}{"user.online": false}, &Message{ID: "m", User: &User{ID: "a"}}, true},
+		{"empty string stays unset", map[string]interface{}{"parent_id": map[string]interface{}{"$exists": false}}, &Message{ID: "m"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchFilter(tt.filter, tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompileFilter_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter map[string]interface{}
+		err    string
+	}{
+		{"unsupported field operator", map[string]interface{}{"name": map[string]interface{}{"$regex": "^j"}}, `field "name": unsupported filter operator "$regex"`},
+		{"unsupported top level operator", map[string]interface{}{"$not": map[string]interface{}{}}, `unsupported filter operator "$not" at top level`},
+		{"in requires array", map[string]interface{}{"id": map[string]interface{}{"$in": "a"}}, `field "id": $in operator requires an array operand`},
+		{"exists requires bool", map[string]interface{}{"id": map[string]interface{}{"$exists": 1}}, `field "id": $exists operator requires a boolean operand`},
+		{"and requires array", map[string]interface{}{"$and": map[string]interface{}{"id": "a"}}, `$and operator requires a non-empty array of filters`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileFilter(tt.filter)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestFilter_MatchNil(t *testing.T) {
+	f, err := CompileFilter(map[string]interface{}{"id": "a"})
+	require.NoError(t, err)
+
+	var ch *Channel
+	_, err = f.Match(ch)
+	require.Error(t, err)
+}
@@ -0,0 +1,814 @@
+package stream_chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ImportItemType is the type of an item of an import file.
+type ImportItemType string
+
+const (
+	ImportItemUser             ImportItemType = "user"
+	ImportItemDevice           ImportItemType = "device"
+	ImportItemFutureChannelBan ImportItemType = "future_channel_ban"
+	ImportItemChannel          ImportItemType = "channel"
+	ImportItemMember           ImportItemType = "member"
+	ImportItemMessage          ImportItemType = "message"
+	ImportItemReaction         ImportItemType = "reaction"
+)
+
+// importItemOrder is the order items must appear in an import file.
+var importItemOrder = map[ImportItemType]int{
+	ImportItemUser:             0,
+	ImportItemDevice:           1,
+	ImportItemFutureChannelBan: 2,
+	ImportItemChannel:          3,
+	ImportItemMember:           4,
+	ImportItemMessage:          5,
+	ImportItemReaction:         6,
+}
+
+// ErrImportItemOrder is returned when items are written out of the order required by the import format:
+// users, devices, future channel bans, channels, members, messages and reactions.
+var ErrImportItemOrder = errors.New("import items must be ordered: user, device, future_channel_ban, channel, member, message, reaction")
+
+type importItem struct {
+	Type ImportItemType         `json:"type"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// ImportWriter writes SDK values in the JSON Lines format consumed by CreateImport.
+// https://getstream.io/chat/docs/go-golang/import/#file-format
+type ImportWriter struct {
+	w      *bufio.Writer
+	enc    *json.Encoder
+	stage  int
+	counts map[ImportItemType]int
+}
+
+// NewImportWriter returns a writer emitting import items to w. Flush must be called when done.
+func NewImportWriter(w io.Writer) *ImportWriter {
+	bw := bufio.NewWriter(w)
+	return &ImportWriter{
+		w:      bw,
+		enc:    json.NewEncoder(bw),
+		counts: make(map[ImportItemType]int),
+	}
+}
+
+// Flush writes any buffered items to the underlying writer.
+func (iw *ImportWriter) Flush() error {
+	return iw.w.Flush()
+}
+
+// Counts returns the number of items written per type.
+func (iw *ImportWriter) Counts() map[ImportItemType]int {
+	counts := make(map[ImportItemType]int, len(iw.counts))
+	for k, v := range iw.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+func (iw *ImportWriter) write(typ ImportItemType, data map[string]interface{}) error {
+	stage := importItemOrder[typ]
+	if stage < iw.stage {
+		return fmt.Errorf("cannot write %s after %s: %w", typ, importStageName(iw.stage), ErrImportItemOrder)
+	}
+	iw.stage = stage
+
+	if err := iw.enc.Encode(importItem{Type: typ, Data: data}); err != nil {
+		return err
+	}
+	iw.counts[typ]++
+	return nil
+}
+
+func importStageName(stage int) ImportItemType {
+	for typ, s := range importItemOrder {
+		if s == stage {
+			return typ
+		}
+	}
+	return ""
+}
+
+// WriteUser writes a user item. Mutes without a target user or channel are left out.
+func (iw *ImportWriter) WriteUser(u *User) error {
+	if u == nil {
+		return errors.New("user is nil")
+	}
+
+	data := copyMap(u.ExtraData)
+	data["id"] = u.ID
+	setIfNotEmpty(data, "name", u.Name)
+	setIfNotEmpty(data, "image", u.Image)
+	setIfNotEmpty(data, "role", u.Role)
+	setIfNotEmpty(data, "language", u.Language)
+	if len(u.Teams) > 0 {
+		data["teams"] = u.Teams
+	}
+	if len(u.TeamsRole) > 0 {
+		data["teams_role"] = u.TeamsRole
+	}
+	if u.Invisible {
+		data["invisible"] = true
+	}
+	if u.PrivacySettings != nil {
+		data["privacy_settings"] = u.PrivacySettings
+	}
+	if len(u.BlockedUserIDs) > 0 {
+		data["blocked_user_ids"] = u.BlockedUserIDs
+	}
+	if len(u.Mutes) > 0 {
+		mutes := make([]string, 0, len(u.Mutes))
+		for _, m := range u.Mutes {
+			if m != nil && m.Target.ID != "" {
+				mutes = append(mutes, m.Target.ID)
+			}
+		}
+		if len(mutes) > 0 {
+			data["user_mutes"] = mutes
+		}
+	}
+	if len(u.ChannelMutes) > 0 {
+		mutes := make([]string, 0, len(u.ChannelMutes))
+		for _, m := range u.ChannelMutes {
+			if m != nil && (m.Channel.CID != "" || m.Channel.Type != "" && m.Channel.ID != "") {
+				mutes = append(mutes, m.Channel.cid())
+			}
+		}
+		if len(mutes) > 0 {
+			data["channel_mutes"] = mutes
+		}
+	}
+	setTimeIfNotZero(data, "created_at", u.CreatedAt)
+
+	return iw.write(ImportItemUser, data)
+}
+
+// WriteDevice writes a device item.
+func (iw *ImportWriter) WriteDevice(d *Device) error {
+	if d == nil {
+		return errors.New("device is nil")
+	}
+
+	return iw.write(ImportItemDevice, map[string]interface{}{
+		"id":                 d.ID,
+		"user_id":            d.UserID,
+		"push_provider_type": d.PushProvider,
+		"push_provider_name": d.PushProviderName,
+	})
+}
+
+// WriteChannel writes a channel item. Channels without an ID are written as
+// distinct channels identified by the user IDs of their members.
+func (iw *ImportWriter) WriteChannel(ch *Channel) error {
+	if ch == nil {
+		return errors.New("channel is nil")
+	}
+
+	data := copyMap(ch.ExtraData)
+	data["type"] = ch.Type
+	if ch.ID != "" {
+		data["id"] = ch.ID
+	} else {
+		data["member_ids"] = channelMemberIDs(ch.Members)
+	}
+	if ch.CreatedBy != nil {
+		data["created_by"] = ch.CreatedBy.ID
+	}
+	setIfNotEmpty(data, "team", ch.Team)
+	if ch.Frozen {
+		data["frozen"] = true
+	}
+	if ch.Disabled {
+		data["disabled"] = true
+	}
+	setTimeIfNotZero(data, "created_at", &ch.CreatedAt)
+	setTimeIfNotZero(data, "truncated_at", ch.TruncatedAt)
+
+	return iw.write(ImportItemChannel, data)
+}
+
+// WriteMember writes a member item of the channel identified by channelCID.
+func (iw *ImportWriter) WriteMember(channelCID string, m *ChannelMember) error {
+	if m == nil {
+		return errors.New("member is nil")
+	}
+
+	data := copyMap(m.ExtraData)
+	if err := setImportChannel(data, channelCID, "channel_id"); err != nil {
+		return err
+	}
+
+	data["user_id"] = m.UserID
+	if m.UserID == "" && m.User != nil {
+		data["user_id"] = m.User.ID
+	}
+	setIfNotEmpty(data, "channel_role", m.ChannelRole)
+	if m.IsModerator {
+		data["is_moderator"] = true
+	}
+	if m.Invited {
+		data["invited"] = true
+	}
+	setTimeIfNotZero(data, "invited_accepted_at", m.InviteAcceptedAt)
+	setTimeIfNotZero(data, "invited_rejected_at", m.InviteRejectedAt)
+	setTimeIfNotZero(data, "archived_at", m.ArchivedAt)
+	setTimeIfNotZero(data, "created_at", &m.CreatedAt)
+
+	return iw.write(ImportItemMember, data)
+}
+
+// WriteMessage writes a message item. The channel is taken from channelCID, or from
+// the message CID when channelCID is empty. Pins without a PinnedBy user are
+// attributed to the message author.
+func (iw *ImportWriter) WriteMessage(channelCID string, m *Message) error {
+	if m == nil {
+		return errors.New("message is nil")
+	}
+	if channelCID == "" {
+		channelCID = m.CID
+	}
+
+	data := copyMap(m.ExtraData)
+	if err := setImportChannel(data, channelCID, "channel_id"); err != nil {
+		return err
+	}
+
+	data["id"] = m.ID
+	data["user"] = m.UserID
+	if m.UserID == "" && m.User != nil {
+		data["user"] = m.User.ID
+	}
+
+	typ := m.Type
+	switch {
+	case m.DeletedAt != nil && typ == "":
+		typ = "deleted"
+	case typ == "" && m.ParentID != "":
+		typ = MessageTypeReply
+	case typ == "":
+		typ = MessageTypeRegular
+	}
+	data["type"] = typ
+
+	setIfNotEmpty(data, "text", m.Text)
+	setIfNotEmpty(data, "html", m.HTML)
+	setIfNotEmpty(data, "parent_id", m.ParentID)
+	setIfNotEmpty(data, "quoted_message_id", m.QuotedMessageID)
+	if m.ShowInChannel {
+		data["show_in_channel"] = true
+	}
+	if len(m.Attachments) > 0 {
+		data["attachments"] = m.Attachments
+	}
+	if len(m.MentionedUsers) > 0 {
+		ids := make([]string, 0, len(m.MentionedUsers))
+		for _, u := range m.MentionedUsers {
+			ids = append(ids, u.ID)
+		}
+		data["mentioned_users_ids"] = ids
+	}
+	if len(m.RestrictedVisibility) > 0 {
+		data["restricted_visibility"] = m.RestrictedVisibility
+	}
+	pinnedBy, _ := data["user"].(string)
+	if m.PinnedBy != nil && m.PinnedBy.ID != "" {
+		pinnedBy = m.PinnedBy.ID
+	}
+	if m.PinnedAt != nil && !m.PinnedAt.IsZero() && pinnedBy != "" {
+		// the import format requires pinned_at, pin_expires and pinned_by_id together,
+		// null pins without an expiry
+		setTimeIfNotZero(data, "pinned_at", m.PinnedAt)
+		data["pin_expires"] = nil
+		setTimeIfNotZero(data, "pin_expires", m.PinExpires)
+		data["pinned_by_id"] = pinnedBy
+	}
+	setTimeIfNotZero(data, "created_at", m.CreatedAt)
+	setTimeIfNotZero(data, "deleted_at", m.DeletedAt)
+
+	return iw.write(ImportItemMessage, data)
+}
+
+// WriteReaction writes a reaction item. createdAt is required by the import format;
+// when zero, a "created_at" value in the reaction ExtraData is used instead.
+func (iw *ImportWriter) WriteReaction(r *Reaction, createdAt time.Time) error {
+	if r == nil {
+		return errors.New("reaction is nil")
+	}
+
+	data := copyMap(r.ExtraData)
+	data["message_id"] = r.MessageID
+	data["user_id"] = r.UserID
+	data["type"] = r.Type
+	setTimeIfNotZero(data, "created_at", &createdAt)
+
+	return iw.write(ImportItemReaction, data)
+}
+
+// setImportChannel sets the channel reference fields of a member or message. Distinct
+// channels are referenced by "type:!members-<ids>" style CIDs, which are not supported
+// here; use the channel_member_ids ExtraData field for those.
+func setImportChannel(data map[string]interface{}, channelCID, idField string) error {
+	if _, ok := data["channel_member_ids"]; ok && channelCID == "" {
+		return nil
+	}
+
+	parts := strings.SplitN(channelCID, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid channel CID %q", channelCID)
+	}
+	data["channel_type"] = parts[0]
+	data[idField] = parts[1]
+	return nil
+}
+
+func channelMemberIDs(members []*ChannelMember) []string {
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		switch {
+		case m.UserID != "":
+			ids = append(ids, m.UserID)
+		case m.User != nil:
+			ids = append(ids, m.User.ID)
+		}
+	}
+	return ids
+}
+
+func setIfNotEmpty(m map[string]interface{}, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+func setTimeIfNotZero(m map[string]interface{}, key string, t *time.Time) {
+	if t != nil && !t.IsZero() {
+		m[key] = t.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// Limits checked by ValidateImport.
+const (
+	importMaxChannelIDLength = 64
+	importMaxIDLength        = 255
+	importMaxCustomDataSize  = 5 * 1024
+	importMaxLineSize        = 10 * 1024 * 1024
+)
+
+// ImportValidationOptions configures ValidateImport.
+type ImportValidationOptions struct {
+	// ExistingUserIDs, ExistingChannelCIDs and ExistingMessageIDs are items that
+	// already exist in the app and may be referenced without appearing in the file.
+	ExistingUserIDs     []string
+	ExistingChannelCIDs []string
+	ExistingMessageIDs  []string
+
+	// MaxMessageLength is the longest allowed message text. Defaults to 5000.
+	MaxMessageLength int
+	// MaxErrors stops the validation once this many errors were found. Zero means no limit.
+	MaxErrors int
+}
+
+// ImportValidationError is a problem found in an import file.
+type ImportValidationError struct {
+	Line     int            `json:"line"`
+	ItemType ImportItemType `json:"item_type,omitempty"`
+	Message  string         `json:"error"`
+}
+
+func (e ImportValidationError) Error() string {
+	if e.ItemType == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.ItemType, e.Message)
+}
+
+// ImportValidationReport is the result of ValidateImport.
+type ImportValidationReport struct {
+	Errors []ImportValidationError `json:"errors"`
+	Counts map[ImportItemType]int  `json:"counts"`
+	// Truncated is set when validation stopped after MaxErrors errors.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Valid reports whether no errors were found.
+func (r *ImportValidationReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Err returns the found errors joined together, or nil if the file is valid.
+func (r *ImportValidationReport) Err() error {
+	errs := make([]error, len(r.Errors))
+	for i := range r.Errors {
+		errs[i] = r.Errors[i]
+	}
+	return errors.Join(errs...)
+}
+
+type importValidator struct {
+	opts   ImportValidationOptions
+	report *ImportValidationReport
+
+	stage    int
+	users    map[string]struct{}
+	channels map[string]struct{}
+	messages map[string]struct{}
+	members  map[string]struct{}
+	devices  map[string]struct{}
+	react    map[string]struct{}
+}
+
+// ValidateImport checks an import file offline before it is uploaded: item order, required
+// fields, timestamps, field sizes, duplicated items and references to users, channels and
+// messages that are neither in the file nor listed as existing in opts.
+// The returned error is only set when r cannot be read.
+func ValidateImport(r io.Reader, opts *ImportValidationOptions) (*ImportValidationReport, error) {
+	v := &importValidator{
+		report:   &ImportValidationReport{Counts: make(map[ImportItemType]int)},
+		users:    make(map[string]struct{}),
+		channels: make(map[string]struct{}),
+		messages: make(map[string]struct{}),
+		members:  make(map[string]struct{}),
+		devices:  make(map[string]struct{}),
+		react:    make(map[string]struct{}),
+	}
+	if opts != nil {
+		v.opts = *opts
+	}
+	if v.opts.MaxMessageLength <= 0 {
+		v.opts.MaxMessageLength = 5000
+	}
+	for _, id := range v.opts.ExistingUserIDs {
+		v.users[id] = struct{}{}
+	}
+	for _, cid := range v.opts.ExistingChannelCIDs {
+		v.channels[cid] = struct{}{}
+	}
+	for _, id := range v.opts.ExistingMessageIDs {
+		v.messages[id] = struct{}{}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), importMaxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		v.validateLine(line, []byte(raw))
+		if v.opts.MaxErrors > 0 && len(v.report.Errors) >= v.opts.MaxErrors {
+			v.report.Truncated = true
+			v.report.Errors = v.report.Errors[:v.opts.MaxErrors]
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return v.report, err
+	}
+	return v.report, nil
+}
+
+func (v *importValidator) validateLine(line int, raw []byte) {
+	var item struct {
+		Type ImportItemType         `json:"type"`
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &item); err != nil {
+		v.addError(line, "", "parse error: "+err.Error())
+		return
+	}
+
+	stage, ok := importItemOrder[item.Type]
+	if !ok {
+		v.addError(line, "", fmt.Sprintf("parse error: invalid item type %q", item.Type))
+		return
+	}
+	if item.Data == nil {
+		v.addError(line, item.Type, "data is required")
+		return
+	}
+	if stage < v.stage {
+		v.addError(line, item.Type, fmt.Sprintf("item appears after %s items", importStageName(v.stage)))
+	} else {
+		v.stage = stage
+	}
+	v.report.Counts[item.Type]++
+
+	var errs []string
+	d := importData(item.Data)
+	switch item.Type {
+	case ImportItemUser:
+		errs = v.validateUser(d)
+	case ImportItemDevice:
+		errs = v.validateDevice(d)
+	case ImportItemFutureChannelBan:
+		errs = v.validateFutureChannelBan(d)
+	case ImportItemChannel:
+		errs = v.validateChannel(d)
+	case ImportItemMember:
+		errs = v.validateMember(d)
+	case ImportItemMessage:
+		errs = v.validateMessage(d)
+	case ImportItemReaction:
+		errs = v.validateReaction(d)
+	}
+	errs = append(errs, d.validateTimestamps()...)
+
+	for _, msg := range errs {
+		v.addError(line, item.Type, msg)
+	}
+}
+
+func (v *importValidator) addError(line int, typ ImportItemType, msg string) {
+	v.report.Errors = append(v.report.Errors, ImportValidationError{Line: line, ItemType: typ, Message: msg})
+}
+
+// importData provides typed access to the fields of an import item.
+type importData map[string]interface{}
+
+func (d importData) str(key string) string {
+	s, _ := d[key].(string)
+	return s
+}
+
+func (d importData) strings(key string) []string {
+	list, _ := d[key].([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (d importData) required(errs *[]string, keys ...string) {
+	for _, key := range keys {
+		if d.str(key) == "" {
+			*errs = append(*errs, fmt.Sprintf("%q required", key))
+		}
+	}
+}
+
+func (d importData) maxLength(errs *[]string, key string, limit int) {
+	if n := len([]rune(d.str(key))); n > limit {
+		*errs = append(*errs, fmt.Sprintf("max %q length exceeded (%d)", key, limit))
+	}
+}
+
+// customDataSize checks the size of the fields which are not part of the documented format.
+func (d importData) customDataSize(errs *[]string, known ...string) {
+	custom := make(map[string]interface{})
+	for k, v := range d {
+		custom[k] = v
+	}
+	for _, k := range known {
+		delete(custom, k)
+	}
+	if len(custom) == 0 {
+		return
+	}
+	b, err := json.Marshal(custom)
+	if err == nil && len(b) > importMaxCustomDataSize {
+		*errs = append(*errs, fmt.Sprintf("custom data exceeds %d bytes (%d)", importMaxCustomDataSize, len(b)))
+	}
+}
+
+var importTimestampFields = []string{
+	"created_at", "deleted_at", "deactivated_at", "truncated_at", "archived_at",
+	"invited_accepted_at", "invited_rejected_at", "hide_messages_before", "last_read",
+	"pinned_at", "pin_expires",
+}
+
+func (d importData) validateTimestamps() []string {
+	var errs []string
+	for _, key := range importTimestampFields {
+		v, ok := d[key]
+		if !ok || v == nil {
+			continue
+		}
+		s, isString := v.(string)
+		if !isString {
+			errs = append(errs, fmt.Sprintf("%q must be an RFC 3339 timestamp", key))
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			errs = append(errs, fmt.Sprintf("%q is not a valid RFC 3339 timestamp: %q", key, s))
+		}
+	}
+	return errs
+}
+
+func (v *importValidator) checkUser(errs *[]string, field, id string) {
+	if id == "" {
+		return
+	}
+	if _, ok := v.users[id]; !ok {
+		*errs = append(*errs, fmt.Sprintf("%s user %q doesn't exist, include it as a user item", field, id))
+	}
+}
+
+// channelKey returns the key a channel is referenced by: its CID, or the
+// sorted member IDs for distinct channels.
+func importChannelKey(channelType, channelID string, memberIDs []string) string {
+	if channelID != "" {
+		return channelType + ":" + channelID
+	}
+	ids := append([]string(nil), memberIDs...)
+	sort.Strings(ids)
+	return channelType + ":!members-" + strings.Join(ids, ",")
+}
+
+func (v *importValidator) validateUser(d importData) []string {
+	var errs []string
+	d.required(&errs, "id")
+	d.maxLength(&errs, "id", importMaxIDLength)
+
+	id := d.str("id")
+	if _, dup := v.users[id]; dup && id != "" && !slices.Contains(v.opts.ExistingUserIDs, id) {
+		errs = append(errs, fmt.Sprintf("duplicated user %q", id))
+	}
+	v.users[id] = struct{}{}
+
+	d.customDataSize(&errs, "id", "name", "image", "role", "teams", "teams_role", "language", "invisible",
+		"privacy_settings", "push_preferences", "blocked_user_ids", "user_mutes", "channel_mutes",
+		"created_at", "deleted_at", "deactivated_at")
+	return errs
+}
+
+func (v *importValidator) validateDevice(d importData) []string {
+	var errs []string
+	d.required(&errs, "id", "user_id", "push_provider_type", "push_provider_name")
+
+	switch PushProviderType(d.str("push_provider_type")) {
+	case "", PushProviderFirebase, PushProviderAPNS, PushProviderHuawei, PushProviderXiaomi:
+	default:
+		errs = append(errs, fmt.Sprintf("'%s' is not a valid push_provider_type", d.str("push_provider_type")))
+	}
+	v.checkUser(&errs, "device", d.str("user_id"))
+
+	id := d.str("id")
+	if _, dup := v.devices[id]; dup && id != "" {
+		errs = append(errs, fmt.Sprintf("duplicated device %q", id))
+	}
+	v.devices[id] = struct{}{}
+	return errs
+}
+
+func (v *importValidator) validateFutureChannelBan(d importData) []string {
+	var errs []string
+	d.required(&errs, "created_by", "target_id")
+	v.checkUser(&errs, "created_by", d.str("created_by"))
+	v.checkUser(&errs, "target", d.str("target_id"))
+	return errs
+}
+
+func (v *importValidator) validateChannel(d importData) []string {
+	var errs []string
+	d.required(&errs, "type", "created_by")
+	d.maxLength(&errs, "id", importMaxChannelIDLength)
+
+	id, memberIDs := d.str("id"), d.strings("member_ids")
+	_, hasMemberIDs := d["member_ids"]
+	switch {
+	case id != "" && hasMemberIDs:
+		errs = append(errs, "either channel.id or channel.member_ids should be provided, but not both")
+	case id == "" && len(memberIDs) == 0:
+		errs = append(errs, "channel.id or channel.member_ids required, but not both")
+	}
+	if strings.Contains(id, ":") {
+		errs = append(errs, fmt.Sprintf("'%s' is not a valid channel.id", id))
+	}
+
+	v.checkUser(&errs, "created_by", d.str("created_by"))
+	for _, uid := range memberIDs {
+		v.checkUser(&errs, "member_ids", uid)
+	}
+
+	key := importChannelKey(d.str("type"), id, memberIDs)
+	if _, dup := v.channels[key]; dup && !slices.Contains(v.opts.ExistingChannelCIDs, key) {
+		errs = append(errs, fmt.Sprintf("duplicated channel %q", key))
+	}
+	v.channels[key] = struct{}{}
+
+	d.customDataSize(&errs, "id", "type", "created_by", "member_ids", "team", "frozen", "disabled",
+		"banned_users", "created_at", "truncated_at")
+	return errs
+}
+
+func (v *importValidator) checkChannel(errs *[]string, d importData) string {
+	d.required(errs, "channel_type")
+	id, memberIDs := d.str("channel_id"), d.strings("channel_member_ids")
+	if id == "" && len(memberIDs) == 0 {
+		*errs = append(*errs, `"channel_id" or "channel_member_ids" required`)
+		return ""
+	}
+
+	key := importChannelKey(d.str("channel_type"), id, memberIDs)
+	if _, ok := v.channels[key]; !ok {
+		*errs = append(*errs, fmt.Sprintf("channel %q doesn't exist, include it as a channel item", key))
+	}
+	return key
+}
+
+func (v *importValidator) validateMember(d importData) []string {
+	var errs []string
+	d.required(&errs, "user_id")
+	key := v.checkChannel(&errs, d)
+	v.checkUser(&errs, "member", d.str("user_id"))
+
+	memberKey := key + "/" + d.str("user_id")
+	if _, dup := v.members[memberKey]; dup && key != "" {
+		errs = append(errs, fmt.Sprintf("duplicated member %q of channel %q", d.str("user_id"), key))
+	}
+	v.members[memberKey] = struct{}{}
+
+	d.customDataSize(&errs, "channel_type", "channel_id", "channel_member_ids", "user_id", "channel_role",
+		"is_moderator", "invited", "invited_accepted_at", "invited_rejected_at", "hide_channel",
+		"hide_messages_before", "archived_at", "last_read", "created_at")
+	return errs
+}
+
+func (v *importValidator) validateMessage(d importData) []string {
+	var errs []string
+	d.required(&errs, "id", "type", "user")
+	d.maxLength(&errs, "id", importMaxIDLength)
+	d.maxLength(&errs, "text", v.opts.MaxMessageLength)
+	v.checkChannel(&errs, d)
+	v.checkUser(&errs, "message", d.str("user"))
+
+	switch MessageType(d.str("type")) {
+	case "", MessageTypeRegular, MessageTypeReply, MessageTypeSystem, "deleted":
+	default:
+		errs = append(errs, fmt.Sprintf("'%s' is not a valid message type", d.str("type")))
+	}
+
+	if parentID := d.str("parent_id"); parentID != "" {
+		if _, ok := v.messages[parentID]; !ok {
+			errs = append(errs, fmt.Sprintf("parent message %q doesn't exist", parentID))
+		}
+	}
+	if quotedID := d.str("quoted_message_id"); quotedID != "" {
+		if _, ok := v.messages[quotedID]; !ok {
+			errs = append(errs, fmt.Sprintf("quoted message %q doesn't exist", quotedID))
+		}
+	}
+	for _, uid := range d.strings("mentioned_users_ids") {
+		v.checkUser(&errs, "mentioned", uid)
+	}
+
+	// pin_expires may be null for messages pinned without an expiry
+	_, pinnedAt := d["pinned_at"]
+	_, pinExpires := d["pin_expires"]
+	_, pinnedBy := d["pinned_by_id"]
+	if (pinnedAt || pinExpires || pinnedBy) && !(pinnedAt && pinExpires && pinnedBy) {
+		errs = append(errs, "pinned_at, pin_expires and pinned_by_id must be provided together")
+	}
+	v.checkUser(&errs, "pinned_by", d.str("pinned_by_id"))
+
+	id := d.str("id")
+	if _, dup := v.messages[id]; dup && id != "" && !slices.Contains(v.opts.ExistingMessageIDs, id) {
+		errs = append(errs, fmt.Sprintf("duplicated message %q", id))
+	}
+	v.messages[id] = struct{}{}
+
+	d.customDataSize(&errs, "id", "channel_type", "channel_id", "channel_member_ids", "user", "text", "html",
+		"type", "attachments", "parent_id", "show_in_channel", "quoted_message_id", "mentioned_users_ids",
+		"restricted_visibility", "pinned_at", "pin_expires", "pinned_by_id", "created_at", "deleted_at")
+	return errs
+}
+
+func (v *importValidator) validateReaction(d importData) []string {
+	var errs []string
+	d.required(&errs, "message_id", "type", "user_id", "created_at")
+	v.checkUser(&errs, "reaction", d.str("user_id"))
+
+	if msgID := d.str("message_id"); msgID != "" {
+		if _, ok := v.messages[msgID]; !ok {
+			errs = append(errs, fmt.Sprintf("message %q doesn't exist", msgID))
+		}
+	}
+
+	key := d.str("message_id") + "/" + d.str("user_id") + "/" + d.str("type")
+	if _, dup := v.react[key]; dup {
+		errs = append(errs, fmt.Sprintf("duplicated reaction %q", key))
+	}
+	v.react[key] = struct{}{}
+
+	d.customDataSize(&errs, "message_id", "type", "user_id", "created_at")
+	return errs
+}
@@ -0,0 +1,166 @@
+package stream_chat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportWriter(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	jane := &User{ID: "jane", Name: "Jane", ExtraData: map[string]interface{}{"color": "blue"}}
+	bob := &User{ID: "bob"}
+
+	var buf bytes.Buffer
+	w := NewImportWriter(&buf)
+	require.NoError(t, w.WriteUser(jane))
+	require.NoError(t, w.WriteUser(bob))
+	require.NoError(t, w.WriteDevice(&Device{ID: "d1", UserID: "jane", PushProvider: PushProviderFirebase, PushProviderName: "fcm"}))
+	require.NoError(t, w.WriteChannel(&Channel{Type: "messaging", ID: "general", CreatedBy: jane, CreatedAt: createdAt}))
+	require.NoError(t, w.WriteMember("messaging:general", &ChannelMember{UserID: "jane"}))
+	require.NoError(t, w.WriteMember("messaging:general", &ChannelMember{User: bob, ChannelRole: "channel_moderator"}))
+	require.NoError(t, w.WriteMessage("", &Message{ID: "m1", CID: "messaging:general", Text: "hi @bob", User: jane, MentionedUsers: []*User{bob}, CreatedAt: &createdAt}))
+	require.NoError(t, w.WriteMessage("messaging:general", &Message{ID: "m2", ParentID: "m1", Text: "hello", UserID: "bob"}))
+	require.NoError(t, w.WriteReaction(&Reaction{MessageID: "m1", UserID: "bob", Type: "like"}, createdAt))
+
+	err := w.WriteUser(&User{ID: "late"})
+	require.ErrorIs(t, err, ErrImportItemOrder)
+	require.NoError(t, w.Flush())
+
+	require.Equal(t, map[ImportItemType]int{
+		ImportItemUser:     2,
+		ImportItemDevice:   1,
+		ImportItemChannel:  1,
+		ImportItemMember:   2,
+		ImportItemMessage:  2,
+		ImportItemReaction: 1,
+	}, w.Counts())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 9)
+	require.JSONEq(t, `{"type":"user","data":{"id":"jane","name":"Jane","color":"blue"}}`, lines[0])
+	require.JSONEq(t, `{"type":"channel","data":{"type":"messaging","id":"general","created_by":"jane","created_at":"2024-01-02T03:04:05Z"}}`, lines[3])
+	require.JSONEq(t, `{"type":"member","data":{"channel_type":"messaging","channel_id":"general","user_id":"bob","channel_role":"channel_moderator"}}`, lines[5])
+	require.JSONEq(t, `{"type":"message","data":{"id":"m2","channel_type":"messaging","channel_id":"general","user":"bob","type":"reply","text":"hello","parent_id":"m1"}}`, lines[7])
+
+	report, err := ValidateImport(&buf, nil)
+	require.NoError(t, err)
+	require.True(t, report.Valid(), report.Err())
+	require.Equal(t, 2, report.Counts[ImportItemMessage])
+
+	t.Run("invalid channel CID", func(t *testing.T) {
+		w := NewImportWriter(&bytes.Buffer{})
+		require.Error(t, w.WriteMessage("", &Message{ID: "m1", UserID: "jane"}))
+	})
+}
+
+func TestImportWriter_RoundTrip(t *testing.T) {
+	pinnedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expires := pinnedAt.Add(time.Hour)
+	jane := &User{
+		ID:           "jane",
+		Mutes:        []*Mute{nil, {Target: User{ID: "bob"}}, {}},
+		ChannelMutes: []*ChannelMute{nil, {Channel: Channel{Type: "messaging", ID: "general"}}, {}},
+	}
+
+	var buf bytes.Buffer
+	w := NewImportWriter(&buf)
+	require.NoError(t, w.WriteUser(jane))
+	require.NoError(t, w.WriteUser(&User{ID: "bob"}))
+	require.NoError(t, w.WriteChannel(&Channel{Type: "messaging", ID: "general", CreatedBy: jane}))
+	require.NoError(t, w.WriteMessage("messaging:general", &Message{ID: "forever", Text: "a", UserID: "jane", PinnedAt: &pinnedAt, PinnedBy: jane}))
+	require.NoError(t, w.WriteMessage("messaging:general", &Message{ID: "hour", Text: "b", UserID: "jane", PinnedAt: &pinnedAt, PinExpires: &expires, PinnedBy: jane}))
+	require.NoError(t, w.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.JSONEq(t, `{"type":"user","data":{"id":"jane","user_mutes":["bob"],"channel_mutes":["messaging:general"]}}`, lines[0])
+	require.Contains(t, lines[3], `"pin_expires":null`)
+	require.Contains(t, lines[4], `"pin_expires":"2024-01-02T04:04:05Z"`)
+
+	report, err := ValidateImport(&buf, nil)
+	require.NoError(t, err)
+	require.True(t, report.Valid(), report.Err())
+	require.Equal(t, 2, report.Counts[ImportItemMessage])
+}
+
+func TestImportWriter_PinWithoutPinnedBy(t *testing.T) {
+	pinnedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	w := NewImportWriter(&buf)
+	require.NoError(t, w.WriteUser(&User{ID: "jane"}))
+	require.NoError(t, w.WriteChannel(&Channel{Type: "messaging", ID: "general", CreatedBy: &User{ID: "jane"}}))
+	require.NoError(t, w.WriteMessage("messaging:general", &Message{ID: "m1", Text: "a", UserID: "jane", Pinned: true, PinnedAt: &pinnedAt}))
+	require.NoError(t, w.WriteMessage("messaging:general", &Message{ID: "m2", Text: "b", Pinned: true, PinnedAt: &pinnedAt}))
+	require.NoError(t, w.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Contains(t, lines[2], `"pinned_by_id":"jane"`)
+	require.NotContains(t, lines[3], `"pinned_at"`)
+	require.NotContains(t, lines[3], `"pin_expires"`)
+
+	report, err := ValidateImport(strings.NewReader(strings.Join(lines[:3], "\n")), nil)
+	require.NoError(t, err)
+	require.True(t, report.Valid(), report.Err())
+}
+
+func TestValidateImport(t *testing.T) {
+	file := strings.Join([]string{
+		`{"type":"user","data":{"id":"jane"}}`,
+		`{"type":"channel","data":{"type":"messaging","id":"general","created_by":"jane"}}`,
+		`{"type":"user","data":{"id":"bob"}}`,
+		`{"type":"channel","data":{"type":"messaging","id":"general","created_by":"ghost"}}`,
+		`{"type":"member","data":{"channel_type":"messaging","channel_id":"random","user_id":"jane"}}`,
+		``,
+		`{"type":"message","data":{"id":"m1","channel_type":"messaging","channel_id":"general","user":"jane","type":"regular","created_at":"yesterday"}}`,
+		`{"type":"message","data":{"id":"m2","channel_type":"messaging","channel_id":"general","user":"bob","type":"reply","parent_id":"m0"}}`,
+		`{"type":"reaction","data":{"message_id":"m1","user_id":"bob","type":"like"}}`,
+		`{"type":"call","data":{}}`,
+		`not json`,
+	}, "\n")
+
+	report, err := ValidateImport(strings.NewReader(file), &ImportValidationOptions{ExistingUserIDs: []string{"bob"}})
+	require.NoError(t, err)
+	require.False(t, report.Valid())
+
+	var got []string
+	for _, e := range report.Errors {
+		got = append(got, e.Error())
+	}
+	require.Equal(t, []string{
+		`line 3: user: item appears after channel items`,
+		`line 4: channel: created_by user "ghost" doesn't exist, include it as a user item`,
+		`line 4: channel: duplicated channel "messaging:general"`,
+		`line 5: member: channel "messaging:random" doesn't exist, include it as a channel item`,
+		`line 7: message: "created_at" is not a valid RFC 3339 timestamp: "yesterday"`,
+		`line 8: message: parent message "m0" doesn't exist`,
+		`line 9: reaction: "created_at" required`,
+		`line 10: parse error: invalid item type "call"`,
+		`line 11: parse error: invalid character 'o' in literal null (expecting 'u')`,
+	}, got)
+	require.Equal(t, 2, report.Counts[ImportItemUser])
+	require.Error(t, report.Err())
+
+	t.Run("max errors", func(t *testing.T) {
+		report, err := ValidateImport(strings.NewReader(file), &ImportValidationOptions{MaxErrors: 2})
+		require.NoError(t, err)
+		require.Len(t, report.Errors, 2)
+		require.True(t, report.Truncated)
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		file := strings.Join([]string{
+			`{"type":"user","data":{"id":"jane","bio":"` + strings.Repeat("a", importMaxCustomDataSize) + `"}}`,
+			`{"type":"channel","data":{"type":"messaging","id":"` + strings.Repeat("c", 65) + `","created_by":"jane"}}`,
+		}, "\n")
+
+		report, err := ValidateImport(strings.NewReader(file), nil)
+		require.NoError(t, err)
+		require.Len(t, report.Errors, 2)
+		require.Contains(t, report.Errors[0].Message, "custom data exceeds")
+		require.Equal(t, `max "id" length exceeded (64)`, report.Errors[1].Message)
+	})
+}
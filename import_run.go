@@ -0,0 +1,313 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Import task states.
+const (
+	ImportStateCompleted = "completed"
+	ImportStateFailed    = "failed"
+)
+
+// Done reports whether the import task reached a final state.
+func (t *ImportTask) Done() bool {
+	return t.State == ImportStateCompleted || t.Failed()
+}
+
+// Failed reports whether the import task ended in a failure state, such as "failed" or "analyze_failed".
+func (t *ImportTask) Failed() bool {
+	return t.State == ImportStateFailed || strings.HasSuffix(t.State, "_failed")
+}
+
+// DecodeResult decodes the untyped import result into v.
+func (t *ImportTask) DecodeResult(v interface{}) error {
+	b, err := json.Marshal(t.Result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// FailureReason returns the error reported in the result of a failed import, if any.
+func (t *ImportTask) FailureReason() string {
+	res, ok := t.Result.(map[string]interface{})
+	if !ok {
+		if s, ok := t.Result.(string); ok {
+			return s
+		}
+		return ""
+	}
+	for _, key := range []string{"error", "description", "reason", "message"} {
+		if s, ok := res[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// ImportFailedError is returned by RunImport when the import task ends in a failure state.
+type ImportFailedError struct {
+	ImportID string
+	State    string
+	Reason   string
+	Task     *ImportTask
+}
+
+func (e *ImportFailedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("import %s %s", e.ImportID, e.State)
+	}
+	return fmt.Sprintf("import %s %s: %s", e.ImportID, e.State, e.Reason)
+}
+
+// ImportPhase is the step of RunImport reported to progress callbacks.
+type ImportPhase string
+
+const (
+	// ImportPhaseUploading is reported while the file is uploaded.
+	ImportPhaseUploading ImportPhase = "uploading"
+	// ImportPhaseUploaded is reported once the upload finished.
+	ImportPhaseUploaded ImportPhase = "uploaded"
+	// ImportPhaseCreated is reported once the import task was created.
+	ImportPhaseCreated ImportPhase = "created"
+	// ImportPhaseState is reported for every state transition of the import task.
+	ImportPhaseState ImportPhase = "state"
+)
+
+// ImportProgress is passed to the progress callback of RunImport.
+type ImportProgress struct {
+	Phase ImportPhase
+	// BytesUploaded is the number of bytes sent so far.
+	BytesUploaded int64
+	// Task is the latest import task state, set from ImportPhaseCreated on.
+	Task *ImportTask
+	// Transition is the history entry that triggered an ImportPhaseState report.
+	Transition *ImportTaskHistory
+}
+
+// ImportUploader uploads an import file to uploadURL. size is -1 when unknown.
+type ImportUploader func(ctx context.Context, uploadURL string, body io.Reader, size int64) error
+
+// ImportSummary is the result of a successful RunImport.
+type ImportSummary struct {
+	ImportID      string
+	Path          string
+	Mode          ImportMode
+	BytesUploaded int64
+	Task          *ImportTask
+	// Duration is the time from the start of the upload until the import completed.
+	Duration time.Duration
+}
+
+type runImportOptions struct {
+	filename      string
+	size          int64
+	uploader      ImportUploader
+	progress      func(ImportProgress)
+	poll          WaitForTaskOptions
+	createOptions []CreateImportOption
+}
+
+type RunImportOption func(*runImportOptions)
+
+// WithImportFilename sets the name of the uploaded file. Defaults to "import.json".
+func WithImportFilename(filename string) RunImportOption {
+	return func(o *runImportOptions) {
+		o.filename = filename
+	}
+}
+
+// WithImportSize sets the size of the file when it can't be determined from the reader.
+// Upload targets usually reject uploads of unknown length.
+func WithImportSize(size int64) RunImportOption {
+	return func(o *runImportOptions) {
+		o.size = size
+	}
+}
+
+// WithImportUploader replaces the HTTP upload of the import file, for example
+// to send it to a local test server.
+func WithImportUploader(uploader ImportUploader) RunImportOption {
+	return func(o *runImportOptions) {
+		o.uploader = uploader
+	}
+}
+
+// WithImportProgress sets a callback reporting upload progress and import state transitions.
+func WithImportProgress(fn func(ImportProgress)) RunImportOption {
+	return func(o *runImportOptions) {
+		o.progress = fn
+	}
+}
+
+// WithImportPolling configures how often GetImport is polled. OnPoll is ignored.
+func WithImportPolling(opts WaitForTaskOptions) RunImportOption {
+	return func(o *runImportOptions) {
+		o.poll = opts
+	}
+}
+
+// WithImportCreateOptions passes options, such as WithMergeCustom, to CreateImport.
+func WithImportCreateOptions(opts ...CreateImportOption) RunImportOption {
+	return func(o *runImportOptions) {
+		o.createOptions = append(o.createOptions, opts...)
+	}
+}
+
+// RunImport uploads an import file and runs it: it requests an upload URL with CreateImportURL,
+// streams r to it, starts the import with CreateImport and polls GetImport until the import
+// completes or fails. A failed import returns the final summary together with an *ImportFailedError.
+// Note: this relies on the import endpoints, which are for internal usage only.
+func (c *Client) RunImport(ctx context.Context, r io.Reader, mode ImportMode, options ...RunImportOption) (*ImportSummary, error) {
+	if r == nil {
+		return nil, errors.New("import file reader must be not nil")
+	}
+
+	opts := runImportOptions{filename: "import.json", size: readerSize(r), uploader: c.uploadImport}
+	for _, fn := range options {
+		fn(&opts)
+	}
+	report := func(p ImportProgress) {
+		if opts.progress != nil {
+			opts.progress(p)
+		}
+	}
+
+	start := time.Now()
+	urlResp, err := c.CreateImportURL(ctx, opts.filename)
+	if err != nil {
+		return nil, fmt.Errorf("create import url: %w", err)
+	}
+
+	body := &progressReader{r: r, fn: func(n int64) {
+		report(ImportProgress{Phase: ImportPhaseUploading, BytesUploaded: n})
+	}}
+	if err := opts.uploader(ctx, urlResp.UploadURL, body, opts.size); err != nil {
+		return nil, fmt.Errorf("upload import file: %w", err)
+	}
+	report(ImportProgress{Phase: ImportPhaseUploaded, BytesUploaded: body.n})
+
+	createResp, err := c.CreateImport(ctx, urlResp.Path, mode, opts.createOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("create import: %w", err)
+	}
+	if createResp.ImportTask == nil {
+		return nil, errors.New("create import: no import task returned")
+	}
+
+	task := createResp.ImportTask
+	summary := &ImportSummary{
+		ImportID:      task.ID,
+		Path:          urlResp.Path,
+		Mode:          mode,
+		BytesUploaded: body.n,
+		Task:          task,
+	}
+	report(ImportProgress{Phase: ImportPhaseCreated, BytesUploaded: body.n, Task: task})
+
+	poll := opts.poll.withDefaults()
+	interval := poll.InitialInterval
+	seen := 0
+	for {
+		for ; seen < len(task.History); seen++ {
+			report(ImportProgress{Phase: ImportPhaseState, BytesUploaded: body.n, Task: task, Transition: task.History[seen]})
+		}
+		summary.Task = task
+		summary.Duration = time.Since(start)
+
+		if task.Failed() {
+			return summary, &ImportFailedError{ImportID: task.ID, State: task.State, Reason: task.FailureReason(), Task: task}
+		}
+		if task.Done() {
+			return summary, nil
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return summary, err
+		}
+		interval = time.Duration(float64(interval) * poll.Multiplier)
+		if interval > poll.MaxInterval {
+			interval = poll.MaxInterval
+		}
+
+		resp, err := c.GetImport(ctx, summary.ImportID)
+		switch {
+		case err != nil && !isTransientError(err):
+			return summary, err
+		case err == nil && resp.ImportTask != nil:
+			task = resp.ImportTask
+		}
+	}
+}
+
+// uploadImport is the default ImportUploader, a plain PUT to the presigned upload URL.
+func (c *Client) uploadImport(ctx context.Context, uploadURL string, body io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+
+	// the upload URL is presigned, so neither the API credentials nor the API timeout apply
+	hc := &http.Client{Transport: c.HTTP.Transport}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected upload status: %s", resp.Status)
+	}
+	return nil
+}
+
+// readerSize returns the number of bytes left in r, or -1 if unknown.
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case *os.File:
+		info, err := v.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		pos, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - pos
+	}
+	return -1
+}
+
+type progressReader struct {
+	r  io.Reader
+	n  int64
+	fn func(int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.n += int64(n)
+		p.fn(p.n)
+	}
+	return n, err
+}
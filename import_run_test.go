@@ -0,0 +1,151 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeImportServer struct {
+	mu       sync.Mutex
+	t        *testing.T
+	states   []string
+	result   interface{}
+	polls    int
+	uploaded string
+	length   int64
+	request  map[string]interface{}
+	baseURL  string
+}
+
+func (s *fakeImportServer) task() map[string]interface{} {
+	state := s.states[min(s.polls, len(s.states)-1)]
+	history := []map[string]interface{}{}
+	prev := "created"
+	for _, st := range s.states[:min(s.polls, len(s.states)-1)+1] {
+		history = append(history, map[string]interface{}{"prev_state": prev, "next_state": st, "created_at": time.Now()})
+		prev = st
+	}
+	task := map[string]interface{}{"id": "imp1", "path": "imports/file.json", "mode": "upsert", "state": state, "history": history}
+	if s.result != nil {
+		task["result"] = s.result
+	}
+	return task
+}
+
+func (s *fakeImportServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/import_urls":
+		_ = json.NewEncoder(w).Encode(map[string]string{"path": "imports/file.json", "upload_url": s.baseURL + "/upload/file.json"})
+	case r.Method == http.MethodPut && r.URL.Path == "/upload/file.json":
+		require.Empty(s.t, r.Header.Get("Authorization"))
+		b, _ := io.ReadAll(r.Body)
+		s.uploaded = string(b)
+		s.length = r.ContentLength
+	case r.Method == http.MethodPost && r.URL.Path == "/imports":
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&s.request))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"import_task": s.task()})
+	case r.Method == http.MethodGet && r.URL.Path == "/imports/imp1":
+		s.polls++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"import_task": s.task()})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClient_RunImport(t *testing.T) {
+	ctx := context.Background()
+	file := `{"type":"user","data":{"id":"jane"}}` + "\n"
+	polling := WithImportPolling(WaitForTaskOptions{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond})
+
+	t.Run("completed", func(t *testing.T) {
+		srv := &fakeImportServer{t: t, states: []string{"uploaded", "analyzing", "importing", "completed"}}
+		c := newTestClient(t, srv)
+		srv.baseURL = c.BaseURL
+
+		var phases []ImportPhase
+		var states []string
+		summary, err := c.RunImport(ctx, strings.NewReader(file), UpsertMode, polling,
+			WithImportCreateOptions(WithMergeCustom(true)),
+			WithImportProgress(func(p ImportProgress) {
+				if len(phases) == 0 || phases[len(phases)-1] != p.Phase {
+					phases = append(phases, p.Phase)
+				}
+				if p.Transition != nil {
+					states = append(states, p.Transition.NextState)
+				}
+			}))
+		require.NoError(t, err)
+
+		require.Equal(t, file, srv.uploaded)
+		require.Equal(t, int64(len(file)), srv.length)
+		require.Equal(t, "imports/file.json", srv.request["path"])
+		require.Equal(t, "upsert", srv.request["mode"])
+		require.Equal(t, true, srv.request["merge_custom"])
+
+		require.Equal(t, "imp1", summary.ImportID)
+		require.Equal(t, int64(len(file)), summary.BytesUploaded)
+		require.Equal(t, ImportStateCompleted, summary.Task.State)
+		require.Equal(t, []ImportPhase{ImportPhaseUploading, ImportPhaseUploaded, ImportPhaseCreated, ImportPhaseState}, phases)
+		require.Equal(t, []string{"uploaded", "analyzing", "importing", "completed"}, states)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		srv := &fakeImportServer{t: t, states: []string{"analyzing", "analyze_failed"}, result: map[string]interface{}{"error": "invalid file"}}
+		c := newTestClient(t, srv)
+		srv.baseURL = c.BaseURL
+
+		summary, err := c.RunImport(ctx, strings.NewReader(file), InsertMode, polling)
+		var failed *ImportFailedError
+		require.ErrorAs(t, err, &failed)
+		require.Equal(t, "analyze_failed", failed.State)
+		require.Equal(t, "invalid file", failed.Reason)
+		require.Equal(t, "analyze_failed", summary.Task.State)
+	})
+
+	t.Run("custom uploader", func(t *testing.T) {
+		srv := &fakeImportServer{t: t, states: []string{"completed"}}
+		c := newTestClient(t, srv)
+		srv.baseURL = c.BaseURL
+
+		var got string
+		uploader := func(_ context.Context, uploadURL string, body io.Reader, size int64) error {
+			require.Equal(t, c.BaseURL+"/upload/file.json", uploadURL)
+			require.Equal(t, int64(-1), size)
+			b, err := io.ReadAll(body)
+			got = string(b)
+			return err
+		}
+
+		r := io.MultiReader(strings.NewReader(file))
+		_, err := c.RunImport(ctx, r, InsertMode, polling, WithImportUploader(uploader))
+		require.NoError(t, err)
+		require.Equal(t, file, got)
+		require.Empty(t, srv.uploaded)
+	})
+
+	t.Run("upload error", func(t *testing.T) {
+		srv := &fakeImportServer{t: t, states: []string{"completed"}}
+		c := newTestClient(t, srv)
+		srv.baseURL = c.BaseURL
+
+		uploader := func(context.Context, string, io.Reader, int64) error {
+			return errors.New("boom")
+		}
+		_, err := c.RunImport(ctx, strings.NewReader(file), InsertMode, WithImportUploader(uploader))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "upload import file: boom")
+		require.Nil(t, srv.request)
+	})
+}
@@ -0,0 +1,357 @@
+package stream_chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MentionKind tells what a mention token refers to.
+type MentionKind string
+
+const (
+	// MentionUser is an @handle referring to a single user.
+	MentionUser MentionKind = "user"
+	// MentionChannel is a broadcast mention such as @channel or @here.
+	MentionChannel MentionKind = "channel"
+)
+
+// MentionFormat is the output format of MentionProcessor.Render.
+type MentionFormat string
+
+const (
+	// MentionFormatPlain replaces handles with display names.
+	MentionFormatPlain MentionFormat = "plain"
+	// MentionFormatHTML escapes the text and wraps mentions in elements
+	// with a "mention" class.
+	MentionFormatHTML MentionFormat = "html"
+	// MentionFormatMarkdown keeps the text as is, since message text is
+	// already Markdown, and renders mentions as bold text or links.
+	MentionFormatMarkdown MentionFormat = "markdown"
+)
+
+// Mention is a mention token found in message text.
+type Mention struct {
+	Kind MentionKind
+	// Handle is the token without the leading @.
+	Handle string
+	// Start and End are the byte offsets of the token, including the @.
+	Start int
+	End   int
+	// User is the mentioned user, nil for broadcast mentions and for
+	// handles that do not match any user.
+	User *User
+}
+
+// mentionRegexp matches @handle tokens that are not part of a word or an email address.
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\w@.])(@([\w\-]+))`)
+
+// UserLookup resolves user IDs to users.
+type UserLookup interface {
+	// LookupUsers returns the users found by ID. Unknown IDs are left out.
+	LookupUsers(ctx context.Context, ids []string) (map[string]*User, error)
+}
+
+// CachedUserLookup is a UserLookup backed by QueryUsers. Results, including
+// IDs that were not found, are cached for the configured TTL.
+type CachedUserLookup struct {
+	client *Client
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedUser
+}
+
+type cachedUser struct {
+	user    *User
+	expires time.Time
+}
+
+// NewCachedUserLookup returns a UserLookup querying users with c and caching
+// them for ttl. A zero ttl defaults to 5 minutes.
+func NewCachedUserLookup(c *Client, ttl time.Duration) *CachedUserLookup {
+	if ttl == 0 {
+		ttl = 5 * time.Minute
+	}
+	return &CachedUserLookup{client: c, ttl: ttl, now: time.Now, cache: make(map[string]cachedUser)}
+}
+
+// LookupUsers implements UserLookup.
+func (l *CachedUserLookup) LookupUsers(ctx context.Context, ids []string) (map[string]*User, error) {
+	found := make(map[string]*User, len(ids))
+	var missing []string
+
+	l.mu.Lock()
+	now := l.now()
+	for _, id := range dedupeSorted(ids) {
+		if entry, ok := l.cache[id]; ok && now.Before(entry.expires) {
+			if entry.user != nil {
+				found[id] = entry.user
+			}
+			continue
+		}
+		missing = append(missing, id)
+	}
+	l.mu.Unlock()
+
+	for _, batch := range batches(missing, 100) {
+		resp, err := l.client.QueryUsers(ctx, &QueryUsersOptions{QueryOption: QueryOption{
+			Filter: map[string]interface{}{"id": map[string]interface{}{"$in": batch}},
+			Limit:  len(batch),
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("query users: %w", err)
+		}
+
+		l.mu.Lock()
+		expires := l.now().Add(l.ttl)
+		for _, id := range batch {
+			l.cache[id] = cachedUser{expires: expires}
+		}
+		for _, u := range resp.Users {
+			l.cache[u.ID] = cachedUser{user: u, expires: expires}
+			found[u.ID] = u
+		}
+		l.mu.Unlock()
+	}
+	return found, nil
+}
+
+// Invalidate drops the given users from the cache, or the whole cache when
+// no IDs are given.
+func (l *CachedUserLookup) Invalidate(ids ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(ids) == 0 {
+		clear(l.cache)
+		return
+	}
+	for _, id := range ids {
+		delete(l.cache, id)
+	}
+}
+
+// MentionOptions configures a MentionProcessor.
+type MentionOptions struct {
+	// Lookup resolves handles to users. Handles are matched against user IDs.
+	Lookup UserLookup
+	// BroadcastHandles are the handles treated as channel wide mentions.
+	// Defaults to "channel" and "here".
+	BroadcastHandles []string
+	// DisplayName returns the text shown for a mentioned user.
+	// Defaults to the user name, or the ID when the name is empty.
+	DisplayName func(*User) string
+	// UserLink optionally returns a link to the user's profile. When set,
+	// HTML and Markdown mentions are rendered as links.
+	UserLink func(*User) string
+}
+
+// MentionProcessor parses @mentions in message text and renders it for
+// display, so outbound and inbound messages treat mentions the same way.
+type MentionProcessor struct {
+	opts      MentionOptions
+	broadcast map[string]bool
+}
+
+// NewMentionProcessor returns a MentionProcessor using the given options.
+func NewMentionProcessor(opts *MentionOptions) (*MentionProcessor, error) {
+	if opts == nil || opts.Lookup == nil {
+		return nil, errors.New("user lookup must be not empty")
+	}
+
+	p := &MentionProcessor{opts: *opts, broadcast: make(map[string]bool)}
+	if p.opts.BroadcastHandles == nil {
+		p.opts.BroadcastHandles = []string{"channel", "here"}
+	}
+	for _, h := range p.opts.BroadcastHandles {
+		p.broadcast[strings.ToLower(h)] = true
+	}
+	if p.opts.DisplayName == nil {
+		p.opts.DisplayName = func(u *User) string {
+			if u.Name != "" {
+				return u.Name
+			}
+			return u.ID
+		}
+	}
+	return p, nil
+}
+
+// Parse returns the mention tokens in text in order of appearance. Users
+// already known are taken from known, the others are resolved through the
+// lookup.
+func (p *MentionProcessor) Parse(ctx context.Context, text string, known ...*User) ([]*Mention, error) {
+	var mentions []*Mention
+	var handles []string
+	for _, loc := range mentionRegexp.FindAllStringSubmatchIndex(text, -1) {
+		m := &Mention{Kind: MentionUser, Start: loc[2], End: loc[3], Handle: text[loc[4]:loc[5]]}
+		if p.broadcast[strings.ToLower(m.Handle)] {
+			m.Kind = MentionChannel
+		} else {
+			handles = append(handles, m.Handle)
+		}
+		mentions = append(mentions, m)
+	}
+	if len(handles) == 0 {
+		return mentions, nil
+	}
+
+	users := make(map[string]*User, len(known))
+	var unknown []string
+	for _, u := range known {
+		if u != nil {
+			users[u.ID] = u
+		}
+	}
+	for _, h := range handles {
+		if _, ok := users[h]; !ok {
+			unknown = append(unknown, h)
+		}
+	}
+	if len(unknown) > 0 {
+		found, err := p.opts.Lookup.LookupUsers(ctx, unknown)
+		if err != nil {
+			return nil, err
+		}
+		for id, u := range found {
+			users[id] = u
+		}
+	}
+
+	for _, m := range mentions {
+		if m.Kind == MentionUser {
+			m.User = users[m.Handle]
+		}
+	}
+	return mentions, nil
+}
+
+// Prepare processes an outbound message: it sets MentionedUsers from the
+// mentions in the text, keeping users that were already set, and renders
+// the text into HTML. The parsed mentions are returned, so callers can act
+// on broadcast mentions.
+func (p *MentionProcessor) Prepare(ctx context.Context, m *Message) ([]*Mention, error) {
+	if m == nil {
+		return nil, errors.New("message is nil")
+	}
+
+	mentions, err := p.Parse(ctx, m.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(m.MentionedUsers))
+	for _, u := range m.MentionedUsers {
+		seen[u.ID] = true
+	}
+	for _, mention := range mentions {
+		if mention.User != nil && !seen[mention.User.ID] {
+			seen[mention.User.ID] = true
+			m.MentionedUsers = append(m.MentionedUsers, mention.User)
+		}
+	}
+	m.HTML = p.render(m.Text, mentions, MentionFormatHTML)
+	return mentions, nil
+}
+
+// Render renders the text of a message in the given format. Mentioned
+// users are taken from MentionedUsers when present, other handles are
+// resolved through the lookup. Handles that match no user are kept as is.
+func (p *MentionProcessor) Render(ctx context.Context, m *Message, format MentionFormat) (string, error) {
+	if m == nil {
+		return "", errors.New("message is nil")
+	}
+	switch format {
+	case MentionFormatPlain, MentionFormatHTML, MentionFormatMarkdown:
+	default:
+		return "", fmt.Errorf("unknown mention format %q", format)
+	}
+
+	mentions, err := p.Parse(ctx, m.Text, m.MentionedUsers...)
+	if err != nil {
+		return "", err
+	}
+	return p.render(m.Text, mentions, format), nil
+}
+
+func (p *MentionProcessor) render(text string, mentions []*Mention, format MentionFormat) string {
+	var sb strings.Builder
+	pos := 0
+	for _, m := range mentions {
+		p.writeText(&sb, text[pos:m.Start], format)
+		pos = m.End
+		if m.Kind == MentionUser && m.User == nil {
+			p.writeText(&sb, text[m.Start:m.End], format)
+			continue
+		}
+		p.writeMention(&sb, m, format)
+	}
+	p.writeText(&sb, text[pos:], format)
+
+	if format == MentionFormatHTML {
+		return "<p>" + sb.String() + "</p>\n"
+	}
+	return sb.String()
+}
+
+func (p *MentionProcessor) writeText(sb *strings.Builder, s string, format MentionFormat) {
+	if format != MentionFormatHTML {
+		sb.WriteString(s)
+		return
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteString("<br>\n")
+		}
+		sb.WriteString(html.EscapeString(line))
+	}
+}
+
+func (p *MentionProcessor) writeMention(sb *strings.Builder, m *Mention, format MentionFormat) {
+	label := "@" + m.Handle
+	var link string
+	if m.User != nil {
+		label = "@" + p.opts.DisplayName(m.User)
+		if p.opts.UserLink != nil {
+			link = p.opts.UserLink(m.User)
+		}
+	}
+
+	switch format {
+	case MentionFormatPlain:
+		sb.WriteString(label)
+	case MentionFormatHTML:
+		switch {
+		case m.User == nil:
+			fmt.Fprintf(sb, `<span class="mention mention-%s">%s</span>`, html.EscapeString(strings.ToLower(m.Handle)), html.EscapeString(label))
+		case link != "":
+			fmt.Fprintf(sb, `<a class="mention" href="%s" data-user-id="%s">%s</a>`, html.EscapeString(link), html.EscapeString(m.User.ID), html.EscapeString(label))
+		default:
+			fmt.Fprintf(sb, `<span class="mention" data-user-id="%s">%s</span>`, html.EscapeString(m.User.ID), html.EscapeString(label))
+		}
+	case MentionFormatMarkdown:
+		label = escapeMarkdown(label)
+		if link != "" {
+			fmt.Fprintf(sb, "[%s](%s)", label, strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(link))
+			return
+		}
+		sb.WriteString("**" + label + "**")
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`(`, `\(`, `)`, `\)`, `<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`, `~`, `\~`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,78 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMentionProcessor(t *testing.T) {
+	ctx := context.Background()
+
+	var queried [][]interface{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/users", r.URL.Path)
+		var q queryRequest
+		require.NoError(t, json.Unmarshal([]byte(r.URL.Query().Get("payload")), &q))
+		ids := q.FilterConditions["id"].(map[string]interface{})["$in"].([]interface{})
+		queried = append(queried, ids)
+		var users []*User
+		for _, id := range ids {
+			switch id {
+			case "jane":
+				users = append(users, &User{ID: "jane", Name: "Jane <Doe>"})
+			case "bob-1":
+				users = append(users, &User{ID: "bob-1"})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
+	}))
+	lookup := NewCachedUserLookup(c, time.Minute)
+	p, err := NewMentionProcessor(&MentionOptions{Lookup: lookup})
+	require.NoError(t, err)
+
+	msg := &Message{Text: "hey @jane and @bob-1, ping @here.\nmail me at me@example.com or @nobody",
+		MentionedUsers: []*User{{ID: "carl"}}}
+	mentions, err := p.Prepare(ctx, msg)
+	require.NoError(t, err)
+	require.Len(t, mentions, 4)
+	require.Equal(t, &Mention{Kind: MentionChannel, Handle: "here", Start: 27, End: 32}, mentions[2])
+	require.Nil(t, mentions[3].User)
+	var mentioned []string
+	for _, u := range msg.MentionedUsers {
+		mentioned = append(mentioned, u.ID)
+	}
+	require.Equal(t, []string{"carl", "jane", "bob-1"}, mentioned)
+	require.Equal(t, `<p>hey <span class="mention" data-user-id="jane">@Jane &lt;Doe&gt;</span> and `+
+		`<span class="mention" data-user-id="bob-1">@bob-1</span>, ping <span class="mention mention-here">@here</span>.<br>
+mail me at me@example.com or @nobody</p>
+`, msg.HTML)
+	require.Equal(t, [][]interface{}{{"bob-1", "jane", "nobody"}}, queried)
+
+	// inbound messages reuse the mentioned users and the cache
+	inbound := &Message{Text: "thanks @jane @carl @nobody", MentionedUsers: []*User{{ID: "carl", Name: "Carl"}}}
+	plain, err := p.Render(ctx, inbound, MentionFormatPlain)
+	require.NoError(t, err)
+	require.Equal(t, "thanks @Jane <Doe> @Carl @nobody", plain)
+	require.Len(t, queried, 1)
+
+	linked, err := NewMentionProcessor(&MentionOptions{Lookup: lookup, UserLink: func(u *User) string { return "https://example.com/u/" + u.ID }})
+	require.NoError(t, err)
+	md, err := linked.Render(ctx, inbound, MentionFormatMarkdown)
+	require.NoError(t, err)
+	require.Equal(t, `thanks [@Jane \<Doe\>](https://example.com/u/jane) [@Carl](https://example.com/u/carl) @nobody`, md)
+
+	lookup.Invalidate("nobody")
+	_, err = p.Render(ctx, inbound, MentionFormatHTML)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"nobody"}, queried[1])
+
+	_, err = p.Render(ctx, inbound, "pdf")
+	require.Error(t, err)
+	_, err = NewMentionProcessor(nil)
+	require.Error(t, err)
+}
@@ -69,6 +69,10 @@ type Message struct {
 
 	SharedLocation *SharedLocation `json:"shared_location,omitempty"`
 	Member         *ChannelMember  `json:"member,omitempty"`
+
+	// PollID attaches a poll created with CreatePoll to the message.
+	PollID string `json:"poll_id,omitempty"`
+	Poll   *Poll  `json:"poll,omitempty"`
 }
 
 type messageForJSON Message
@@ -106,6 +110,7 @@ func (m *Message) toRequest() messageRequest {
 		ExtraData:            m.ExtraData,
 		HTML:                 m.HTML,
 		Pinned:               m.Pinned,
+		PinExpires:           m.PinExpires,
 		ParentID:             m.ParentID,
 		MML:                  m.MML,
 		ShowInChannel:        m.ShowInChannel,
@@ -113,6 +118,7 @@ func (m *Message) toRequest() messageRequest {
 		QuotedMessageID:      m.QuotedMessageID,
 		RestrictedVisibility: m.RestrictedVisibility,
 		SharedLocation:       m.SharedLocation,
+		PollID:               m.PollID,
 	}
 
 	if len(m.MentionedUsers) > 0 {
@@ -150,8 +156,10 @@ type messageRequestMessage struct {
 	QuotedMessageID      string                 `json:"quoted_message_id"`
 	HTML                 string                 `json:"html,omitempty"`
 	Pinned               bool                   `json:"pinned,omitempty"`
+	PinExpires           *time.Time             `json:"pin_expires,omitempty"`
 	RestrictedVisibility []string               `json:"restricted_visibility"`
 	SharedLocation       *SharedLocation        `json:"shared_location,omitempty"`
+	PollID               string                 `json:"poll_id,omitempty"`
 	ExtraData            map[string]interface{} `json:"-"`
 }
 
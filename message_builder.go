@@ -0,0 +1,430 @@
+package stream_chat
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Attachment types understood by the Stream SDKs.
+const (
+	AttachmentTypeImage = "image"
+	AttachmentTypeVideo = "video"
+	AttachmentTypeAudio = "audio"
+	AttachmentTypeFile  = "file"
+	AttachmentTypeGiphy = "giphy"
+)
+
+// Attachment action styles.
+const (
+	AttachmentActionStylePrimary = "primary"
+	AttachmentActionStyleDefault = "default"
+)
+
+// AttachmentAction is a button rendered with an attachment. Clicking it sends
+// Name and Value to the server through the message action endpoint.
+type AttachmentAction struct {
+	Name  string `json:"name"`
+	Text  string `json:"text"`
+	Style string `json:"style,omitempty"`
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+// AttachmentBuilder assembles an Attachment of a given kind and validates
+// the fields that kind requires. Setters record problems instead of failing,
+// Build reports all of them at once.
+type AttachmentBuilder struct {
+	attachment Attachment
+	actions    []AttachmentAction
+	errs       []error
+}
+
+func newAttachmentBuilder(typ string) *AttachmentBuilder {
+	return &AttachmentBuilder{attachment: Attachment{Type: typ}}
+}
+
+// NewImageAttachment starts an image attachment pointing to imageURL.
+func NewImageAttachment(imageURL string) *AttachmentBuilder {
+	b := newAttachmentBuilder(AttachmentTypeImage)
+	b.attachment.ImageURL = b.url("image URL", imageURL)
+	return b
+}
+
+// NewVideoAttachment starts a video attachment pointing to assetURL.
+func NewVideoAttachment(assetURL string) *AttachmentBuilder {
+	b := newAttachmentBuilder(AttachmentTypeVideo)
+	b.attachment.AssetURL = b.url("asset URL", assetURL)
+	return b
+}
+
+// NewAudioAttachment starts an audio attachment pointing to assetURL.
+func NewAudioAttachment(assetURL string) *AttachmentBuilder {
+	b := newAttachmentBuilder(AttachmentTypeAudio)
+	b.attachment.AssetURL = b.url("asset URL", assetURL)
+	return b
+}
+
+// NewFileAttachment starts a file attachment pointing to assetURL. The title
+// is the file name shown to users.
+func NewFileAttachment(assetURL, title string) *AttachmentBuilder {
+	b := newAttachmentBuilder(AttachmentTypeFile)
+	b.attachment.AssetURL = b.url("asset URL", assetURL)
+	b.attachment.Title = title
+	if title == "" {
+		b.errs = append(b.errs, errors.New("file title must be not empty"))
+	}
+	return b
+}
+
+// NewGiphyAttachment starts a giphy attachment showing the gif at imageURL.
+// The title usually holds the search query.
+func NewGiphyAttachment(imageURL, title string) *AttachmentBuilder {
+	b := newAttachmentBuilder(AttachmentTypeGiphy)
+	b.attachment.ImageURL = b.url("image URL", imageURL)
+	b.attachment.ThumbURL = b.attachment.ImageURL
+	b.attachment.Title = title
+	return b
+}
+
+// NewLinkPreviewAttachment starts a link preview for pageURL. The server
+// scrapes the page and fills in the preview unless enrichment is skipped,
+// in which case the title, text and image should be set explicitly.
+func NewLinkPreviewAttachment(pageURL string) *AttachmentBuilder {
+	b := newAttachmentBuilder("")
+	b.attachment.OGScrapeURL = b.url("page URL", pageURL)
+	b.attachment.TitleLink = b.attachment.OGScrapeURL
+	return b
+}
+
+// NewActionsAttachment starts an attachment carrying action buttons. At least
+// one action must be added with Action or Button.
+func NewActionsAttachment(text string) *AttachmentBuilder {
+	b := newAttachmentBuilder("")
+	b.attachment.Text = text
+	return b
+}
+
+// NewCustomAttachment starts an attachment of a custom type, such as one
+// rendered by a custom component in the client.
+func NewCustomAttachment(typ string) *AttachmentBuilder {
+	b := newAttachmentBuilder(typ)
+	if typ == "" {
+		b.errs = append(b.errs, errors.New("attachment type must be not empty"))
+	}
+	return b
+}
+
+func (b *AttachmentBuilder) url(field, s string) string {
+	if err := validateURL(field, s); err != nil {
+		b.errs = append(b.errs, err)
+	}
+	return s
+}
+
+func validateURL(field, s string) error {
+	if s == "" {
+		return fmt.Errorf("%s must be not empty", field)
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("%s %q must be an absolute http(s) URL", field, s)
+	}
+	return nil
+}
+
+// Title sets the attachment title.
+func (b *AttachmentBuilder) Title(title string) *AttachmentBuilder {
+	b.attachment.Title = title
+	return b
+}
+
+// TitleLink sets the URL the title links to.
+func (b *AttachmentBuilder) TitleLink(link string) *AttachmentBuilder {
+	b.attachment.TitleLink = b.url("title link", link)
+	return b
+}
+
+// Text sets the attachment text.
+func (b *AttachmentBuilder) Text(text string) *AttachmentBuilder {
+	b.attachment.Text = text
+	return b
+}
+
+// Author sets the author name shown with the attachment.
+func (b *AttachmentBuilder) Author(name string) *AttachmentBuilder {
+	b.attachment.AuthorName = name
+	return b
+}
+
+// Image sets the image shown with the attachment, e.g. a link preview image.
+func (b *AttachmentBuilder) Image(imageURL string) *AttachmentBuilder {
+	b.attachment.ImageURL = b.url("image URL", imageURL)
+	return b
+}
+
+// Thumb sets the thumbnail URL, e.g. a video poster.
+func (b *AttachmentBuilder) Thumb(thumbURL string) *AttachmentBuilder {
+	b.attachment.ThumbURL = b.url("thumb URL", thumbURL)
+	return b
+}
+
+// MimeType sets the MIME type of the asset.
+func (b *AttachmentBuilder) MimeType(mimeType string) *AttachmentBuilder {
+	b.attachment.MimeType = mimeType
+	return b
+}
+
+// FileSize sets the size of the asset in bytes.
+func (b *AttachmentBuilder) FileSize(size int64) *AttachmentBuilder {
+	if size < 0 {
+		b.errs = append(b.errs, fmt.Errorf("file size %d must be not negative", size))
+	}
+	return b.Set("file_size", size)
+}
+
+// Dimensions sets the original width and height of an image or video.
+func (b *AttachmentBuilder) Dimensions(width, height int) *AttachmentBuilder {
+	if width <= 0 || height <= 0 {
+		b.errs = append(b.errs, fmt.Errorf("dimensions %dx%d must be positive", width, height))
+	}
+	return b.Set("original_width", width).Set("original_height", height)
+}
+
+// Duration sets the playback length of an audio or video asset.
+func (b *AttachmentBuilder) Duration(d time.Duration) *AttachmentBuilder {
+	if d < 0 {
+		b.errs = append(b.errs, fmt.Errorf("duration %s must be not negative", d))
+	}
+	return b.Set("duration", d.Seconds())
+}
+
+// Action adds a custom action to the attachment.
+func (b *AttachmentBuilder) Action(action AttachmentAction) *AttachmentBuilder {
+	label := fmt.Sprintf("%q", action.Name)
+	if action.Name == "" {
+		label = fmt.Sprintf("#%d", len(b.actions)+1)
+		b.errs = append(b.errs, fmt.Errorf("action %s: name must be not empty", label))
+	}
+	if action.Text == "" {
+		b.errs = append(b.errs, fmt.Errorf("action %s: text must be not empty", label))
+	}
+	if action.Type == "" {
+		b.errs = append(b.errs, fmt.Errorf("action %s: type must be not empty", label))
+	}
+	switch action.Style {
+	case "", AttachmentActionStylePrimary, AttachmentActionStyleDefault:
+	default:
+		b.errs = append(b.errs, fmt.Errorf("action %s: unknown style %q", label, action.Style))
+	}
+	b.actions = append(b.actions, action)
+	return b
+}
+
+// Button adds a button action sending name and value when clicked.
+func (b *AttachmentBuilder) Button(name, text, value, style string) *AttachmentBuilder {
+	return b.Action(AttachmentAction{Name: name, Text: text, Value: value, Style: style, Type: "button"})
+}
+
+// Set sets a custom field on the attachment.
+func (b *AttachmentBuilder) Set(key string, value interface{}) *AttachmentBuilder {
+	if b.attachment.ExtraData == nil {
+		b.attachment.ExtraData = make(map[string]interface{})
+	}
+	b.attachment.ExtraData[key] = value
+	return b
+}
+
+// Build validates the attachment and returns it.
+func (b *AttachmentBuilder) Build() (*Attachment, error) {
+	errs := b.errs
+	if b.attachment.Type == "" && b.attachment.OGScrapeURL == "" && len(b.actions) == 0 {
+		errs = append(errs, errors.New("actions attachment must have at least one action"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	a := b.attachment
+	a.ExtraData = maps.Clone(b.attachment.ExtraData)
+	if len(b.actions) > 0 {
+		if a.ExtraData == nil {
+			a.ExtraData = make(map[string]interface{})
+		}
+		a.ExtraData["actions"] = slices.Clone(b.actions)
+	}
+	return &a, nil
+}
+
+// MessageBuilder assembles a Message ready to be passed to SendMessage.
+// Like AttachmentBuilder, it collects problems and reports them from Build.
+type MessageBuilder struct {
+	message     Message
+	attachments []*AttachmentBuilder
+	pinFor      time.Duration
+	errs        []error
+	now         func() time.Time
+}
+
+// NewMessageBuilder starts a message with the given text.
+func NewMessageBuilder(text string) *MessageBuilder {
+	return &MessageBuilder{message: Message{Text: text}, now: time.Now}
+}
+
+// Text sets the message text.
+func (b *MessageBuilder) Text(text string) *MessageBuilder {
+	b.message.Text = text
+	return b
+}
+
+// ID sets a client generated message ID.
+func (b *MessageBuilder) ID(id string) *MessageBuilder {
+	b.message.ID = id
+	return b
+}
+
+// Attach adds an attachment. Its validation errors are reported by Build.
+func (b *MessageBuilder) Attach(attachments ...*AttachmentBuilder) *MessageBuilder {
+	b.attachments = append(b.attachments, attachments...)
+	return b
+}
+
+// Quote makes the message quote the message with the given ID.
+func (b *MessageBuilder) Quote(messageID string) *MessageBuilder {
+	if messageID == "" {
+		b.errs = append(b.errs, errors.New("quoted message ID must be not empty"))
+	}
+	b.message.QuotedMessageID = messageID
+	return b
+}
+
+// Reply makes the message a thread reply to parentID. When showInChannel is
+// set the reply is shown in the channel as well.
+func (b *MessageBuilder) Reply(parentID string, showInChannel bool) *MessageBuilder {
+	if parentID == "" {
+		b.errs = append(b.errs, errors.New("parent message ID must be not empty"))
+	}
+	b.message.ParentID = parentID
+	b.message.ShowInChannel = showInChannel
+	return b
+}
+
+// RestrictTo makes the message visible only to the given users.
+func (b *MessageBuilder) RestrictTo(userIDs ...string) *MessageBuilder {
+	if len(userIDs) == 0 {
+		b.errs = append(b.errs, errors.New("restricted visibility must be not empty"))
+	}
+	b.message.RestrictedVisibility = dedupeSorted(append(b.message.RestrictedVisibility, userIDs...))
+	return b
+}
+
+// Silent marks the message as silent: it does not increase unread counts or
+// trigger push notifications.
+func (b *MessageBuilder) Silent() *MessageBuilder {
+	b.message.Silent = true
+	return b
+}
+
+// Pin pins the message. A zero expiry pins it until it is unpinned.
+func (b *MessageBuilder) Pin(expires time.Time) *MessageBuilder {
+	b.message.Pinned = true
+	b.message.PinExpires = nil
+	b.pinFor = 0
+	if !expires.IsZero() {
+		b.message.PinExpires = &expires
+	}
+	return b
+}
+
+// PinFor pins the message for the given duration from the time Build is called.
+func (b *MessageBuilder) PinFor(d time.Duration) *MessageBuilder {
+	if d <= 0 {
+		b.errs = append(b.errs, fmt.Errorf("pin duration %s must be positive", d))
+	}
+	b.message.Pinned = true
+	b.message.PinExpires = nil
+	b.pinFor = d
+	return b
+}
+
+// Location shares a static location from the given device.
+func (b *MessageBuilder) Location(latitude, longitude float64, deviceID string) *MessageBuilder {
+	return b.LiveLocation(latitude, longitude, deviceID, time.Time{})
+}
+
+// LiveLocation shares a live location from the given device, updated until endAt.
+// A zero endAt shares a static location.
+func (b *MessageBuilder) LiveLocation(latitude, longitude float64, deviceID string, endAt time.Time) *MessageBuilder {
+	if latitude < -90 || latitude > 90 {
+		b.errs = append(b.errs, fmt.Errorf("latitude %v must be between -90 and 90", latitude))
+	}
+	if longitude < -180 || longitude > 180 {
+		b.errs = append(b.errs, fmt.Errorf("longitude %v must be between -180 and 180", longitude))
+	}
+	if deviceID == "" {
+		b.errs = append(b.errs, errors.New("location device ID must be not empty"))
+	}
+	loc := &SharedLocation{Latitude: &latitude, Longitude: &longitude, CreatedByDeviceID: deviceID}
+	if !endAt.IsZero() {
+		loc.EndAt = &endAt
+	}
+	b.message.SharedLocation = loc
+	return b
+}
+
+// Mention adds the given users to the mentioned users of the message.
+func (b *MessageBuilder) Mention(userIDs ...string) *MessageBuilder {
+	for _, id := range userIDs {
+		b.message.MentionedUsers = append(b.message.MentionedUsers, &User{ID: id})
+	}
+	return b
+}
+
+// Set sets a custom field on the message.
+func (b *MessageBuilder) Set(key string, value interface{}) *MessageBuilder {
+	if b.message.ExtraData == nil {
+		b.message.ExtraData = make(map[string]interface{})
+	}
+	b.message.ExtraData[key] = value
+	return b
+}
+
+// Build validates the message and its attachments and returns the message.
+func (b *MessageBuilder) Build() (*Message, error) {
+	errs := append([]error(nil), b.errs...)
+	m := b.message
+	m.ExtraData = maps.Clone(b.message.ExtraData)
+	m.RestrictedVisibility = slices.Clone(b.message.RestrictedVisibility)
+	m.MentionedUsers = slices.Clone(b.message.MentionedUsers)
+	m.Attachments = nil
+	for i, ab := range b.attachments {
+		a, err := ab.Build()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("attachment #%d: %w", i+1, err))
+			continue
+		}
+		m.Attachments = append(m.Attachments, a)
+	}
+
+	now := b.now()
+	if b.pinFor > 0 {
+		expires := now.Add(b.pinFor)
+		m.PinExpires = &expires
+	}
+	if m.PinExpires != nil && !m.PinExpires.After(now) {
+		errs = append(errs, fmt.Errorf("pin expiry %s must be in the future", m.PinExpires.Format(time.RFC3339)))
+	}
+	if loc := m.SharedLocation; loc != nil && loc.EndAt != nil && !loc.EndAt.After(now) {
+		errs = append(errs, fmt.Errorf("live location end %s must be in the future", loc.EndAt.Format(time.RFC3339)))
+	}
+	if m.Text == "" && len(b.attachments) == 0 && m.SharedLocation == nil {
+		errs = append(errs, errors.New("message text, attachments or location must be not empty"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,98 @@
+package stream_chat
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageBuilder_Build(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	b := NewMessageBuilder("look at this").
+		Attach(
+			NewImageAttachment("https://cdn.example.com/cat.png").Dimensions(640, 480),
+			NewFileAttachment("https://cdn.example.com/report.pdf", "report.pdf").MimeType("application/pdf").FileSize(1024),
+			NewActionsAttachment("Approve the request?").
+				Button("decision", "Approve", "yes", AttachmentActionStylePrimary).
+				Button("decision", "Reject", "no", ""),
+		).
+		Quote("quoted").
+		Reply("parent", true).
+		RestrictTo("jane", "bob", "jane").
+		Silent().
+		PinFor(time.Hour).
+		Set("priority", "high")
+	b.now = func() time.Time { return now }
+
+	m, err := b.Build()
+	require.NoError(t, err)
+	require.Equal(t, "quoted", m.QuotedMessageID)
+	require.Equal(t, "parent", m.ParentID)
+	require.True(t, m.ShowInChannel)
+	require.Equal(t, []string{"bob", "jane"}, m.RestrictedVisibility)
+	require.True(t, m.Silent)
+	require.True(t, m.Pinned)
+	require.Equal(t, now.Add(time.Hour), *m.PinExpires)
+	require.Len(t, m.Attachments, 3)
+	require.Equal(t, AttachmentTypeImage, m.Attachments[0].Type)
+	require.Equal(t, 640, m.Attachments[0].ExtraData["original_width"])
+	require.Equal(t, AttachmentTypeFile, m.Attachments[1].Type)
+
+	req, err := json.Marshal(m.toRequest())
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(req, &decoded))
+	msg := decoded["message"].(map[string]interface{})
+	require.Equal(t, "2024-01-01T13:00:00Z", msg["pin_expires"])
+	require.Equal(t, "high", msg["priority"])
+	actions := msg["attachments"].([]interface{})[2].(map[string]interface{})["actions"].([]interface{})
+	require.Equal(t, map[string]interface{}{"name": "decision", "text": "Approve", "style": "primary", "type": "button", "value": "yes"}, actions[0])
+
+	// building again does not share state with the first message
+	b.Set("priority", "low")
+	require.Equal(t, "high", m.ExtraData["priority"])
+
+	loc, err := NewMessageBuilder("").LiveLocation(52.37, 4.89, "phone", time.Now().Add(time.Hour)).Build()
+	require.NoError(t, err)
+	require.Equal(t, 52.37, *loc.SharedLocation.Latitude)
+	require.Equal(t, "phone", loc.SharedLocation.CreatedByDeviceID)
+	require.NotNil(t, loc.SharedLocation.EndAt)
+}
+
+func TestMessageBuilder_Validation(t *testing.T) {
+	_, err := NewMessageBuilder("").Build()
+	require.Error(t, err)
+
+	_, err = NewMessageBuilder("hi").
+		Attach(
+			NewImageAttachment(""),
+			NewVideoAttachment("ftp://example.com/movie.mp4"),
+			NewActionsAttachment("nothing to click"),
+			NewLinkPreviewAttachment("https://example.com").Button("", "Open", "", "loud"),
+		).
+		Reply("", false).
+		Pin(time.Now().Add(-time.Minute)).
+		Location(91, 0, "").
+		Build()
+	require.Error(t, err)
+	for _, msg := range []string{
+		"attachment #1: image URL must be not empty",
+		`attachment #2: asset URL "ftp://example.com/movie.mp4" must be an absolute http(s) URL`,
+		"attachment #3: actions attachment must have at least one action",
+		"attachment #4: action #1: name must be not empty",
+		`action #1: unknown style "loud"`,
+		"parent message ID must be not empty",
+		"latitude 91 must be between -90 and 90",
+		"location device ID must be not empty",
+		"must be in the future",
+	} {
+		require.Contains(t, err.Error(), msg)
+	}
+
+	a, err := NewGiphyAttachment("https://media.giphy.com/cat.gif", "cat").Build()
+	require.NoError(t, err)
+	require.Equal(t, a.ImageURL, a.ThumbURL)
+}
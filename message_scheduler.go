@@ -0,0 +1,554 @@
+package stream_chat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrScheduledMessageNotFound is returned for unknown scheduled message IDs.
+	ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+	// ErrScheduledMessageNotPending is returned when canceling or rescheduling
+	// a message which was already sent, failed or canceled.
+	ErrScheduledMessageNotPending = errors.New("scheduled message is not pending")
+)
+
+// ScheduledMessageStatus is the delivery status of a scheduled message.
+type ScheduledMessageStatus string
+
+const (
+	ScheduledMessagePending  ScheduledMessageStatus = "pending"
+	ScheduledMessageSent     ScheduledMessageStatus = "sent"
+	ScheduledMessageFailed   ScheduledMessageStatus = "failed"
+	ScheduledMessageCanceled ScheduledMessageStatus = "canceled"
+)
+
+// ScheduledMessage is a message waiting to be sent, or the record of one
+// which was sent, failed or canceled.
+type ScheduledMessage struct {
+	// ID identifies the schedule. It is also the ID of the sent message, so
+	// delivering the same scheduled message twice cannot create a duplicate.
+	ID      string                 `json:"id"`
+	CID     string                 `json:"cid"`
+	UserID  string                 `json:"user_id"`
+	Message *Message               `json:"message"`
+	SendAt  time.Time              `json:"send_at"`
+	Status  ScheduledMessageStatus `json:"status"`
+
+	// Attempts is the number of failed delivery attempts.
+	Attempts int `json:"attempts,omitempty"`
+	// NextAttemptAt is when delivery is attempted next, later than SendAt
+	// after a transient failure.
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (m *ScheduledMessage) clone() (*ScheduledMessage, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode scheduled message %s: %w", m.ID, err)
+	}
+	var out ScheduledMessage
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("cannot decode scheduled message %s: %w", m.ID, err)
+	}
+	return &out, nil
+}
+
+// ScheduledMessageStore persists scheduled messages.
+type ScheduledMessageStore interface {
+	// Get returns the scheduled message with the given ID, or
+	// ErrScheduledMessageNotFound.
+	Get(ctx context.Context, id string) (*ScheduledMessage, error)
+	// Put inserts or replaces a scheduled message.
+	Put(ctx context.Context, m *ScheduledMessage) error
+	// Due returns up to limit pending messages whose next attempt is not
+	// after now, the earliest first.
+	Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledMessage, error)
+	// List returns all scheduled messages ordered by send time.
+	List(ctx context.Context) ([]*ScheduledMessage, error)
+}
+
+// MemoryScheduledMessageStore is a ScheduledMessageStore that keeps the messages in memory.
+type MemoryScheduledMessageStore struct {
+	mu       sync.Mutex
+	messages map[string]*ScheduledMessage
+}
+
+// NewMemoryScheduledMessageStore returns an empty in-memory store.
+func NewMemoryScheduledMessageStore() *MemoryScheduledMessageStore {
+	return &MemoryScheduledMessageStore{messages: make(map[string]*ScheduledMessage)}
+}
+
+func (s *MemoryScheduledMessageStore) Get(_ context.Context, id string) (*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[id]
+	if !ok {
+		return nil, ErrScheduledMessageNotFound
+	}
+	return m.clone()
+}
+
+func (s *MemoryScheduledMessageStore) Put(_ context.Context, m *ScheduledMessage) error {
+	c, err := m.clone()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages[m.ID] = c
+	return nil
+}
+
+func (s *MemoryScheduledMessageStore) Due(_ context.Context, now time.Time, limit int) ([]*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*ScheduledMessage
+	for _, m := range s.messages {
+		if m.Status == ScheduledMessagePending && !m.NextAttemptAt.After(now) {
+			due = append(due, m)
+		}
+	}
+	slices.SortFunc(due, func(a, b *ScheduledMessage) int {
+		if c := a.NextAttemptAt.Compare(b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	for i, m := range due {
+		c, err := m.clone()
+		if err != nil {
+			return nil, err
+		}
+		due[i] = c
+	}
+	return due, nil
+}
+
+func (s *MemoryScheduledMessageStore) List(_ context.Context) ([]*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]*ScheduledMessage, 0, len(s.messages))
+	for _, m := range s.messages {
+		c, err := m.clone()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	slices.SortFunc(out, func(a, b *ScheduledMessage) int {
+		if c := a.SendAt.Compare(b.SendAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return out, nil
+}
+
+// FileScheduledMessageStore is a ScheduledMessageStore backed by a JSON file.
+// The messages are kept in memory and the file is rewritten atomically on
+// every change, so it suits schedules of up to a few thousand messages.
+type FileScheduledMessageStore struct {
+	*MemoryScheduledMessageStore
+	path string
+}
+
+// NewFileScheduledMessageStore returns a store persisting the messages to path,
+// loading the messages already saved there. The file is created on first change.
+func NewFileScheduledMessageStore(path string) (*FileScheduledMessageStore, error) {
+	if path == "" {
+		return nil, errors.New("path must not be empty")
+	}
+
+	s := &FileScheduledMessageStore{MemoryScheduledMessageStore: NewMemoryScheduledMessageStore(), path: path}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var messages []*ScheduledMessage
+	if err := json.Unmarshal(b, &messages); err != nil {
+		return nil, fmt.Errorf("cannot decode scheduled messages %s: %w", path, err)
+	}
+	for _, m := range messages {
+		s.messages[m.ID] = m
+	}
+	return s, nil
+}
+
+func (s *FileScheduledMessageStore) Put(ctx context.Context, m *ScheduledMessage) error {
+	c, err := m.clone()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, existed := s.messages[m.ID]
+	s.messages[m.ID] = c
+
+	messages := make([]*ScheduledMessage, 0, len(s.messages))
+	for _, m := range s.messages {
+		messages = append(messages, m)
+	}
+	slices.SortFunc(messages, func(a, b *ScheduledMessage) int { return strings.Compare(a.ID, b.ID) })
+	b, err := json.MarshalIndent(messages, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(s.path, b)
+	}
+	if err != nil {
+		// keep memory consistent with the file
+		if existed {
+			s.messages[m.ID] = prev
+		} else {
+			delete(s.messages, m.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// MessageSchedulerOptions configures a MessageScheduler.
+type MessageSchedulerOptions struct {
+	// Store persists the scheduled messages. Defaults to an in-memory store.
+	Store ScheduledMessageStore
+	// PollInterval is how often Run checks for due messages. Defaults to 1 second.
+	PollInterval time.Duration
+	// MaxAttempts is the number of delivery attempts before a message is
+	// marked as failed. Defaults to 10.
+	MaxAttempts int
+	// RetryBackoff is the delay before the first retry, doubled on every
+	// following one up to MaxRetryBackoff. Defaults to 1 second and 5 minutes.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// SendOptions are applied to every sent message, e.g. MessageSkipPush.
+	SendOptions []SendMessageOption
+}
+
+// MessageScheduler sends messages at a later time. Stream has no server-side
+// scheduled send, so the messages are kept in a store and sent by Run.
+//
+// Delivery is at least once: a message is marked as sent only after the API
+// accepted it. Because the message ID is fixed when the message is scheduled,
+// a retry after an interruption does not create a duplicate.
+type MessageScheduler struct {
+	client *Client
+	opts   MessageSchedulerOptions
+	now    func() time.Time
+
+	// mu guards the store updates and sending, the IDs of the messages
+	// being sent. It is not held while a message is sent.
+	mu      sync.Mutex
+	sending map[string]bool
+}
+
+// NewMessageScheduler returns a scheduler sending messages with c.
+func NewMessageScheduler(c *Client, opts *MessageSchedulerOptions) (*MessageScheduler, error) {
+	if c == nil {
+		return nil, errors.New("client is nil")
+	}
+
+	s := &MessageScheduler{client: c, now: time.Now, sending: make(map[string]bool)}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.Store == nil {
+		s.opts.Store = NewMemoryScheduledMessageStore()
+	}
+	if s.opts.PollInterval <= 0 {
+		s.opts.PollInterval = time.Second
+	}
+	if s.opts.MaxAttempts <= 0 {
+		s.opts.MaxAttempts = 10
+	}
+	if s.opts.RetryBackoff <= 0 {
+		s.opts.RetryBackoff = time.Second
+	}
+	if s.opts.MaxRetryBackoff <= 0 {
+		s.opts.MaxRetryBackoff = 5 * time.Minute
+	}
+	return s, nil
+}
+
+// ScheduleMessage schedules msg to be sent by userID to the channel cid at the given time.
+// The message ID is used as the schedule ID; a random one is generated when it is empty.
+// Scheduling a message with the ID of an existing schedule fails.
+func (s *MessageScheduler) ScheduleMessage(ctx context.Context, cid string, msg *Message, userID string, at time.Time) (*ScheduledMessage, error) {
+	switch {
+	case msg == nil:
+		return nil, errors.New("message is nil")
+	case userID == "":
+		return nil, errors.New("user ID must be not empty")
+	case at.IsZero():
+		return nil, errors.New("send time must be not empty")
+	}
+	if parts := strings.SplitN(cid, ":", 2); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid channel CID %q", cid)
+	}
+
+	out := *msg
+	if out.ID == "" {
+		id, err := newScheduledMessageID()
+		if err != nil {
+			return nil, err
+		}
+		out.ID = id
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.opts.Store.Get(ctx, out.ID)
+	switch {
+	case err == nil:
+		return nil, fmt.Errorf("message %s is already scheduled", out.ID)
+	case !errors.Is(err, ErrScheduledMessageNotFound):
+		return nil, err
+	}
+
+	now := s.now()
+	m := &ScheduledMessage{
+		ID:            out.ID,
+		CID:           cid,
+		UserID:        userID,
+		Message:       &out,
+		SendAt:        at,
+		Status:        ScheduledMessagePending,
+		NextAttemptAt: at,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.opts.Store.Put(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func newScheduledMessageID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Get returns the scheduled message with the given ID.
+func (s *MessageScheduler) Get(ctx context.Context, id string) (*ScheduledMessage, error) {
+	return s.opts.Store.Get(ctx, id)
+}
+
+// Cancel cancels a pending scheduled message. A message which is being sent
+// cannot be canceled anymore.
+func (s *MessageScheduler) Cancel(ctx context.Context, id string) (*ScheduledMessage, error) {
+	return s.update(ctx, id, func(m *ScheduledMessage) {
+		m.Status = ScheduledMessageCanceled
+	})
+}
+
+// Reschedule moves a pending scheduled message to a new send time and
+// resets its delivery attempts.
+func (s *MessageScheduler) Reschedule(ctx context.Context, id string, at time.Time) (*ScheduledMessage, error) {
+	if at.IsZero() {
+		return nil, errors.New("send time must be not empty")
+	}
+	return s.update(ctx, id, func(m *ScheduledMessage) {
+		m.SendAt = at
+		m.NextAttemptAt = at
+		m.Attempts = 0
+		m.LastError = ""
+	})
+}
+
+func (s *MessageScheduler) update(ctx context.Context, id string, fn func(*ScheduledMessage)) (*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.opts.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != ScheduledMessagePending {
+		return nil, fmt.Errorf("message %s is %s: %w", id, m.Status, ErrScheduledMessageNotPending)
+	}
+	if s.sending[id] {
+		return nil, fmt.Errorf("message %s is being sent: %w", id, ErrScheduledMessageNotPending)
+	}
+	fn(m)
+	m.UpdatedAt = s.now()
+	if err := s.opts.Store.Put(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Run sends due messages every PollInterval until ctx is canceled.
+// Errors of the store are returned; delivery errors are recorded on the
+// scheduled messages.
+func (s *MessageScheduler) Run(ctx context.Context) error {
+	for {
+		if _, err := s.SendDue(ctx); err != nil {
+			return err
+		}
+		if err := sleepContext(ctx, s.opts.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// SendDue sends the messages which are due now and returns how many were sent.
+func (s *MessageScheduler) SendDue(ctx context.Context) (int, error) {
+	const batchSize = 100
+
+	sent := 0
+	for {
+		due, err := s.opts.Store.Due(ctx, s.now(), batchSize)
+		if err != nil {
+			return sent, fmt.Errorf("load due messages: %w", err)
+		}
+		progress := false
+		for _, m := range due {
+			ok, changed, err := s.deliver(ctx, m.ID)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+			progress = progress || changed
+		}
+		if len(due) < batchSize || !progress {
+			return sent, nil
+		}
+	}
+}
+
+// deliver sends one due message and records the outcome. It reports whether
+// the message was sent and whether its record changed.
+func (s *MessageScheduler) deliver(ctx context.Context, id string) (bool, bool, error) {
+	m, err := s.claim(ctx, id)
+	if m == nil || err != nil {
+		return false, false, err
+	}
+
+	sendErr := s.send(ctx, m)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sending, id)
+
+	if ctx.Err() != nil {
+		return false, false, ctx.Err()
+	}
+
+	now := s.now()
+	m.UpdatedAt = now
+	switch {
+	case sendErr == nil:
+		m.Status = ScheduledMessageSent
+		m.SentAt = &now
+		m.LastError = ""
+	case isRetryableSendError(sendErr) && m.Attempts+1 < s.opts.MaxAttempts:
+		m.Attempts++
+		m.LastError = sendErr.Error()
+		m.NextAttemptAt = now.Add(s.backoff(m.Attempts, sendErr))
+	default:
+		m.Attempts++
+		m.Status = ScheduledMessageFailed
+		m.LastError = sendErr.Error()
+	}
+	if err := s.opts.Store.Put(ctx, m); err != nil {
+		return false, false, fmt.Errorf("save scheduled message %s: %w", m.ID, err)
+	}
+	return sendErr == nil, true, nil
+}
+
+// claim marks a due message as being sent, so it is not canceled, rescheduled
+// or sent by another SendDue while the lock is released. It returns nil when
+// the message is not due anymore.
+func (s *MessageScheduler) claim(ctx context.Context, id string) (*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sending[id] {
+		return nil, nil
+	}
+	// the message may have been canceled or rescheduled since it was loaded
+	m, err := s.opts.Store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != ScheduledMessagePending || m.NextAttemptAt.After(s.now()) {
+		return nil, nil
+	}
+	s.sending[id] = true
+	return m, nil
+}
+
+func (s *MessageScheduler) send(ctx context.Context, m *ScheduledMessage) error {
+	parts := strings.SplitN(m.CID, ":", 2)
+	ch := s.client.Channel(parts[0], parts[1])
+
+	msg := *m.Message
+	_, err := ch.SendMessage(ctx, &msg, m.UserID, s.opts.SendOptions...)
+	if err == nil {
+		return nil
+	}
+
+	// the message may have been sent by an earlier attempt whose outcome was lost
+	var apiErr Error
+	if errors.As(err, &apiErr) && !isTransientError(err) {
+		if resp, getErr := s.client.GetMessage(ctx, m.ID); getErr == nil && resp.Message != nil && resp.Message.CID == m.CID {
+			return nil
+		}
+	}
+	return err
+}
+
+// isRetryableSendError reports whether sending may succeed later: the API was
+// rate limited or unavailable, or the request did not reach it.
+func isRetryableSendError(err error) bool {
+	var apiErr Error
+	if !errors.As(err, &apiErr) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return isTransientError(err)
+}
+
+func (s *MessageScheduler) backoff(attempt int, err error) time.Duration {
+	var apiErr Error
+	if errors.As(err, &apiErr) && apiErr.RateLimit != nil && apiErr.RateLimit.Reset > 0 {
+		if wait := apiErr.RateLimit.ResetTime().Sub(s.now()); wait > 0 {
+			return min(wait, s.opts.MaxRetryBackoff)
+		}
+	}
+	d := s.opts.RetryBackoff << min(attempt-1, 30)
+	if d <= 0 || d > s.opts.MaxRetryBackoff {
+		return s.opts.MaxRetryBackoff
+	}
+	return d
+}
@@ -0,0 +1,189 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageScheduler(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	sent := make(map[string]int)
+	failures := map[string]int{"retry": 1}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/channels/messaging/general/message":
+			var req messageRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			id := req.Message.ID
+			require.True(t, req.SkipPush)
+			switch {
+			case failures[id] > 0:
+				failures[id]--
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"code": 0, "message": "unavailable", "StatusCode": 503}`))
+			case id == "sent-before" || id == "invalid":
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"code": 4, "message": "bad request", "StatusCode": 400}`))
+			default:
+				sent[id]++
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]interface{}{"id": id, "cid": "messaging:general"}})
+			}
+		case r.Method == http.MethodGet && r.URL.Path == "/messages/sent-before":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]interface{}{"id": "sent-before", "cid": "messaging:general"}})
+		case r.Method == http.MethodGet && r.URL.Path == "/messages/invalid":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code": 16, "message": "not found", "StatusCode": 404}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+
+	path := filepath.Join(t.TempDir(), "scheduled.json")
+	store, err := NewFileScheduledMessageStore(path)
+	require.NoError(t, err)
+	opts := &MessageSchedulerOptions{Store: store, MaxAttempts: 2, RetryBackoff: time.Minute, SendOptions: []SendMessageOption{MessageSkipPush}}
+	s, err := NewMessageScheduler(c, opts)
+	require.NoError(t, err)
+	s.now = func() time.Time { return now }
+
+	at := now.Add(time.Hour)
+	for _, id := range []string{"retry", "sent-before", "invalid", "canceled", "moved"} {
+		_, err := s.ScheduleMessage(ctx, "messaging:general", &Message{ID: id, Text: id}, "jane", at)
+		require.NoError(t, err)
+	}
+	auto, err := s.ScheduleMessage(ctx, "messaging:general", &Message{Text: "generated"}, "jane", at)
+	require.NoError(t, err)
+	require.Len(t, auto.ID, 32)
+	require.Equal(t, auto.ID, auto.Message.ID)
+
+	_, err = s.ScheduleMessage(ctx, "messaging:general", &Message{ID: "retry"}, "jane", at)
+	require.Error(t, err)
+	_, err = s.ScheduleMessage(ctx, "general", &Message{Text: "hi"}, "jane", at)
+	require.Error(t, err)
+
+	_, err = s.Cancel(ctx, "canceled")
+	require.NoError(t, err)
+	_, err = s.Reschedule(ctx, "moved", at.Add(time.Hour))
+	require.NoError(t, err)
+
+	n, err := s.SendDue(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// the schedule survives a restart
+	store, err = NewFileScheduledMessageStore(path)
+	require.NoError(t, err)
+	opts.Store = store
+	s, err = NewMessageScheduler(c, opts)
+	require.NoError(t, err)
+	now = at
+	s.now = func() time.Time { return now }
+
+	n, err = s.SendDue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, map[string]int{auto.ID: 1}, sent)
+
+	retry, err := s.Get(ctx, "retry")
+	require.NoError(t, err)
+	require.Equal(t, ScheduledMessagePending, retry.Status)
+	require.Equal(t, 1, retry.Attempts)
+	require.Equal(t, at.Add(time.Minute), retry.NextAttemptAt)
+
+	before, err := s.Get(ctx, "sent-before")
+	require.NoError(t, err)
+	require.Equal(t, ScheduledMessageSent, before.Status)
+
+	invalid, err := s.Get(ctx, "invalid")
+	require.NoError(t, err)
+	require.Equal(t, ScheduledMessageFailed, invalid.Status)
+	require.Contains(t, invalid.LastError, "bad request")
+
+	_, err = s.Cancel(ctx, "invalid")
+	require.ErrorIs(t, err, ErrScheduledMessageNotPending)
+	_, err = s.Cancel(ctx, "unknown")
+	require.ErrorIs(t, err, ErrScheduledMessageNotFound)
+
+	now = at.Add(time.Hour)
+	n, err = s.SendDue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, map[string]int{auto.ID: 1, "retry": 1, "moved": 1}, sent)
+
+	all, err := s.opts.Store.List(ctx)
+	require.NoError(t, err)
+	statuses := make(map[string]ScheduledMessageStatus)
+	for _, m := range all {
+		statuses[m.ID] = m.Status
+	}
+	require.Equal(t, map[string]ScheduledMessageStatus{
+		auto.ID: ScheduledMessageSent, "retry": ScheduledMessageSent, "sent-before": ScheduledMessageSent,
+		"invalid": ScheduledMessageFailed, "canceled": ScheduledMessageCanceled, "moved": ScheduledMessageSent,
+	}, statuses)
+
+	// nothing is sent twice
+	n, err = s.SendDue(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestMessageScheduler_CancelWhileSending(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]interface{}{"id": "slow", "cid": "messaging:general"}})
+	}))
+	s, err := NewMessageScheduler(c, nil)
+	require.NoError(t, err)
+
+	_, err = s.ScheduleMessage(ctx, "messaging:general", &Message{ID: "slow", Text: "hi"}, "jane", time.Now())
+	require.NoError(t, err)
+
+	done := make(chan int)
+	go func() {
+		n, err := s.SendDue(ctx)
+		require.NoError(t, err)
+		done <- n
+	}()
+	<-started
+
+	// the message is already on its way, so it cannot be canceled, but Cancel
+	// and the other messages are not blocked by the request
+	_, err = s.Cancel(ctx, "slow")
+	require.ErrorIs(t, err, ErrScheduledMessageNotPending)
+	_, err = s.ScheduleMessage(ctx, "messaging:general", &Message{ID: "later", Text: "hi"}, "jane", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = s.Cancel(ctx, "later")
+	require.NoError(t, err)
+
+	close(release)
+	require.Equal(t, 1, <-done)
+	m, err := s.Get(ctx, "slow")
+	require.NoError(t, err)
+	require.Equal(t, ScheduledMessageSent, m.Status)
+}
+
+func TestMessageScheduler_UnencodableMessage(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewMessageScheduler(&Client{}, nil)
+	require.NoError(t, err)
+
+	msg := &Message{ID: "bad", Text: "hi", ExtraData: map[string]interface{}{"fn": func() {}}}
+	_, err = s.ScheduleMessage(ctx, "messaging:general", msg, "jane", time.Now())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot encode scheduled message bad")
+
+	_, err = s.Get(ctx, "bad")
+	require.ErrorIs(t, err, ErrScheduledMessageNotFound)
+}
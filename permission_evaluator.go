@@ -0,0 +1,261 @@
+package stream_chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResourceOwnership tells whether the resource of an action, such as a message, belongs
+// to the user performing it.
+type ResourceOwnership bool
+
+const (
+	ResourceOwned    ResourceOwnership = true
+	ResourceNotOwned ResourceOwnership = false
+)
+
+func (o ResourceOwnership) String() string {
+	if o {
+		return "own"
+	}
+	return "another user's"
+}
+
+// PermissionSource is what decided a PermissionDecision.
+type PermissionSource string
+
+const (
+	PermissionSourceChannelTypeGrant PermissionSource = "channel_type_grant"
+	PermissionSourceAppGrant         PermissionSource = "app_grant"
+	PermissionSourcePolicy           PermissionSource = "policy"
+	PermissionSourceDefault          PermissionSource = "default"
+)
+
+// PermissionDecision is the answer of PermissionEvaluator.Can with the grant or policy which decided it.
+type PermissionDecision struct {
+	Allowed     bool              `json:"allowed"`
+	Role        string            `json:"role"`
+	Action      string            `json:"action"`
+	ChannelType string            `json:"channel_type,omitempty"`
+	Ownership   ResourceOwnership `json:"ownership"`
+	Source      PermissionSource  `json:"source"`
+	// Permission is the granted permission which allowed the action.
+	Permission *Permission `json:"permission,omitempty"`
+	// Policy is the legacy policy which allowed or denied the action.
+	Policy *Policy `json:"policy,omitempty"`
+	// Conditional is true if the permission has a condition or is limited to the user's team,
+	// which can't be evaluated offline, so the action is only allowed when they hold.
+	Conditional bool `json:"conditional,omitempty"`
+	// Reason explains the decision.
+	Reason string `json:"reason"`
+}
+
+// String formats the decision for humans.
+func (d *PermissionDecision) String() string {
+	verdict := "denied"
+	if d.Allowed {
+		verdict = "allowed"
+	}
+	scope := "the app"
+	if d.ChannelType != "" {
+		scope = fmt.Sprintf("channel type %q", d.ChannelType)
+	}
+	return fmt.Sprintf("%s on %s resource by role %q in %s: %s, %s", d.Action, d.Ownership, d.Role, scope, verdict, d.Reason)
+}
+
+// PermissionEvaluatorData is the configuration a PermissionEvaluator works on, as returned
+// by ListPermissions, ListRoles, ListChannelTypes and GetAppSettings.
+type PermissionEvaluatorData struct {
+	Permissions  []*Permission
+	Roles        []*Role
+	ChannelTypes map[string]*ChannelType
+	App          *AppSettings
+}
+
+// PermissionEvaluator answers permission questions offline, for example to check
+// permission changes before applying them.
+type PermissionEvaluator struct {
+	permissions  map[string]*Permission
+	roles        map[string]*Role
+	channelTypes map[string]*ChannelType
+	appGrants    map[string][]string
+	policies     map[string][]Policy
+}
+
+// NewPermissionEvaluator returns an evaluator of the given configuration. Roles are optional:
+// if none are given, any role name is accepted.
+func NewPermissionEvaluator(data PermissionEvaluatorData) *PermissionEvaluator {
+	e := &PermissionEvaluator{
+		permissions:  make(map[string]*Permission, len(data.Permissions)),
+		channelTypes: data.ChannelTypes,
+	}
+	for _, p := range data.Permissions {
+		e.permissions[p.ID] = p
+	}
+	if len(data.Roles) > 0 {
+		e.roles = make(map[string]*Role, len(data.Roles))
+		for _, r := range data.Roles {
+			e.roles[r.Name] = r
+		}
+	}
+	if data.App != nil {
+		e.appGrants = data.App.Grants
+		e.policies = data.App.Policies
+	}
+	return e
+}
+
+// LoadPermissionEvaluator fetches the permissions, roles, channel types and app settings
+// of the app and returns an evaluator of them.
+func (p *PermissionClient) LoadPermissionEvaluator(ctx context.Context) (*PermissionEvaluator, error) {
+	perms, err := p.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := p.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	channelTypes, err := p.client.ListChannelTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	app, err := p.client.GetAppSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if app.App == nil {
+		return nil, errors.New("unexpected error: app settings response is nil")
+	}
+
+	return NewPermissionEvaluator(PermissionEvaluatorData{
+		Permissions:  perms.Permissions,
+		Roles:        roles.Roles,
+		ChannelTypes: channelTypes.ChannelTypes,
+		App:          app.App,
+	}), nil
+}
+
+// Can tells whether role may perform action, such as "DeleteMessage", on a resource in
+// channels of channelType. An empty channelType checks app level actions. Grants of the
+// channel type are checked first, then the app grants and finally the legacy policies.
+// Actions which are not granted are denied.
+func (e *PermissionEvaluator) Can(role, action string, channelType string, ownership ResourceOwnership) *PermissionDecision {
+	d := &PermissionDecision{Role: role, Action: action, ChannelType: channelType, Ownership: ownership, Source: PermissionSourceDefault}
+
+	if e.roles != nil && e.roles[role] == nil {
+		d.Reason = fmt.Sprintf("role %q doesn't exist", role)
+		return d
+	}
+
+	var ct *ChannelType
+	if channelType != "" {
+		ct = e.channelTypes[channelType]
+		if ct == nil && e.channelTypes != nil {
+			d.Reason = fmt.Sprintf("channel type %q doesn't exist", channelType)
+			return d
+		}
+	}
+
+	if ct != nil {
+		if e.decideByGrants(d, ct.Grants, PermissionSourceChannelTypeGrant) {
+			return d
+		}
+	}
+	if e.decideByGrants(d, e.appGrants, PermissionSourceAppGrant) {
+		return d
+	}
+	if channelType != "" && e.decideByPolicies(d, e.policies[channelType]) {
+		return d
+	}
+
+	d.Reason = "no grant or policy allows it"
+	return d
+}
+
+// decideByGrants allows the action if a permission granted to the role matches it.
+// Unconditional permissions are preferred.
+func (e *PermissionEvaluator) decideByGrants(d *PermissionDecision, grants map[string][]string, source PermissionSource) bool {
+	var conditional *Permission
+	for _, id := range grants[d.Role] {
+		perm := e.permissions[id]
+		if perm == nil || perm.Action != d.Action {
+			continue
+		}
+		if perm.Owner && d.Ownership != ResourceOwned {
+			continue
+		}
+		if len(perm.Condition) > 0 || perm.SameTeam {
+			if conditional == nil {
+				conditional = perm
+			}
+			continue
+		}
+		d.Allowed, d.Source, d.Permission = true, source, perm
+		d.Reason = fmt.Sprintf("permission %q is granted to the role by the %s", perm.ID, grantScope(source, d.ChannelType))
+		return true
+	}
+
+	if conditional != nil {
+		d.Allowed, d.Source, d.Permission, d.Conditional = true, source, conditional, true
+		d.Reason = fmt.Sprintf("permission %q is granted to the role by the %s, %s", conditional.ID, grantScope(source, d.ChannelType), permissionRestriction(conditional))
+		return true
+	}
+	return false
+}
+
+// permissionRestriction describes what a conditional permission depends on.
+func permissionRestriction(perm *Permission) string {
+	switch {
+	case len(perm.Condition) > 0 && perm.SameTeam:
+		return "if its condition holds and the resource is in the user's team"
+	case perm.SameTeam:
+		return "if the resource is in the user's team"
+	default:
+		return "if its condition holds"
+	}
+}
+
+func grantScope(source PermissionSource, channelType string) string {
+	if source == PermissionSourceChannelTypeGrant {
+		return fmt.Sprintf("grants of channel type %q", channelType)
+	}
+	return "app grants"
+}
+
+// decideByPolicies applies the first matching legacy policy by descending priority.
+func (e *PermissionEvaluator) decideByPolicies(d *PermissionDecision, policies []Policy) bool {
+	sorted := append([]Policy{}, policies...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority > sorted[j].Priority })
+
+	for i := range sorted {
+		p := &sorted[i]
+		if !matchesPolicyValue(p.Resources, d.Action) || !matchesPolicyValue(p.Roles, d.Role) {
+			continue
+		}
+		if p.Owner && d.Ownership != ResourceOwned {
+			continue
+		}
+
+		d.Allowed, d.Source, d.Policy = p.Action == 1, PermissionSourcePolicy, p
+		verb := "denies"
+		if d.Allowed {
+			verb = "allows"
+		}
+		d.Reason = fmt.Sprintf("policy %q with priority %d %s it", p.Name, p.Priority, verb)
+		return true
+	}
+	return false
+}
+
+func matchesPolicyValue(values []string, v string) bool {
+	for _, s := range values {
+		if s == "*" || strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package stream_chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPermissionEvaluator() *PermissionEvaluator {
+	return NewPermissionEvaluator(PermissionEvaluatorData{
+		Permissions: []*Permission{
+			{ID: "delete-message", Action: "DeleteMessage"},
+			{ID: "delete-message-owner", Action: "DeleteMessage", Owner: true},
+			{ID: "read-channel", Action: "ReadChannel"},
+			{ID: "pin-vip", Action: "PinMessage", Condition: map[string]interface{}{"$subject.vip": true}},
+			{ID: "ban-user", Action: "BanUser", Level: "app"},
+			{ID: "update-channel-team", Action: "UpdateChannel", SameTeam: true},
+		},
+		Roles: []*Role{{Name: "user"}, {Name: "channel_member"}, {Name: "channel_moderator"}, {Name: "admin"}},
+		ChannelTypes: map[string]*ChannelType{
+			"livestream": {Grants: map[string][]string{
+				"channel_member":    {"read-channel", "delete-message-owner", "pin-vip"},
+				"channel_moderator": {"read-channel", "delete-message", "update-channel-team"},
+			}},
+			"legacy": {},
+		},
+		App: &AppSettings{
+			Grants: map[string][]string{"admin": {"ban-user"}},
+			Policies: map[string][]Policy{"legacy": {
+				{Name: "deny all", Resources: []string{"*"}, Roles: []string{"*"}, Action: 0, Priority: 1},
+				{Name: "members read", Resources: []string{"ReadChannel"}, Roles: []string{"channel_member"}, Action: 1, Priority: 50},
+				{Name: "owners delete", Resources: []string{"DeleteMessage"}, Roles: []string{"channel_member"}, Action: 1, Owner: true, Priority: 60},
+			}},
+		},
+	})
+}
+
+func TestPermissionEvaluator_Can(t *testing.T) {
+	e := newTestPermissionEvaluator()
+
+	for _, tc := range []struct {
+		role, action, channelType string
+		ownership                 ResourceOwnership
+		allowed                   bool
+		source                    PermissionSource
+		reason                    string
+	}{
+		{"channel_member", "DeleteMessage", "livestream", ResourceOwned, true, PermissionSourceChannelTypeGrant, `permission "delete-message-owner" is granted`},
+		{"channel_member", "DeleteMessage", "livestream", ResourceNotOwned, false, PermissionSourceDefault, "no grant or policy allows it"},
+		{"channel_moderator", "DeleteMessage", "livestream", ResourceNotOwned, true, PermissionSourceChannelTypeGrant, `permission "delete-message" is granted`},
+		{"channel_member", "PinMessage", "livestream", ResourceOwned, true, PermissionSourceChannelTypeGrant, "if its condition holds"},
+		{"channel_moderator", "UpdateChannel", "livestream", ResourceNotOwned, true, PermissionSourceChannelTypeGrant, "if the resource is in the user's team"},
+		{"admin", "BanUser", "", ResourceNotOwned, true, PermissionSourceAppGrant, `permission "ban-user" is granted to the role by the app grants`},
+		{"channel_member", "ReadChannel", "legacy", ResourceNotOwned, true, PermissionSourcePolicy, `policy "members read" with priority 50 allows it`},
+		{"channel_member", "DeleteMessage", "legacy", ResourceNotOwned, false, PermissionSourcePolicy, `policy "deny all" with priority 1 denies it`},
+		{"channel_member", "DeleteMessage", "legacy", ResourceOwned, true, PermissionSourcePolicy, `policy "owners delete"`},
+		{"ghost", "ReadChannel", "livestream", ResourceOwned, false, PermissionSourceDefault, `role "ghost" doesn't exist`},
+		{"user", "ReadChannel", "missing", ResourceOwned, false, PermissionSourceDefault, `channel type "missing" doesn't exist`},
+	} {
+		d := e.Can(tc.role, tc.action, tc.channelType, tc.ownership)
+		require.Equal(t, tc.allowed, d.Allowed, d.String())
+		require.Equal(t, tc.source, d.Source, d.String())
+		require.Contains(t, d.Reason, tc.reason)
+	}
+
+	d := e.Can("channel_member", "PinMessage", "livestream", ResourceOwned)
+	require.True(t, d.Conditional)
+	require.Equal(t, "pin-vip", d.Permission.ID)
+	require.True(t, e.Can("channel_moderator", "UpdateChannel", "livestream", ResourceNotOwned).Conditional)
+	require.Equal(t, `DeleteMessage on another user's resource by role "channel_member" in channel type "livestream": denied, no grant or policy allows it`,
+		e.Can("channel_member", "DeleteMessage", "livestream", ResourceNotOwned).String())
+}
@@ -0,0 +1,443 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// builtinRoles are the roles every app has. They are never created or deleted by a PermissionSpec.
+var builtinRoles = []string{
+	"admin",
+	"anonymous",
+	"channel_member",
+	"channel_moderator",
+	"global_admin",
+	"global_moderator",
+	"guest",
+	"moderator",
+	"user",
+}
+
+func isBuiltinRole(name string) bool {
+	for _, r := range builtinRoles {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionSpec is the desired state of the custom roles, custom permissions and
+// per channel type grants of an app, usually kept in a YAML or JSON file.
+//
+//	roles: [support_agent]
+//	permissions:
+//	  - id: read-support-channels
+//	    name: Read support channels
+//	    action: ReadChannel
+//	grants:
+//	  support:
+//	    support_agent: [read-channel, read-support-channels]
+type PermissionSpec struct {
+	// Roles are the custom roles.
+	Roles []string `json:"roles"`
+	// Permissions are the custom permissions.
+	Permissions []*Permission `json:"permissions"`
+	// Grants are the permission IDs granted per channel type and role. Roles which are
+	// left out are not changed.
+	Grants map[string]map[string][]string `json:"grants"`
+	// Prune deletes the custom roles and custom permissions which are not in the spec.
+	// Built-in roles and permissions are never deleted. Planning fails if a pruned role or
+	// permission is still granted by a channel type, including types the spec leaves out.
+	Prune bool `json:"prune,omitempty"`
+}
+
+// ParsePermissionSpec parses a permission spec in JSON or YAML and validates it.
+func ParsePermissionSpec(data []byte) (*PermissionSpec, error) {
+	data, err := specToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec PermissionSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// LoadPermissionSpec reads a permission spec from a JSON or YAML file.
+func LoadPermissionSpec(filename string) (*PermissionSpec, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePermissionSpec(data)
+}
+
+// Validate checks the spec without calling the API.
+func (s *PermissionSpec) Validate() error {
+	roles := make(map[string]bool, len(s.Roles))
+	for i, name := range s.Roles {
+		switch {
+		case name == "":
+			return fmt.Errorf("role #%d: name must be not empty", i+1)
+		case roles[name]:
+			return fmt.Errorf("role %q: duplicated", name)
+		case isBuiltinRole(name):
+			return fmt.Errorf("role %q: built-in roles can't be declared", name)
+		}
+		roles[name] = true
+	}
+
+	perms := make(map[string]bool, len(s.Permissions))
+	for i, perm := range s.Permissions {
+		switch {
+		case perm == nil || perm.ID == "":
+			return fmt.Errorf("permission #%d: id must be not empty", i+1)
+		case perms[perm.ID]:
+			return fmt.Errorf("permission %q: duplicated", perm.ID)
+		case perm.Action == "":
+			return fmt.Errorf("permission %q: action must be not empty", perm.ID)
+		}
+		perms[perm.ID] = true
+	}
+
+	for channelType, grants := range s.Grants {
+		if channelType == "" {
+			return errors.New("grants: channel type must be not empty")
+		}
+		for role := range grants {
+			if role == "" {
+				return fmt.Errorf("grants of channel type %q: role must be not empty", channelType)
+			}
+		}
+	}
+	return nil
+}
+
+// PermissionResource is the kind of object changed by a PermissionChange.
+type PermissionResource string
+
+const (
+	PermissionResourceRole   PermissionResource = "role"
+	PermissionResourcePerm   PermissionResource = "permission"
+	PermissionResourceGrants PermissionResource = "grants of channel type"
+)
+
+// PermissionChange is a change of a single role, permission or channel type grants.
+type PermissionChange struct {
+	Action   ChangeAction       `json:"action"`
+	Resource PermissionResource `json:"resource"`
+	Name     string             `json:"name"`
+	Fields   []*FieldChange     `json:"fields,omitempty"`
+
+	permission *Permission
+	grants     map[string][]string
+}
+
+// PermissionPlan is the list of changes needed to reach a PermissionSpec, in the order
+// they are applied: roles and permissions are created before the grants referencing
+// them are updated, and deleted after.
+type PermissionPlan struct {
+	Changes []*PermissionChange `json:"changes"`
+}
+
+// Empty reports whether the app already matches the spec.
+func (p *PermissionPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String formats the plan for humans, one line per change and changed field.
+func (p *PermissionPlan) String() string {
+	if p.Empty() {
+		return "No changes. Roles and permissions match the spec.\n"
+	}
+
+	var sb strings.Builder
+	for _, c := range p.Changes {
+		fmt.Fprintf(&sb, "%s %s %s %q\n", changeSymbols[c.Action], c.Action, c.Resource, c.Name)
+		for _, f := range c.Fields {
+			if c.Action == ChangeCreate {
+				fmt.Fprintf(&sb, "    %s = %s\n", f.Field, formatFieldValue(f.To))
+				continue
+			}
+			fmt.Fprintf(&sb, "    %s\n", f)
+		}
+	}
+	return sb.String()
+}
+
+// PlanPermissions compares the spec with the roles, permissions and channel types of the
+// app and returns the changes needed to apply it. Nothing is changed, so it can be used
+// as a dry run.
+func (p *PermissionClient) PlanPermissions(ctx context.Context, spec *PermissionSpec) (*PermissionPlan, error) {
+	if spec == nil {
+		return nil, errors.New("permission spec is nil")
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	roles, err := p.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := p.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	channelTypes, err := p.client.ListChannelTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return planPermissions(spec, roles.Roles, perms.Permissions, channelTypes.ChannelTypes)
+}
+
+func planPermissions(spec *PermissionSpec, roles []*Role, perms []*Permission, channelTypes map[string]*ChannelType) (*PermissionPlan, error) {
+	plan := &PermissionPlan{}
+
+	liveRoles := make(map[string]*Role, len(roles))
+	for _, r := range roles {
+		liveRoles[r.Name] = r
+	}
+	livePerms := make(map[string]*Permission, len(perms))
+	for _, perm := range perms {
+		livePerms[perm.ID] = perm
+	}
+
+	wantRoles := make(map[string]bool, len(spec.Roles))
+	for _, name := range sortedStrings(spec.Roles) {
+		wantRoles[name] = true
+		if r, ok := liveRoles[name]; ok {
+			if !r.Custom {
+				return nil, fmt.Errorf("role %q: built-in roles can't be declared", name)
+			}
+			continue
+		}
+		plan.Changes = append(plan.Changes, &PermissionChange{Action: ChangeCreate, Resource: PermissionResourceRole, Name: name})
+	}
+
+	wantPerms := make(map[string]bool, len(spec.Permissions))
+	for _, perm := range sortedPermissions(spec.Permissions) {
+		wantPerms[perm.ID] = true
+		change, err := diffPermission(perm, livePerms[perm.ID])
+		if err != nil {
+			return nil, fmt.Errorf("permission %q: %w", perm.ID, err)
+		}
+		if change != nil {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	for _, channelType := range sortedKeys(spec.Grants) {
+		ct, ok := channelTypes[channelType]
+		if !ok || ct == nil {
+			return nil, fmt.Errorf("grants of channel type %q: channel type doesn't exist", channelType)
+		}
+
+		change := &PermissionChange{Action: ChangeUpdate, Resource: PermissionResourceGrants, Name: channelType, grants: make(map[string][]string)}
+		for _, role := range sortedKeys(spec.Grants[channelType]) {
+			if _, ok := liveRoles[role]; !ok && !wantRoles[role] && !isBuiltinRole(role) {
+				return nil, fmt.Errorf("grants of channel type %q: role %q doesn't exist", channelType, role)
+			}
+			want := spec.Grants[channelType][role]
+			for _, id := range want {
+				if _, ok := livePerms[id]; !ok && !wantPerms[id] {
+					return nil, fmt.Errorf("grants of channel type %q: permission %q doesn't exist", channelType, id)
+				}
+			}
+
+			have := ct.Grants[role]
+			if !sameStringSet(have, want) {
+				change.Fields = append(change.Fields, &FieldChange{Field: role, From: sortedStrings(have), To: sortedStrings(want)})
+				change.grants[role] = want
+			}
+		}
+		if len(change.Fields) > 0 {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	if spec.Prune {
+		granted := grantedAfterSpec(spec, channelTypes)
+		for _, perm := range sortedPermissions(perms) {
+			if perm.Custom && !wantPerms[perm.ID] {
+				if ref, ok := granted.permissions[perm.ID]; ok {
+					return nil, fmt.Errorf("permission %q: can't be pruned, still granted to role %q of channel type %q", perm.ID, ref.role, ref.channelType)
+				}
+				plan.Changes = append(plan.Changes, &PermissionChange{Action: ChangeDelete, Resource: PermissionResourcePerm, Name: perm.ID})
+			}
+		}
+
+		var names []string
+		for name, r := range liveRoles {
+			if r.Custom && !wantRoles[name] && !isBuiltinRole(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if ref, ok := granted.roles[name]; ok {
+				return nil, fmt.Errorf("role %q: can't be pruned, still granted permissions of channel type %q", name, ref.channelType)
+			}
+			plan.Changes = append(plan.Changes, &PermissionChange{Action: ChangeDelete, Resource: PermissionResourceRole, Name: name})
+		}
+	}
+	return plan, nil
+}
+
+// grantRef is a role of a channel type.
+type grantRef struct {
+	channelType, role string
+}
+
+// specGrants are the permissions and roles referenced by the grants of any channel type
+// once a spec is applied, with the first grant referencing them.
+type specGrants struct {
+	permissions map[string]grantRef
+	roles       map[string]grantRef
+}
+
+// grantedAfterSpec collects the grants of all channel types, including those the spec
+// doesn't mention, as they are after the spec is applied.
+func grantedAfterSpec(spec *PermissionSpec, channelTypes map[string]*ChannelType) specGrants {
+	g := specGrants{permissions: make(map[string]grantRef), roles: make(map[string]grantRef)}
+	for _, channelType := range sortedKeys(channelTypes) {
+		grants := make(map[string][]string)
+		if ct := channelTypes[channelType]; ct != nil {
+			for role, ids := range ct.Grants {
+				grants[role] = ids
+			}
+		}
+		for role, ids := range spec.Grants[channelType] {
+			grants[role] = ids
+		}
+
+		for _, role := range sortedKeys(grants) {
+			ids := grants[role]
+			if len(ids) == 0 {
+				continue
+			}
+			if _, ok := g.roles[role]; !ok {
+				g.roles[role] = grantRef{channelType, role}
+			}
+			for _, id := range ids {
+				if _, ok := g.permissions[id]; !ok {
+					g.permissions[id] = grantRef{channelType, role}
+				}
+			}
+		}
+	}
+	return g
+}
+
+// diffPermission returns the change needed for have to match want, or nil if it matches.
+func diffPermission(want, have *Permission) (*PermissionChange, error) {
+	wantFields, err := permissionFields(want)
+	if err != nil {
+		return nil, err
+	}
+
+	if have == nil {
+		change := &PermissionChange{Action: ChangeCreate, Resource: PermissionResourcePerm, Name: want.ID, permission: want}
+		for _, key := range sortedKeys(wantFields) {
+			if !isZeroJSONValue(wantFields[key]) {
+				change.Fields = append(change.Fields, &FieldChange{Field: key, To: wantFields[key]})
+			}
+		}
+		return change, nil
+	}
+
+	haveFields, err := permissionFields(have)
+	if err != nil {
+		return nil, err
+	}
+	change := &PermissionChange{Action: ChangeUpdate, Resource: PermissionResourcePerm, Name: want.ID, permission: want}
+	for _, key := range sortedKeys(wantFields) {
+		if !reflect.DeepEqual(wantFields[key], haveFields[key]) {
+			change.Fields = append(change.Fields, &FieldChange{Field: key, From: haveFields[key], To: wantFields[key]})
+		}
+	}
+	if len(change.Fields) == 0 {
+		return nil, nil
+	}
+	if !have.Custom {
+		return nil, errors.New("built-in permissions can't be updated")
+	}
+	return change, nil
+}
+
+// permissionFields returns the fields of perm which are compared, by JSON name.
+func permissionFields(perm *Permission) (map[string]interface{}, error) {
+	m, err := toJSONMap(perm)
+	if err != nil {
+		return nil, err
+	}
+	delete(m, "id")
+	delete(m, "custom")
+	if isZeroJSONValue(m["condition"]) {
+		m["condition"] = nil
+	}
+	return m, nil
+}
+
+func sortedPermissions(perms []*Permission) []*Permission {
+	out := append([]*Permission{}, perms...)
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// ApplyPermissionPlan applies the changes of a plan in order and stops at the first error.
+func (p *PermissionClient) ApplyPermissionPlan(ctx context.Context, plan *PermissionPlan) error {
+	if plan == nil {
+		return errors.New("permission plan is nil")
+	}
+
+	for _, change := range plan.Changes {
+		var err error
+		switch {
+		case change.Resource == PermissionResourceRole && change.Action == ChangeCreate:
+			_, err = p.CreateRole(ctx, change.Name)
+		case change.Resource == PermissionResourceRole && change.Action == ChangeDelete:
+			if isBuiltinRole(change.Name) {
+				err = errors.New("built-in roles can't be deleted")
+				break
+			}
+			_, err = p.DeleteRole(ctx, change.Name)
+		case change.Resource == PermissionResourcePerm && change.Action == ChangeCreate:
+			_, err = p.CreatePermission(ctx, change.permission)
+		case change.Resource == PermissionResourcePerm && change.Action == ChangeUpdate:
+			_, err = p.UpdatePermission(ctx, change.Name, change.permission)
+		case change.Resource == PermissionResourcePerm && change.Action == ChangeDelete:
+			_, err = p.DeletePermission(ctx, change.Name)
+		case change.Resource == PermissionResourceGrants && change.Action == ChangeUpdate:
+			_, err = p.client.UpdateChannelType(ctx, change.Name, map[string]interface{}{"grants": change.grants})
+		default:
+			err = fmt.Errorf("unknown action %q", change.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s %q: %w", change.Action, change.Resource, change.Name, err)
+		}
+	}
+	return nil
+}
+
+// ApplyPermissions plans the spec and applies the changes. The plan is returned
+// even if applying it failed.
+func (p *PermissionClient) ApplyPermissions(ctx context.Context, spec *PermissionSpec) (*PermissionPlan, error) {
+	plan, err := p.PlanPermissions(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	return plan, p.ApplyPermissionPlan(ctx, plan)
+}
@@ -0,0 +1,182 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const permissionSpecYAML = `
+prune: true
+roles: [support_agent]
+permissions:
+  - id: read-support
+    name: Read support channels
+    action: ReadChannel
+  - id: close-ticket
+    name: Close tickets
+    action: UpdateChannel
+    owner: true
+grants:
+  support:
+    support_agent: [read-channel, read-support]
+    channel_member: [read-channel]
+`
+
+type fakePermissionServer struct {
+	mu       sync.Mutex
+	t        *testing.T
+	requests []string
+	bodies   []map[string]interface{}
+}
+
+func (s *fakePermissionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		var body map[string]interface{}
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&body))
+		s.bodies = append(s.bodies, body)
+	}
+
+	var resp interface{} = map[string]interface{}{}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/roles":
+		resp = map[string]interface{}{"roles": []map[string]interface{}{
+			{"name": "admin", "custom": false},
+			{"name": "user", "custom": false},
+			{"name": "old_role", "custom": true},
+		}}
+	case r.Method == http.MethodGet && r.URL.Path == "/permissions":
+		resp = map[string]interface{}{"permissions": []map[string]interface{}{
+			{"id": "read-channel", "name": "Read Channel", "action": "ReadChannel", "custom": false},
+			{"id": "close-ticket", "name": "Close tickets", "action": "UpdateChannel", "custom": true},
+			{"id": "old-perm", "name": "Old", "action": "ReadChannel", "custom": true},
+		}}
+	case r.Method == http.MethodGet && r.URL.Path == "/channeltypes":
+		resp = map[string]interface{}{"channel_types": map[string]interface{}{
+			"support": map[string]interface{}{"name": "support", "grants": map[string][]string{"channel_member": {"read-channel"}}},
+		}}
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func TestParsePermissionSpec(t *testing.T) {
+	spec, err := ParsePermissionSpec([]byte(permissionSpecYAML))
+	require.NoError(t, err)
+	require.True(t, spec.Prune)
+	require.Equal(t, []string{"support_agent"}, spec.Roles)
+	require.Len(t, spec.Permissions, 2)
+	require.True(t, spec.Permissions[1].Owner)
+	require.Equal(t, []string{"read-channel", "read-support"}, spec.Grants["support"]["support_agent"])
+
+	for _, tc := range []struct {
+		spec string
+		err  string
+	}{
+		{`{"roles": [""]}`, "role #1: name must be not empty"},
+		{`{"roles": ["a", "a"]}`, `role "a": duplicated`},
+		{`{"roles": ["admin"]}`, `role "admin": built-in roles can't be declared`},
+		{`{"permissions": [{"name": "x"}]}`, "permission #1: id must be not empty"},
+		{`{"permissions": [{"id": "x"}]}`, `permission "x": action must be not empty`},
+		{`{"grants": {"messaging": {"": ["read-channel"]}}}`, `grants of channel type "messaging": role must be not empty`},
+	} {
+		_, err := ParsePermissionSpec([]byte(tc.spec))
+		require.Error(t, err, tc.spec)
+		require.Contains(t, err.Error(), tc.err)
+	}
+}
+
+func TestPermissionClient_PlanAndApplyPermissions(t *testing.T) {
+	ctx := context.Background()
+	spec, err := ParsePermissionSpec([]byte(permissionSpecYAML))
+	require.NoError(t, err)
+
+	srv := &fakePermissionServer{t: t}
+	p := newTestClient(t, srv).Permissions()
+
+	plan, err := p.PlanPermissions(ctx, spec)
+	require.NoError(t, err)
+	require.Equal(t, `+ create role "support_agent"
+~ update permission "close-ticket"
+    owner: false => true
++ create permission "read-support"
+    action = "ReadChannel"
+    name = "Read support channels"
+~ update grants of channel type "support"
+    support_agent: [] => ["read-channel","read-support"]
+- delete permission "old-perm"
+- delete role "old_role"
+`, plan.String())
+
+	require.NoError(t, p.ApplyPermissionPlan(ctx, plan))
+	require.Equal(t, []string{
+		"GET /roles",
+		"GET /permissions",
+		"GET /channeltypes",
+		"POST /roles",
+		"PUT /permissions/close-ticket",
+		"POST /permissions",
+		"PUT /channeltypes/support",
+		"DELETE /permissions/old-perm",
+		"DELETE /roles/old_role",
+	}, srv.requests)
+	require.Equal(t, map[string]interface{}{
+		"grants": map[string]interface{}{"support_agent": []interface{}{"read-channel", "read-support"}},
+	}, srv.bodies[3])
+
+	t.Run("unknown references", func(t *testing.T) {
+		for _, tc := range []struct {
+			spec string
+			err  string
+		}{
+			{`{"grants": {"missing": {"user": []}}}`, `grants of channel type "missing": channel type doesn't exist`},
+			{`{"grants": {"support": {"ghost": []}}}`, `role "ghost" doesn't exist`},
+			{`{"grants": {"support": {"user": ["fly"]}}}`, `permission "fly" doesn't exist`},
+			{`{"permissions": [{"id": "read-channel", "action": "DeleteChannel"}]}`, "built-in permissions can't be updated"},
+		} {
+			spec, err := ParsePermissionSpec([]byte(tc.spec))
+			require.NoError(t, err)
+			_, err = p.PlanPermissions(ctx, spec)
+			require.Error(t, err, tc.spec)
+			require.Contains(t, err.Error(), tc.err)
+		}
+	})
+
+	t.Run("pruning what other channel types still grant", func(t *testing.T) {
+		roles := []*Role{{Name: "old_role", Custom: true}}
+		perms := []*Permission{{ID: "read-channel"}, {ID: "old-perm", Custom: true}}
+
+		_, err := planPermissions(&PermissionSpec{Prune: true}, roles, perms, map[string]*ChannelType{
+			"livestream": {Grants: map[string][]string{"user": {"read-channel", "old-perm"}}},
+		})
+		require.EqualError(t, err, `permission "old-perm": can't be pruned, still granted to role "user" of channel type "livestream"`)
+
+		_, err = planPermissions(&PermissionSpec{Prune: true}, roles, perms[:1], map[string]*ChannelType{
+			"livestream": {Grants: map[string][]string{"old_role": {"read-channel"}}},
+		})
+		require.EqualError(t, err, `role "old_role": can't be pruned, still granted permissions of channel type "livestream"`)
+
+		// grants replaced by the spec no longer reference them
+		plan, err := planPermissions(&PermissionSpec{Prune: true, Grants: map[string]map[string][]string{
+			"livestream": {"user": {"read-channel"}, "old_role": {}},
+		}}, roles, perms, map[string]*ChannelType{
+			"livestream": {Grants: map[string][]string{"user": {"read-channel", "old-perm"}, "old_role": {"read-channel"}}},
+		})
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 3)
+	})
+
+	t.Run("built-in roles are protected", func(t *testing.T) {
+		plan := &PermissionPlan{Changes: []*PermissionChange{{Action: ChangeDelete, Resource: PermissionResourceRole, Name: "admin"}}}
+		err := p.ApplyPermissionPlan(ctx, plan)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "built-in roles can't be deleted")
+	})
+}
@@ -0,0 +1,485 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+// VotingVisibility designates whether the votes of a poll are public or anonymous.
+type VotingVisibility string
+
+const (
+	VotingVisibilityPublic    VotingVisibility = "public"
+	VotingVisibilityAnonymous VotingVisibility = "anonymous"
+)
+
+// PollOption is an option users can vote on.
+type PollOption struct {
+	ID   string `json:"id,omitempty"`
+	Text string `json:"text"`
+
+	ExtraData map[string]interface{} `json:"-"`
+}
+
+type pollOptionForJSON PollOption
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *PollOption) UnmarshalJSON(data []byte) error {
+	var o2 pollOptionForJSON
+	if err := json.Unmarshal(data, &o2); err != nil {
+		return err
+	}
+	*o = PollOption(o2)
+
+	if err := json.Unmarshal(data, &o.ExtraData); err != nil {
+		return err
+	}
+	removeFromMap(o.ExtraData, *o)
+	flattenExtraData(o.ExtraData)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o PollOption) MarshalJSON() ([]byte, error) {
+	return addToMapAndMarshal(o.ExtraData, pollOptionForJSON(o))
+}
+
+// PollVote is a vote cast on a poll option, or an answer to an open ended poll.
+type PollVote struct {
+	ID         string `json:"id"`
+	PollID     string `json:"poll_id"`
+	OptionID   string `json:"option_id,omitempty"`
+	IsAnswer   bool   `json:"is_answer,omitempty"`
+	AnswerText string `json:"answer_text,omitempty"`
+
+	UserID string `json:"user_id,omitempty"`
+	User   *User  `json:"user,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Poll is a poll which can be sent as part of a message.
+type Poll struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	VotingVisibility          VotingVisibility `json:"voting_visibility,omitempty"`
+	EnforceUniqueVote         bool             `json:"enforce_unique_vote"`
+	MaxVotesAllowed           *int             `json:"max_votes_allowed,omitempty"`
+	AllowUserSuggestedOptions bool             `json:"allow_user_suggested_options"`
+	AllowAnswers              bool             `json:"allow_answers"`
+	IsClosed                  bool             `json:"is_closed,omitempty"`
+
+	Options []*PollOption `json:"options"`
+
+	VoteCount           int                    `json:"vote_count,omitempty"`
+	VoteCountsByOption  map[string]int         `json:"vote_counts_by_option,omitempty"`
+	AnswersCount        int                    `json:"answers_count,omitempty"`
+	LatestVotesByOption map[string][]*PollVote `json:"latest_votes_by_option,omitempty"`
+	LatestAnswers       []*PollVote            `json:"latest_answers,omitempty"`
+	OwnVotes            []*PollVote            `json:"own_votes,omitempty"`
+
+	CreatedByID string    `json:"created_by_id,omitempty"`
+	CreatedBy   *User     `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	ExtraData map[string]interface{} `json:"-"`
+}
+
+type pollForJSON Poll
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *Poll) UnmarshalJSON(data []byte) error {
+	var p2 pollForJSON
+	if err := json.Unmarshal(data, &p2); err != nil {
+		return err
+	}
+	*p = Poll(p2)
+
+	if err := json.Unmarshal(data, &p.ExtraData); err != nil {
+		return err
+	}
+	removeFromMap(p.ExtraData, *p)
+	flattenExtraData(p.ExtraData)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p Poll) MarshalJSON() ([]byte, error) {
+	return addToMapAndMarshal(p.ExtraData, pollForJSON(p))
+}
+
+func (p *Poll) toRequest(userID string) pollRequest {
+	return pollRequest{
+		ID:                        p.ID,
+		Name:                      p.Name,
+		Description:               p.Description,
+		VotingVisibility:          p.VotingVisibility,
+		EnforceUniqueVote:         p.EnforceUniqueVote,
+		MaxVotesAllowed:           p.MaxVotesAllowed,
+		AllowUserSuggestedOptions: p.AllowUserSuggestedOptions,
+		AllowAnswers:              p.AllowAnswers,
+		IsClosed:                  p.IsClosed,
+		Options:                   p.Options,
+		UserID:                    userID,
+		ExtraData:                 p.ExtraData,
+	}
+}
+
+type pollRequest struct {
+	ID                        string                 `json:"id,omitempty"`
+	Name                      string                 `json:"name"`
+	Description               string                 `json:"description,omitempty"`
+	VotingVisibility          VotingVisibility       `json:"voting_visibility,omitempty"`
+	EnforceUniqueVote         bool                   `json:"enforce_unique_vote"`
+	MaxVotesAllowed           *int                   `json:"max_votes_allowed,omitempty"`
+	AllowUserSuggestedOptions bool                   `json:"allow_user_suggested_options"`
+	AllowAnswers              bool                   `json:"allow_answers"`
+	IsClosed                  bool                   `json:"is_closed"`
+	Options                   []*PollOption          `json:"options,omitempty"`
+	UserID                    string                 `json:"user_id,omitempty"`
+	ExtraData                 map[string]interface{} `json:"-"`
+}
+
+type pollRequestForJSON pollRequest
+
+func (r pollRequest) MarshalJSON() ([]byte, error) {
+	return addToMapAndMarshal(r.ExtraData, pollRequestForJSON(r))
+}
+
+type PollResponse struct {
+	Poll *Poll `json:"poll"`
+	Response
+}
+
+type PollOptionResponse struct {
+	PollOption *PollOption `json:"poll_option"`
+	Response
+}
+
+type PollVoteResponse struct {
+	Vote *PollVote `json:"vote"`
+	Poll *Poll     `json:"poll,omitempty"`
+	Response
+}
+
+func userIDParams(userID string) url.Values {
+	if userID == "" {
+		return nil
+	}
+	return url.Values{"user_id": {userID}}
+}
+
+// CreatePoll creates a poll on behalf of userID. The poll can then be sent by
+// the same user as part of a message, by setting Message.PollID.
+func (c *Client) CreatePoll(ctx context.Context, poll *Poll, userID string) (*PollResponse, error) {
+	switch {
+	case poll == nil:
+		return nil, errors.New("poll is nil")
+	case poll.Name == "":
+		return nil, errors.New("poll name must be not empty")
+	case poll.MaxVotesAllowed != nil && (*poll.MaxVotesAllowed < 1 || *poll.MaxVotesAllowed > 10):
+		return nil, errors.New("max votes allowed must be between 1 and 10")
+	}
+
+	var resp PollResponse
+	err := c.makeRequest(ctx, http.MethodPost, "polls", nil, poll.toRequest(userID), &resp)
+	return &resp, err
+}
+
+// GetPoll returns the poll with the given ID. When userID is set, the own
+// votes of that user are included.
+func (c *Client) GetPoll(ctx context.Context, pollID, userID string) (*PollResponse, error) {
+	if pollID == "" {
+		return nil, errors.New("poll ID must be not empty")
+	}
+
+	p := path.Join("polls", url.PathEscape(pollID))
+
+	var resp PollResponse
+	err := c.makeRequest(ctx, http.MethodGet, p, userIDParams(userID), nil, &resp)
+	return &resp, err
+}
+
+// UpdatePoll fully updates a poll. Properties omitted from the poll are
+// removed or set to their default values.
+func (c *Client) UpdatePoll(ctx context.Context, poll *Poll, userID string) (*PollResponse, error) {
+	switch {
+	case poll == nil:
+		return nil, errors.New("poll is nil")
+	case poll.ID == "":
+		return nil, errors.New("poll ID must be not empty")
+	case poll.Name == "":
+		return nil, errors.New("poll name must be not empty")
+	}
+
+	var resp PollResponse
+	err := c.makeRequest(ctx, http.MethodPut, "polls", nil, poll.toRequest(userID), &resp)
+	return &resp, err
+}
+
+// PartialUpdatePoll sets and unsets the given poll properties.
+func (c *Client) PartialUpdatePoll(ctx context.Context, pollID, userID string, update PartialUpdate) (*PollResponse, error) {
+	if pollID == "" {
+		return nil, errors.New("poll ID must be not empty")
+	}
+	if len(update.Set) == 0 && len(update.Unset) == 0 {
+		return nil, errors.New("set or unset must be not empty")
+	}
+
+	data := map[string]interface{}{}
+	if len(update.Set) > 0 {
+		data["set"] = update.Set
+	}
+	if len(update.Unset) > 0 {
+		data["unset"] = update.Unset
+	}
+	if userID != "" {
+		data["user_id"] = userID
+	}
+
+	p := path.Join("polls", url.PathEscape(pollID))
+
+	var resp PollResponse
+	err := c.makeRequest(ctx, http.MethodPatch, p, nil, data, &resp)
+	return &resp, err
+}
+
+// ClosePoll closes a poll, preventing any further votes.
+func (c *Client) ClosePoll(ctx context.Context, pollID, userID string) (*PollResponse, error) {
+	return c.PartialUpdatePoll(ctx, pollID, userID, PartialUpdate{Set: map[string]interface{}{"is_closed": true}})
+}
+
+// DeletePoll deletes a poll with its options and votes. It cannot be undone.
+func (c *Client) DeletePoll(ctx context.Context, pollID, userID string) (*Response, error) {
+	if pollID == "" {
+		return nil, errors.New("poll ID must be not empty")
+	}
+
+	p := path.Join("polls", url.PathEscape(pollID))
+
+	var resp Response
+	err := c.makeRequest(ctx, http.MethodDelete, p, userIDParams(userID), nil, &resp)
+	return &resp, err
+}
+
+type pollOptionRequest struct {
+	*PollOption
+	UserID string `json:"user_id,omitempty"`
+}
+
+func (r pollOptionRequest) MarshalJSON() ([]byte, error) {
+	data := copyMap(r.ExtraData)
+	if r.UserID != "" {
+		data["user_id"] = r.UserID
+	}
+	return addToMapAndMarshal(data, pollOptionForJSON(*r.PollOption))
+}
+
+// CreatePollOption adds an option to a poll.
+func (c *Client) CreatePollOption(ctx context.Context, pollID string, option *PollOption, userID string) (*PollOptionResponse, error) {
+	switch {
+	case pollID == "":
+		return nil, errors.New("poll ID must be not empty")
+	case option == nil:
+		return nil, errors.New("poll option is nil")
+	case option.Text == "":
+		return nil, errors.New("poll option text must be not empty")
+	}
+
+	p := path.Join("polls", url.PathEscape(pollID), "options")
+
+	var resp PollOptionResponse
+	err := c.makeRequest(ctx, http.MethodPost, p, nil, pollOptionRequest{PollOption: option, UserID: userID}, &resp)
+	return &resp, err
+}
+
+// UpdatePollOption fully updates a poll option.
+func (c *Client) UpdatePollOption(ctx context.Context, pollID string, option *PollOption, userID string) (*PollOptionResponse, error) {
+	switch {
+	case pollID == "":
+		return nil, errors.New("poll ID must be not empty")
+	case option == nil:
+		return nil, errors.New("poll option is nil")
+	case option.ID == "":
+		return nil, errors.New("poll option ID must be not empty")
+	case option.Text == "":
+		return nil, errors.New("poll option text must be not empty")
+	}
+
+	p := path.Join("polls", url.PathEscape(pollID), "options")
+
+	var resp PollOptionResponse
+	err := c.makeRequest(ctx, http.MethodPut, p, nil, pollOptionRequest{PollOption: option, UserID: userID}, &resp)
+	return &resp, err
+}
+
+// GetPollOption returns an option of a poll.
+func (c *Client) GetPollOption(ctx context.Context, pollID, optionID, userID string) (*PollOptionResponse, error) {
+	switch {
+	case pollID == "":
+		return nil, errors.New("poll ID must be not empty")
+	case optionID == "":
+		return nil, errors.New("poll option ID must be not empty")
+	}
+
+	p := path.Join("polls", url.PathEscape(pollID), "options", url.PathEscape(optionID))
+
+	var resp PollOptionResponse
+	err := c.makeRequest(ctx, http.MethodGet, p, userIDParams(userID), nil, &resp)
+	return &resp, err
+}
+
+// DeletePollOption deletes an option of a poll with its votes.
+func (c *Client) DeletePollOption(ctx context.Context, pollID, optionID, userID string) (*Response, error) {
+	switch {
+	case pollID == "":
+		return nil, errors.New("poll ID must be not empty")
+	case optionID == "":
+		return nil, errors.New("poll option ID must be not empty")
+	}
+
+	p := path.Join("polls", url.PathEscape(pollID), "options", url.PathEscape(optionID))
+
+	var resp Response
+	err := c.makeRequest(ctx, http.MethodDelete, p, userIDParams(userID), nil, &resp)
+	return &resp, err
+}
+
+func (c *Client) castVote(ctx context.Context, messageID, pollID, userID string, vote map[string]interface{}) (*PollVoteResponse, error) {
+	switch {
+	case messageID == "":
+		return nil, errors.New("message ID must be not empty")
+	case pollID == "":
+		return nil, errors.New("poll ID must be not empty")
+	case userID == "":
+		return nil, errors.New("user ID must be not empty")
+	}
+
+	p := path.Join("messages", url.PathEscape(messageID), "polls", url.PathEscape(pollID), "vote")
+	data := map[string]interface{}{"vote": vote, "user_id": userID}
+
+	var resp PollVoteResponse
+	err := c.makeRequest(ctx, http.MethodPost, p, nil, data, &resp)
+	return &resp, err
+}
+
+// CastVote casts a vote of userID on an option of the poll sent with the given message.
+// When the poll enforces unique votes, the vote replaces the previous one of the user.
+func (c *Client) CastVote(ctx context.Context, messageID, pollID, optionID, userID string) (*PollVoteResponse, error) {
+	if optionID == "" {
+		return nil, errors.New("poll option ID must be not empty")
+	}
+	return c.castVote(ctx, messageID, pollID, userID, map[string]interface{}{"option_id": optionID})
+}
+
+// CastPollAnswer adds an answer of userID to an open ended poll. It replaces
+// the previous answer of the user.
+func (c *Client) CastPollAnswer(ctx context.Context, messageID, pollID, answer, userID string) (*PollVoteResponse, error) {
+	if answer == "" {
+		return nil, errors.New("answer must be not empty")
+	}
+	return c.castVote(ctx, messageID, pollID, userID, map[string]interface{}{"answer_text": answer})
+}
+
+// RemoveVote removes a vote or an answer from the poll sent with the given message.
+func (c *Client) RemoveVote(ctx context.Context, messageID, pollID, voteID, userID string) (*PollVoteResponse, error) {
+	switch {
+	case messageID == "":
+		return nil, errors.New("message ID must be not empty")
+	case pollID == "":
+		return nil, errors.New("poll ID must be not empty")
+	case voteID == "":
+		return nil, errors.New("vote ID must be not empty")
+	case userID == "":
+		return nil, errors.New("user ID must be not empty")
+	}
+
+	p := path.Join("messages", url.PathEscape(messageID), "polls", url.PathEscape(pollID), "vote", url.PathEscape(voteID))
+
+	var resp PollVoteResponse
+	err := c.makeRequest(ctx, http.MethodDelete, p, userIDParams(userID), nil, &resp)
+	return &resp, err
+}
+
+// QueryPollsRequest represents the options for the QueryPolls request.
+type QueryPollsRequest struct {
+	// Filter on fields such as 'id', 'name', 'is_closed', 'created_by_id' or 'created_at'.
+	Filter map[string]any `json:"filter,omitempty"`
+	Sort   []*SortOption  `json:"sort,omitempty"`
+
+	// Limit the number of polls returned.
+	Limit int `json:"limit,omitempty"`
+	// Pagination parameter. Pass the 'next' value from a previous response to continue from that point.
+	Next string `json:"next,omitempty"`
+	// Pagination parameter. Pass the 'prev' value from a previous response to continue from that point.
+	Prev string `json:"prev,omitempty"`
+}
+
+type QueryPollsResponse struct {
+	Polls []*Poll `json:"polls"`
+	// Next is to be used as the 'next' parameter to get the next page.
+	Next *string `json:"next,omitempty"`
+	// Prev is to be used as the 'prev' parameter to get the previous page.
+	Prev *string `json:"prev,omitempty"`
+	Response
+}
+
+// QueryPolls returns the polls matching the request. When userID is set, the
+// own votes of that user are included.
+func (c *Client) QueryPolls(ctx context.Context, q *QueryPollsRequest, userID string) (*QueryPollsResponse, error) {
+	if q == nil {
+		q = &QueryPollsRequest{}
+	}
+
+	var resp QueryPollsResponse
+	err := c.makeRequest(ctx, http.MethodPost, "polls/query", userIDParams(userID), q, &resp)
+	return &resp, err
+}
+
+// QueryPollVotesRequest represents the options for the QueryPollVotes request.
+type QueryPollVotesRequest struct {
+	// Filter on fields such as 'id', 'user_id', 'option_id', 'is_answer' or 'created_at'.
+	Filter map[string]any `json:"filter,omitempty"`
+	Sort   []*SortOption  `json:"sort,omitempty"`
+
+	// Limit the number of votes returned.
+	Limit int `json:"limit,omitempty"`
+	// Pagination parameter. Pass the 'next' value from a previous response to continue from that point.
+	Next string `json:"next,omitempty"`
+	// Pagination parameter. Pass the 'prev' value from a previous response to continue from that point.
+	Prev string `json:"prev,omitempty"`
+}
+
+type QueryPollVotesResponse struct {
+	Votes []*PollVote `json:"votes"`
+	// Next is to be used as the 'next' parameter to get the next page.
+	Next *string `json:"next,omitempty"`
+	// Prev is to be used as the 'prev' parameter to get the previous page.
+	Prev *string `json:"prev,omitempty"`
+	Response
+}
+
+// QueryPollVotes returns the votes on a poll matching the request.
+func (c *Client) QueryPollVotes(ctx context.Context, pollID string, q *QueryPollVotesRequest, userID string) (*QueryPollVotesResponse, error) {
+	if pollID == "" {
+		return nil, errors.New("poll ID must be not empty")
+	}
+	if q == nil {
+		q = &QueryPollVotesRequest{}
+	}
+
+	p := path.Join("polls", url.PathEscape(pollID), "votes")
+
+	var resp QueryPollVotesResponse
+	err := c.makeRequest(ctx, http.MethodPost, p, userIDParams(userID), q, &resp)
+	return &resp, err
+}
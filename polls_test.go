@@ -0,0 +1,135 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Polls(t *testing.T) {
+	ctx := context.Background()
+
+	type request struct {
+		method, path, userID string
+		body                 map[string]interface{}
+	}
+	var requests []request
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.Path, userID: r.URL.Query().Get("user_id")}
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			require.NoError(t, json.Unmarshal(b, &req.body))
+		}
+		requests = append(requests, req)
+
+		switch r.URL.Path {
+		case "/polls", "/polls/poll-1":
+			_, _ = w.Write([]byte(`{"poll": {"id": "poll-1", "name": "Lunch?", "max_votes_allowed": null, "vote_count": 1,
+				"options": [{"id": "o1", "text": "Pizza", "emoji": "pizza"}],
+				"vote_counts_by_option": {"o1": 1},
+				"latest_votes_by_option": {"o1": [{"id": "v1", "poll_id": "poll-1", "option_id": "o1", "user_id": "bob"}]},
+				"created_by_id": "jane", "color": "red"}}`))
+		case "/messages/msg-1/polls/poll-1/vote", "/messages/msg-1/polls/poll-1/vote/v1":
+			_, _ = w.Write([]byte(`{"vote": {"id": "v1", "poll_id": "poll-1", "option_id": "o1", "user_id": "bob"}}`))
+		case "/polls/query":
+			_, _ = w.Write([]byte(`{"polls": [{"id": "poll-1", "name": "Lunch?"}], "next": "abc"}`))
+		case "/polls/poll-1/votes":
+			_, _ = w.Write([]byte(`{"votes": [{"id": "v2", "poll_id": "poll-1", "is_answer": true, "answer_text": "Sushi"}]}`))
+		case "/polls/poll-1/options", "/polls/poll-1/options/o2":
+			_, _ = w.Write([]byte(`{"poll_option": {"id": "o2", "text": "Tacos"}}`))
+		case "/channels/messaging/general/message":
+			_, _ = w.Write([]byte(`{"message": {"id": "msg-1", "poll_id": "poll-1", "poll": {"id": "poll-1", "name": "Lunch?"}}}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+
+	maxVotes := 2
+	resp, err := c.CreatePoll(ctx, &Poll{
+		Name:            "Lunch?",
+		MaxVotesAllowed: &maxVotes,
+		Options:         []*PollOption{{Text: "Pizza", ExtraData: map[string]interface{}{"emoji": "pizza"}}},
+		ExtraData:       map[string]interface{}{"color": "red"},
+	}, "jane")
+	require.NoError(t, err)
+	poll := resp.Poll
+	require.Equal(t, "poll-1", poll.ID)
+	require.Nil(t, poll.MaxVotesAllowed)
+	require.Equal(t, map[string]interface{}{"color": "red"}, poll.ExtraData)
+	require.Equal(t, "pizza", poll.Options[0].ExtraData["emoji"])
+	require.Equal(t, "bob", poll.LatestVotesByOption["o1"][0].UserID)
+	require.Equal(t, request{method: http.MethodPost, path: "/polls", body: map[string]interface{}{
+		"name": "Lunch?", "max_votes_allowed": float64(2), "enforce_unique_vote": false, "allow_user_suggested_options": false,
+		"allow_answers": false, "is_closed": false, "user_id": "jane", "color": "red",
+		"options": []interface{}{map[string]interface{}{"text": "Pizza", "emoji": "pizza"}},
+	}}, requests[0])
+
+	msg, err := c.Channel("messaging", "general").SendMessage(ctx, &Message{PollID: poll.ID}, "jane")
+	require.NoError(t, err)
+	require.Equal(t, "poll-1", requests[1].body["message"].(map[string]interface{})["poll_id"])
+	require.Equal(t, "Lunch?", msg.Message.Poll.Name)
+
+	vote, err := c.CastVote(ctx, "msg-1", "poll-1", "o1", "bob")
+	require.NoError(t, err)
+	require.Equal(t, "v1", vote.Vote.ID)
+	require.Equal(t, map[string]interface{}{"vote": map[string]interface{}{"option_id": "o1"}, "user_id": "bob"}, requests[2].body)
+
+	_, err = c.CastPollAnswer(ctx, "msg-1", "poll-1", "Sushi", "bob")
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"answer_text": "Sushi"}, requests[3].body["vote"])
+
+	_, err = c.RemoveVote(ctx, "msg-1", "poll-1", "v1", "bob")
+	require.NoError(t, err)
+	require.Equal(t, request{method: http.MethodDelete, path: "/messages/msg-1/polls/poll-1/vote/v1", userID: "bob"}, requests[4])
+
+	_, err = c.ClosePoll(ctx, "poll-1", "jane")
+	require.NoError(t, err)
+	require.Equal(t, request{method: http.MethodPatch, path: "/polls/poll-1", body: map[string]interface{}{
+		"set": map[string]interface{}{"is_closed": true}, "user_id": "jane",
+	}}, requests[5])
+
+	option, err := c.CreatePollOption(ctx, "poll-1", &PollOption{Text: "Tacos"}, "jane")
+	require.NoError(t, err)
+	require.Equal(t, "o2", option.PollOption.ID)
+	require.Equal(t, map[string]interface{}{"text": "Tacos", "user_id": "jane"}, requests[6].body)
+
+	_, err = c.DeletePollOption(ctx, "poll-1", "o2", "")
+	require.NoError(t, err)
+	require.Equal(t, request{method: http.MethodDelete, path: "/polls/poll-1/options/o2"}, requests[7])
+
+	polls, err := c.QueryPolls(ctx, &QueryPollsRequest{Filter: map[string]any{"is_closed": true}, Limit: 10}, "jane")
+	require.NoError(t, err)
+	require.Len(t, polls.Polls, 1)
+	require.Equal(t, "abc", *polls.Next)
+	require.Equal(t, "jane", requests[8].userID)
+
+	votes, err := c.QueryPollVotes(ctx, "poll-1", &QueryPollVotesRequest{Filter: map[string]any{"is_answer": true}}, "")
+	require.NoError(t, err)
+	require.Equal(t, "Sushi", votes.Votes[0].AnswerText)
+
+	_, err = c.DeletePoll(ctx, "poll-1", "jane")
+	require.NoError(t, err)
+	require.Equal(t, request{method: http.MethodDelete, path: "/polls/poll-1", userID: "jane"}, requests[10])
+
+	tooMany := 11
+	_, err = c.CreatePoll(ctx, &Poll{Name: "x", MaxVotesAllowed: &tooMany}, "jane")
+	require.Error(t, err)
+	_, err = c.UpdatePoll(ctx, &Poll{Name: "x"}, "jane")
+	require.Error(t, err)
+	_, err = c.CastVote(ctx, "msg-1", "poll-1", "", "bob")
+	require.Error(t, err)
+	require.Len(t, requests, 11)
+}
+
+func TestEvent_Poll(t *testing.T) {
+	var e Event
+	require.NoError(t, json.Unmarshal([]byte(`{"type": "poll.vote_casted", "poll": {"id": "p1", "vote_count": 1},
+		"poll_vote": {"id": "v1", "poll_id": "p1", "option_id": "o1"}}`), &e))
+	require.Equal(t, EventPollVoteCasted, e.Type)
+	require.Equal(t, 1, e.Poll.VoteCount)
+	require.Equal(t, "o1", e.PollVote.OptionID)
+	require.Empty(t, e.ExtraData)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
@@ -174,6 +175,66 @@ func (c *Client) QueryChannels(ctx context.Context, q *QueryOption, sort ...*Sor
 	}, nil
 }
 
+// queryChannelsInOrder calls fn with every channel matching q.Filter, oldest first. Offsets
+// are limited to 1000 by the API, so pages are read from the creation time of the last
+// channel instead.
+func (c *Client) queryChannelsInOrder(ctx context.Context, q QueryOption, fn func(*Channel) error) error {
+	var cursor time.Time
+	// seen are the channels created at cursor, which are returned again by the next page
+	seen := make(map[string]bool)
+	filter := q.Filter
+	for {
+		if !cursor.IsZero() {
+			q.Filter = filterCreatedFrom(filter, cursor)
+		}
+		resp, err := c.QueryChannels(ctx, &q, &SortOption{Field: "created_at", Direction: 1})
+		if err != nil {
+			return fmt.Errorf("query channels: %w", err)
+		}
+
+		advanced := false
+		for _, ch := range resp.Channels {
+			if seen[ch.cid()] {
+				continue
+			}
+			if !ch.CreatedAt.Equal(cursor) {
+				cursor = ch.CreatedAt
+				clear(seen)
+			}
+			seen[ch.cid()] = true
+			advanced = true
+			if err := fn(ch); err != nil {
+				return err
+			}
+		}
+		if len(resp.Channels) < q.Limit {
+			return nil
+		}
+		if !advanced {
+			return fmt.Errorf("more than %d channels were created at %s, use a larger page size",
+				q.Limit, cursor.UTC().Format(time.RFC3339Nano))
+		}
+	}
+}
+
+// filterCreatedFrom restricts filter to the channels created at or after from.
+func filterCreatedFrom(filter map[string]interface{}, from time.Time) map[string]interface{} {
+	gte := from.UTC().Format(time.RFC3339Nano)
+	ops, isMap := filter["created_at"].(map[string]interface{})
+	if _, hasGte := ops["$gte"]; filter["created_at"] != nil && (!isMap || hasGte) {
+		return map[string]interface{}{"$and": []interface{}{
+			filter,
+			map[string]interface{}{"created_at": map[string]interface{}{"$gte": gte}},
+		}}
+	}
+
+	out := copyMap(filter)
+	created := copyMap(ops)
+	created["$gte"] = gte
+	out["created_at"] = created
+	return out
+}
+
 type SearchRequest struct {
 	// Required
 	Query          string                 `json:"query"`
@@ -0,0 +1,383 @@
+package stream_chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	defaultRetentionChannelPageSize = 30
+	defaultRetentionMessagePageSize = 100
+	defaultRetentionMaxRetries      = 5
+	maxRetentionRateLimitWait       = time.Minute
+)
+
+// RetentionPolicy removes the messages older than MaxAge from the channels it matches.
+// Empty filters match every channel.
+type RetentionPolicy struct {
+	// Name identifies the policy in the audit log.
+	Name   string
+	MaxAge time.Duration
+
+	ChannelTypes []string
+	Teams        []string
+	// ChannelData matches the channels whose custom data has these values.
+	ChannelData map[string]interface{}
+	// ChannelMatch is an optional predicate on the channel.
+	ChannelMatch func(ch *Channel) bool
+	// MessageMatch is an optional predicate restricting the expired messages which are removed.
+	MessageMatch func(m *Message) bool
+
+	// HardDelete removes the messages permanently instead of soft deleting them.
+	HardDelete bool
+}
+
+func (p *RetentionPolicy) matches(ch *Channel) bool {
+	if len(p.ChannelTypes) > 0 && !slices.Contains(p.ChannelTypes, ch.Type) {
+		return false
+	}
+	if len(p.Teams) > 0 && !slices.Contains(p.Teams, ch.Team) {
+		return false
+	}
+	for k, want := range p.ChannelData {
+		if !jsonEqual(ch.ExtraData[k], want) {
+			return false
+		}
+	}
+	return p.ChannelMatch == nil || p.ChannelMatch(ch)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// RetentionSweepOptions configures a RetentionSweeper.
+type RetentionSweepOptions struct {
+	// AuditLog receives a JSON line for every removed message and every failure.
+	AuditLog io.Writer
+	// DryRun finds the expired messages without removing them.
+	DryRun bool
+	// Now is the time the message ages are computed from. Defaults to the current time.
+	Now time.Time
+	// ChannelPageSize and MessagePageSize are the number of channels and messages read per
+	// request. They default to 30 and 100.
+	ChannelPageSize int
+	MessagePageSize int
+	// MaxRetries is the number of times a rate limited deletion is retried. Defaults to 5.
+	MaxRetries int
+}
+
+// RetentionAuditAction is the outcome recorded in a RetentionAuditEntry.
+type RetentionAuditAction string
+
+const (
+	RetentionDeleted     RetentionAuditAction = "deleted"
+	RetentionWouldDelete RetentionAuditAction = "would_delete"
+	RetentionFailed      RetentionAuditAction = "failed"
+)
+
+// RetentionAuditEntry is a line of the audit log of a RetentionSweeper.
+type RetentionAuditEntry struct {
+	Time      time.Time            `json:"time"`
+	Action    RetentionAuditAction `json:"action"`
+	Policy    string               `json:"policy"`
+	CID       string               `json:"cid"`
+	MessageID string               `json:"message_id"`
+	ParentID  string               `json:"parent_id,omitempty"`
+	UserID    string               `json:"user_id,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+	Hard      bool                 `json:"hard"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// RetentionReport is the result of RetentionSweeper.Sweep.
+type RetentionReport struct {
+	// Channels is the number of channels governed by a policy.
+	Channels int `json:"channels"`
+	// Deleted is the number of messages removed, or that would be removed in a dry run.
+	Deleted int `json:"deleted"`
+	Failed  int `json:"failed"`
+	// ByPolicy is the number of messages removed per policy.
+	ByPolicy map[string]int `json:"by_policy"`
+	DryRun   bool           `json:"dry_run"`
+}
+
+// RetentionSweeper enforces message retention policies client-side, for plans or rules
+// that ChannelConfig.MessageRetention doesn't cover.
+type RetentionSweeper struct {
+	client   *Client
+	policies []*RetentionPolicy
+	opts     RetentionSweepOptions
+	audit    *json.Encoder
+	// quotaReset is when the rate limit window resets after a delete used up the quota.
+	quotaReset time.Time
+}
+
+// NewRetentionSweeper returns a sweeper applying policies. A channel is governed by the
+// first policy matching it.
+func NewRetentionSweeper(c *Client, policies []*RetentionPolicy, opts *RetentionSweepOptions) (*RetentionSweeper, error) {
+	if len(policies) == 0 {
+		return nil, errors.New("policies must be not empty")
+	}
+	names := make(map[string]bool, len(policies))
+	for i, p := range policies {
+		switch {
+		case p == nil || p.Name == "":
+			return nil, fmt.Errorf("policy #%d: name must be not empty", i+1)
+		case names[p.Name]:
+			return nil, fmt.Errorf("policy %q: duplicated", p.Name)
+		case p.MaxAge <= 0:
+			return nil, fmt.Errorf("policy %q: max age must be positive", p.Name)
+		}
+		names[p.Name] = true
+	}
+
+	s := &RetentionSweeper{client: c, policies: policies}
+	if opts != nil {
+		s.opts = *opts
+	}
+	if s.opts.ChannelPageSize <= 0 {
+		s.opts.ChannelPageSize = defaultRetentionChannelPageSize
+	}
+	if s.opts.MessagePageSize <= 0 {
+		s.opts.MessagePageSize = defaultRetentionMessagePageSize
+	}
+	if s.opts.MaxRetries <= 0 {
+		s.opts.MaxRetries = defaultRetentionMaxRetries
+	}
+	if s.opts.AuditLog != nil {
+		s.audit = json.NewEncoder(s.opts.AuditLog)
+	}
+	return s, nil
+}
+
+// Sweep removes the expired messages of every channel governed by a policy. Expired
+// messages, including thread replies, are found with Search; channels which can't be
+// searched are read through their message history instead. Deletions back off when the
+// rate limit is reached. Failed deletions are recorded and the sweep goes on; errors
+// reading channels or messages stop it.
+func (s *RetentionSweeper) Sweep(ctx context.Context) (*RetentionReport, error) {
+	now := s.opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	report := &RetentionReport{ByPolicy: make(map[string]int), DryRun: s.opts.DryRun}
+
+	for i, p := range s.policies {
+		cutoff := now.Add(-p.MaxAge)
+		filter := map[string]interface{}{"created_at": map[string]interface{}{"$lt": cutoff.UTC().Format(time.RFC3339Nano)}}
+		if len(p.ChannelTypes) > 0 {
+			filter["type"] = map[string]interface{}{"$in": p.ChannelTypes}
+		}
+		if len(p.Teams) > 0 {
+			filter["team"] = map[string]interface{}{"$in": p.Teams}
+		}
+
+		q := QueryOption{Filter: filter, Limit: s.opts.ChannelPageSize}
+		err := s.client.queryChannelsInOrder(ctx, q, func(ch *Channel) error {
+			if !p.matches(ch) || governedBy(s.policies[:i], ch) {
+				return nil
+			}
+			report.Channels++
+			if err := s.sweepChannel(ctx, p, ch, cutoff, report); err != nil {
+				return fmt.Errorf("channel %s: %w", ch.cid(), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return report, fmt.Errorf("policy %q: %w", p.Name, err)
+		}
+	}
+	return report, nil
+}
+
+func governedBy(policies []*RetentionPolicy, ch *Channel) bool {
+	for _, p := range policies {
+		if p.matches(ch) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *RetentionSweeper) sweepChannel(ctx context.Context, p *RetentionPolicy, ch *Channel, cutoff time.Time, report *RetentionReport) error {
+	remove := func(m *Message) error {
+		if !messageTime(m).Before(cutoff) || m.DeletedAt != nil && !p.HardDelete {
+			return nil
+		}
+		if p.MessageMatch != nil && !p.MessageMatch(m) {
+			return nil
+		}
+		return s.remove(ctx, p, ch, m, report)
+	}
+
+	err := s.searchExpired(ctx, ch, cutoff, remove)
+	var apiErr Error
+	if errors.As(err, &apiErr) && !isTransientError(err) {
+		// search is disabled for the channel type, read the history instead
+		return s.historyExpired(ctx, ch, cutoff, remove)
+	}
+	return err
+}
+
+func (s *RetentionSweeper) searchExpired(ctx context.Context, ch *Channel, cutoff time.Time, fn func(*Message) error) error {
+	req := SearchRequest{
+		Filters:        map[string]interface{}{"cid": ch.cid()},
+		MessageFilters: map[string]interface{}{"created_at": map[string]interface{}{"$lt": cutoff.UTC().Format(time.RFC3339Nano)}},
+		Limit:          s.opts.MessagePageSize,
+		Sort:           []SortOption{{Field: "created_at", Direction: 1}},
+	}
+	for {
+		resp, err := s.client.SearchWithFullResponse(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, r := range resp.Results {
+			if r.Message == nil {
+				continue
+			}
+			if err := fn(r.Message); err != nil {
+				return err
+			}
+		}
+		if resp.Next == "" {
+			return nil
+		}
+		req.Next = resp.Next
+	}
+}
+
+func (s *RetentionSweeper) historyExpired(ctx context.Context, ch *Channel, cutoff time.Time, fn func(*Message) error) error {
+	pageSize := s.opts.MessagePageSize
+	var cursor time.Time
+	for {
+		resp, err := ch.Query(ctx, &QueryRequest{State: true, Messages: messagesFrom(cursor, pageSize)})
+		if err != nil {
+			return fmt.Errorf("query messages: %w", err)
+		}
+
+		advanced := false
+		for _, m := range resp.Messages {
+			if !messageTime(m).Before(cutoff) {
+				return nil
+			}
+			if messageTime(m).After(cursor) {
+				advanced = true
+				cursor = messageTime(m)
+			}
+			if m.ParentID != "" {
+				continue
+			}
+			// replies are removed before their parent
+			if m.ReplyCount > 0 {
+				if err := eachReply(ctx, ch, m.ID, s.opts.MessagePageSize, fn); err != nil {
+					return err
+				}
+			}
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		if len(resp.Messages) < pageSize {
+			return nil
+		}
+		if !advanced {
+			return errMessagePageNotAdvanced(cursor, pageSize)
+		}
+	}
+}
+
+func (s *RetentionSweeper) remove(ctx context.Context, p *RetentionPolicy, ch *Channel, m *Message, report *RetentionReport) error {
+	entry := &RetentionAuditEntry{
+		Action:    RetentionDeleted,
+		Policy:    p.Name,
+		CID:       ch.cid(),
+		MessageID: m.ID,
+		ParentID:  m.ParentID,
+		UserID:    m.UserID,
+		CreatedAt: messageTime(m),
+		Hard:      p.HardDelete,
+	}
+	if m.User != nil {
+		entry.UserID = m.User.ID
+	}
+
+	var err error
+	if s.opts.DryRun {
+		entry.Action = RetentionWouldDelete
+	} else {
+		err = s.deleteMessage(ctx, m.ID, p.HardDelete)
+	}
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	entry.Time = time.Now()
+	if err != nil {
+		entry.Action = RetentionFailed
+		entry.Error = err.Error()
+		report.Failed++
+	} else {
+		report.Deleted++
+		report.ByPolicy[p.Name]++
+	}
+
+	if s.audit != nil {
+		if err := s.audit.Encode(entry); err != nil {
+			return fmt.Errorf("write audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteMessage deletes the message, waiting for the rate limit window to reset when the
+// quota is used up. After a delete which used up the quota, the wait happens before the
+// next delete, so the deleted message is recorded even if ctx is canceled meanwhile.
+func (s *RetentionSweeper) deleteMessage(ctx context.Context, id string, hard bool) error {
+	var options []DeleteMessageOption
+	if hard {
+		options = append(options, DeleteMessageWithHard())
+	}
+
+	if wait := time.Until(s.quotaReset); wait > 0 {
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+	s.quotaReset = time.Time{}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := s.client.DeleteMessageWithOptions(ctx, id, options...)
+		if err == nil {
+			if rl := resp.RateLimitInfo; rl != nil && rl.Limit > 0 && rl.Remaining == 0 {
+				s.quotaReset = time.Now().Add(rateLimitWait(rl, attempt))
+			}
+			return nil
+		}
+
+		var apiErr Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || attempt >= s.opts.MaxRetries {
+			return err
+		}
+		if err := sleepContext(ctx, rateLimitWait(apiErr.RateLimit, attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// rateLimitWait returns the time until the rate limit window resets, or an exponential
+// backoff when the reset time is unknown.
+func rateLimitWait(rl *RateLimitInfo, attempt int) time.Duration {
+	if rl != nil && rl.Reset > 0 {
+		return min(max(time.Until(rl.ResetTime()), 0), maxRetentionRateLimitWait)
+	}
+	return min(time.Second<<attempt, maxRetentionRateLimitWait)
+}
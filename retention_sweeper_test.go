@@ -0,0 +1,252 @@
+package stream_chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionSweeper_Sweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var deletes []string
+	rateLimited := false
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/channels":
+			var q map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			channels := []map[string]interface{}{
+				{"channel": map[string]interface{}{"type": "messaging", "id": "gold", "cid": "messaging:gold", "tier": "gold", "created_at": "2023-01-01T00:00:00Z"}},
+				{"channel": map[string]interface{}{"type": "messaging", "id": "basic", "cid": "messaging:basic", "tier": "basic", "created_at": "2023-01-02T00:00:00Z"}},
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"channels": pageChannels(t, q, channels)})
+		case r.URL.Path == "/search":
+			var q SearchRequest
+			require.NoError(t, json.Unmarshal([]byte(r.URL.Query().Get("payload")), &q))
+			if q.Filters["cid"] == "messaging:basic" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"code": 4, "message": "search is disabled", "StatusCode": 400}`))
+				return
+			}
+			require.Equal(t, "2024-02-29T00:00:00Z", q.MessageFilters["created_at"].(map[string]interface{})["$lt"])
+			results := []map[string]interface{}{
+				{"message": map[string]interface{}{"id": "g1", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-02-01T00:00:00Z"}},
+				{"message": map[string]interface{}{"id": "g2", "user": map[string]interface{}{"id": "bob"}, "created_at": "2024-02-02T00:00:00Z", "keep": true}},
+			}
+			next := "page2"
+			if q.Next == "page2" {
+				results = []map[string]interface{}{
+					{"message": map[string]interface{}{"id": "g3", "parent_id": "g1", "user": map[string]interface{}{"id": "bob"}, "created_at": "2024-02-03T00:00:00Z"}},
+				}
+				next = ""
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results, "next": next})
+		case r.URL.Path == "/channels/messaging/basic/query":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"channel": map[string]interface{}{"type": "messaging", "id": "basic", "cid": "messaging:basic"},
+				"messages": []map[string]interface{}{
+					{"id": "b1", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-01T00:00:00Z", "reply_count": 1},
+					{"id": "b2", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-01-02T00:00:00Z", "deleted_at": "2024-01-03T00:00:00Z"},
+					{"id": "b3", "user": map[string]interface{}{"id": "jane"}, "created_at": "2024-02-28T00:00:00Z"},
+				},
+			})
+		case r.URL.Path == "/messages/b1/replies":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageReplies(r.URL.Query(), []map[string]interface{}{
+				{"id": "b1r", "parent_id": "b1", "user": map[string]interface{}{"id": "bob"}, "created_at": "2024-01-01T01:00:00Z"},
+			})})
+		case r.Method == http.MethodDelete:
+			id := strings.TrimPrefix(r.URL.Path, "/messages/")
+			if id == "g1" && !rateLimited {
+				rateLimited = true
+				w.Header().Set(HeaderRateReset, strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10))
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"code": 9, "message": "too many requests", "StatusCode": 429}`))
+				return
+			}
+			if id == "b1r" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"code": 17, "message": "not allowed", "StatusCode": 403}`))
+				return
+			}
+			deletes = append(deletes, id+"?hard="+r.URL.Query().Get("hard"))
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+
+	policies := []*RetentionPolicy{
+		{
+			Name: "gold", MaxAge: 24 * time.Hour, HardDelete: true,
+			ChannelData:  map[string]interface{}{"tier": "gold"},
+			MessageMatch: func(m *Message) bool { return m.ExtraData["keep"] != true },
+		},
+		{Name: "messaging", MaxAge: 30 * 24 * time.Hour, ChannelTypes: []string{"messaging"}},
+	}
+
+	var audit bytes.Buffer
+	s, err := NewRetentionSweeper(c, policies, &RetentionSweepOptions{Now: now, AuditLog: &audit, DryRun: true})
+	require.NoError(t, err)
+	report, err := s.Sweep(ctx)
+	require.NoError(t, err)
+	require.Empty(t, deletes)
+	require.Equal(t, &RetentionReport{Channels: 2, Deleted: 4, ByPolicy: map[string]int{"gold": 2, "messaging": 2}, DryRun: true}, report)
+
+	audit.Reset()
+	s, err = NewRetentionSweeper(c, policies, &RetentionSweepOptions{Now: now, AuditLog: &audit})
+	require.NoError(t, err)
+	report, err = s.Sweep(ctx)
+	require.NoError(t, err)
+	require.True(t, rateLimited)
+	require.Equal(t, []string{"g1?hard=true", "g3?hard=true", "b1?hard="}, deletes)
+	require.Equal(t, 3, report.Deleted)
+	require.Equal(t, 1, report.Failed)
+
+	var entries []RetentionAuditEntry
+	dec := json.NewDecoder(&audit)
+	for dec.More() {
+		var e RetentionAuditEntry
+		require.NoError(t, dec.Decode(&e))
+		entries = append(entries, e)
+	}
+	require.Len(t, entries, 4)
+	require.Equal(t, RetentionAuditEntry{
+		Time: entries[0].Time, Action: RetentionDeleted, Policy: "gold", CID: "messaging:gold", MessageID: "g1",
+		UserID: "jane", CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Hard: true,
+	}, entries[0])
+	require.Equal(t, RetentionFailed, entries[2].Action)
+	require.Equal(t, "b1r", entries[2].MessageID)
+	require.Equal(t, "not allowed", entries[2].Error)
+
+	_, err = NewRetentionSweeper(c, []*RetentionPolicy{{Name: "a"}}, nil)
+	require.Error(t, err)
+}
+
+func TestRetentionSweeper_SameCreationTime(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q map[string]interface{}
+		switch r.URL.Path {
+		case "/channels":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"channels": pageChannels(t, q, []map[string]interface{}{
+				{"channel": map[string]interface{}{"type": "messaging", "id": "basic", "cid": "messaging:basic", "created_at": "2023-01-01T00:00:00Z"}},
+			})})
+		case "/search":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code": 4, "message": "search is disabled", "StatusCode": 400}`))
+		case "/channels/messaging/basic/query":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageMessages(q, []map[string]interface{}{
+				{"id": "b1", "created_at": "2024-01-01T00:00:00Z"},
+				{"id": "b2", "created_at": "2024-01-01T00:00:00Z"},
+				{"id": "b3", "created_at": "2024-01-01T00:00:00Z"},
+			})})
+		case "/messages/b1", "/messages/b2":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+
+	s, err := NewRetentionSweeper(c, []*RetentionPolicy{{Name: "all", MaxAge: time.Hour}}, &RetentionSweepOptions{MessagePageSize: 2})
+	require.NoError(t, err)
+	_, err = s.Sweep(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than 2 messages were created at 2024-01-01T00:00:00Z")
+}
+
+func TestRetentionSweeper_ManyReplies(t *testing.T) {
+	var deletes []string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q map[string]interface{}
+		switch {
+		case r.URL.Path == "/channels":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"channels": pageChannels(t, q, []map[string]interface{}{
+				{"channel": map[string]interface{}{"type": "messaging", "id": "basic", "cid": "messaging:basic", "created_at": "2023-01-01T00:00:00Z"}},
+			})})
+		case r.URL.Path == "/search":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code": 4, "message": "search is disabled", "StatusCode": 400}`))
+		case r.URL.Path == "/channels/messaging/basic/query":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageMessages(q, []map[string]interface{}{
+				{"id": "b1", "created_at": "2024-01-01T00:00:00Z", "reply_count": 3},
+			})})
+		case r.URL.Path == "/messages/b1/replies":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageReplies(r.URL.Query(), []map[string]interface{}{
+				{"id": "r1", "parent_id": "b1", "created_at": "2024-01-01T01:00:00Z"},
+				{"id": "r2", "parent_id": "b1", "created_at": "2024-01-01T02:00:00Z"},
+				{"id": "r3", "parent_id": "b1", "created_at": "2024-01-01T03:00:00Z"},
+			})})
+		case r.Method == http.MethodDelete:
+			deletes = append(deletes, strings.TrimPrefix(r.URL.Path, "/messages/"))
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+
+	s, err := NewRetentionSweeper(c, []*RetentionPolicy{{Name: "all", MaxAge: time.Hour}}, &RetentionSweepOptions{MessagePageSize: 2})
+	require.NoError(t, err)
+	_, err = s.Sweep(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"r1", "r2", "r3", "b1"}, deletes)
+}
+
+func TestRetentionSweeper_QuotaUsedUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var deletes []string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q map[string]interface{}
+		switch {
+		case r.URL.Path == "/channels":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"channels": pageChannels(t, q, []map[string]interface{}{
+				{"channel": map[string]interface{}{"type": "messaging", "id": "basic", "cid": "messaging:basic", "created_at": "2023-01-01T00:00:00Z"}},
+			})})
+		case r.URL.Path == "/search":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code": 4, "message": "search is disabled", "StatusCode": 400}`))
+		case r.URL.Path == "/channels/messaging/basic/query":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageMessages(q, []map[string]interface{}{
+				{"id": "b1", "created_at": "2024-01-01T00:00:00Z"},
+				{"id": "b2", "created_at": "2024-01-02T00:00:00Z"},
+			})})
+		case r.Method == http.MethodDelete:
+			deletes = append(deletes, strings.TrimPrefix(r.URL.Path, "/messages/"))
+			// the quota is used up and the sweep is stopped while waiting for the reset
+			w.Header().Set(HeaderRateLimit, "10")
+			w.Header().Set(HeaderRateRemaining, "0")
+			w.Header().Set(HeaderRateReset, strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+			_, _ = w.Write([]byte(`{}`))
+			time.AfterFunc(50*time.Millisecond, cancel)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+
+	var audit bytes.Buffer
+	s, err := NewRetentionSweeper(c, []*RetentionPolicy{{Name: "all", MaxAge: time.Hour}}, &RetentionSweepOptions{AuditLog: &audit})
+	require.NoError(t, err)
+	_, err = s.Sweep(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, []string{"b1"}, deletes)
+
+	var entry RetentionAuditEntry
+	require.NoError(t, json.Unmarshal(audit.Bytes(), &entry))
+	require.Equal(t, RetentionDeleted, entry.Action)
+	require.Equal(t, "b1", entry.MessageID)
+}
@@ -0,0 +1,629 @@
+package stream_chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SlackImportOptions configures ConvertSlackExport.
+type SlackImportOptions struct {
+	// ChannelType is the Stream channel type of public and private Slack channels. Defaults to "messaging".
+	ChannelType string
+	// DirectChannelType is the Stream channel type of direct and group messages. Defaults to ChannelType.
+	DirectChannelType string
+	// BotUserID, if set, is the user bot messages without a Slack user are attributed to.
+	// The user is added to the import when it is not part of the export.
+	BotUserID string
+	// AdminRole, if set, is the Stream role given to Slack workspace admins and owners,
+	// for example "admin". By default everyone is imported with the "user" role.
+	AdminRole string
+}
+
+// SlackUnmappedItem is an item of a Slack export that could not be converted.
+type SlackUnmappedItem struct {
+	// Kind is the kind of item: user, channel, member, message, reaction or file.
+	Kind    string `json:"kind"`
+	Channel string `json:"channel,omitempty"`
+	ID      string `json:"id"`
+	Reason  string `json:"reason"`
+}
+
+// SlackFileReference is a file shared in Slack. Imported messages keep the Slack URL of the
+// file as attachment, which requires Slack authentication, so files usually need to be re-hosted.
+type SlackFileReference struct {
+	MessageID string `json:"message_id"`
+	FileID    string `json:"file_id"`
+	Name      string `json:"name"`
+	MimeType  string `json:"mime_type,omitempty"`
+	URL       string `json:"url"`
+}
+
+// SlackImportReport is the result of ConvertSlackExport.
+type SlackImportReport struct {
+	Counts   map[ImportItemType]int `json:"counts"`
+	Unmapped []SlackUnmappedItem    `json:"unmapped"`
+	Files    []SlackFileReference   `json:"files"`
+	// ElevatedUsers are the IDs of the users imported with SlackImportOptions.AdminRole.
+	ElevatedUsers []string `json:"elevated_users,omitempty"`
+}
+
+func (r *SlackImportReport) unmapped(kind, channel, id, reason string) {
+	r.Unmapped = append(r.Unmapped, SlackUnmappedItem{Kind: kind, Channel: channel, ID: id, Reason: reason})
+}
+
+type slackUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+	Deleted  bool   `json:"deleted"`
+	IsBot    bool   `json:"is_bot"`
+	IsAdmin  bool   `json:"is_admin"`
+	IsOwner  bool   `json:"is_owner"`
+	TZ       string `json:"tz"`
+	Profile  struct {
+		RealName    string `json:"real_name"`
+		DisplayName string `json:"display_name"`
+		Email       string `json:"email"`
+		Title       string `json:"title"`
+		Image512    string `json:"image_512"`
+		Image192    string `json:"image_192"`
+	} `json:"profile"`
+}
+
+func (u *slackUser) displayName() string {
+	for _, name := range []string{u.Profile.DisplayName, u.Profile.RealName, u.RealName, u.Name} {
+		if name != "" {
+			return name
+		}
+	}
+	return u.ID
+}
+
+type slackChannel struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Created    int64    `json:"created"`
+	Creator    string   `json:"creator"`
+	IsArchived bool     `json:"is_archived"`
+	Members    []string `json:"members"`
+	Topic      struct {
+		Value string `json:"value"`
+	} `json:"topic"`
+	Purpose struct {
+		Value string `json:"value"`
+	} `json:"purpose"`
+
+	kind   string
+	folder string
+}
+
+type slackFile struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Title      string `json:"title"`
+	MimeType   string `json:"mimetype"`
+	Size       int64  `json:"size"`
+	URLPrivate string `json:"url_private"`
+	Mode       string `json:"mode"`
+}
+
+type slackAttachment struct {
+	Title      string `json:"title"`
+	TitleLink  string `json:"title_link"`
+	Text       string `json:"text"`
+	Fallback   string `json:"fallback"`
+	AuthorName string `json:"author_name"`
+	ImageURL   string `json:"image_url"`
+	ThumbURL   string `json:"thumb_url"`
+	FromURL    string `json:"from_url"`
+}
+
+type slackMessage struct {
+	Type        string            `json:"type"`
+	Subtype     string            `json:"subtype"`
+	TS          string            `json:"ts"`
+	ThreadTS    string            `json:"thread_ts"`
+	User        string            `json:"user"`
+	BotID       string            `json:"bot_id"`
+	Text        string            `json:"text"`
+	Files       []slackFile       `json:"files"`
+	Attachments []slackAttachment `json:"attachments"`
+	Reactions   []struct {
+		Name  string   `json:"name"`
+		Users []string `json:"users"`
+	} `json:"reactions"`
+}
+
+// slackMessageSubtypes maps the supported Slack message subtypes to Stream message types.
+var slackMessageSubtypes = map[string]MessageType{
+	"":                 MessageTypeRegular,
+	"bot_message":      MessageTypeRegular,
+	"file_share":       MessageTypeRegular,
+	"me_message":       MessageTypeRegular,
+	"thread_broadcast": MessageTypeRegular,
+	"channel_join":     MessageTypeSystem,
+	"channel_leave":    MessageTypeSystem,
+	"channel_topic":    MessageTypeSystem,
+	"channel_purpose":  MessageTypeSystem,
+	"channel_name":     MessageTypeSystem,
+	"channel_archive":  MessageTypeSystem,
+	"group_join":       MessageTypeSystem,
+	"group_leave":      MessageTypeSystem,
+}
+
+type slackConverter struct {
+	opts   SlackImportOptions
+	export fs.FS
+	w      *ImportWriter
+	report *SlackImportReport
+
+	users     map[string]*slackUser
+	reactions []slackPendingReaction
+}
+
+type slackPendingReaction struct {
+	reaction  *Reaction
+	createdAt time.Time
+}
+
+// ConvertSlackExport converts a Slack workspace export to the Stream import format and writes it to w.
+// export is the unzipped export, for example a *zip.Reader or os.DirFS: users.json, channels.json,
+// groups.json, dms.json and mpims.json and one folder of daily message files per conversation.
+//
+// Slack users, channels, members, messages, thread replies and reactions are converted. Items that
+// can't be converted are listed in the report instead of failing the conversion. The output can be
+// checked with ValidateImport and imported with RunImport.
+func ConvertSlackExport(export fs.FS, w io.Writer, opts *SlackImportOptions) (*SlackImportReport, error) {
+	c := &slackConverter{
+		export: export,
+		w:      NewImportWriter(w),
+		report: &SlackImportReport{},
+		users:  make(map[string]*slackUser),
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.ChannelType == "" {
+		c.opts.ChannelType = "messaging"
+	}
+	if c.opts.DirectChannelType == "" {
+		c.opts.DirectChannelType = c.opts.ChannelType
+	}
+
+	if err := c.convertUsers(); err != nil {
+		return nil, err
+	}
+
+	channels, err := c.readChannels()
+	if err != nil {
+		return nil, err
+	}
+	var converted []*slackChannel
+	for _, ch := range channels {
+		ok, err := c.convertChannel(ch)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			converted = append(converted, ch)
+		}
+	}
+	for _, ch := range converted {
+		if err := c.convertMembers(ch); err != nil {
+			return nil, err
+		}
+	}
+	for _, ch := range converted {
+		if err := c.convertMessages(ch); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range c.reactions {
+		if err := c.w.WriteReaction(r.reaction, r.createdAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	c.report.Counts = c.w.Counts()
+	return c.report, nil
+}
+
+// readJSON decodes a top level export file. Missing files are not an error, as exports
+// only contain the conversation kinds the exporting user had access to.
+func (c *slackConverter) readJSON(name string, v interface{}) (bool, error) {
+	data, err := fs.ReadFile(c.export, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("%s: %w", name, err)
+	}
+	return true, nil
+}
+
+func (c *slackConverter) convertUsers() error {
+	var users []*slackUser
+	found, err := c.readJSON("users.json", &users)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("users.json not found in the Slack export")
+	}
+
+	for _, su := range users {
+		if su.ID == "" {
+			c.report.unmapped("user", "", su.Name, "missing user id")
+			continue
+		}
+		c.users[su.ID] = su
+
+		u := &User{
+			ID:        su.ID,
+			Name:      su.displayName(),
+			Image:     su.Profile.Image512,
+			Role:      "user",
+			ExtraData: map[string]interface{}{"slack_username": su.Name},
+		}
+		if u.Image == "" {
+			u.Image = su.Profile.Image192
+		}
+		if (su.IsAdmin || su.IsOwner) && c.opts.AdminRole != "" {
+			u.Role = c.opts.AdminRole
+			c.report.ElevatedUsers = append(c.report.ElevatedUsers, su.ID)
+		}
+		setIfNotEmpty(u.ExtraData, "email", su.Profile.Email)
+		setIfNotEmpty(u.ExtraData, "title", su.Profile.Title)
+		setIfNotEmpty(u.ExtraData, "timezone", su.TZ)
+		if su.IsBot {
+			u.ExtraData["bot"] = true
+		}
+		if su.Deleted {
+			u.ExtraData["slack_deleted"] = true
+		}
+		if err := c.w.WriteUser(u); err != nil {
+			return err
+		}
+	}
+
+	if id := c.opts.BotUserID; id != "" {
+		if _, ok := c.users[id]; !ok {
+			c.users[id] = &slackUser{ID: id, Name: id, IsBot: true}
+			if err := c.w.WriteUser(&User{ID: id, Name: id, Role: "user", ExtraData: map[string]interface{}{"bot": true}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *slackConverter) readChannels() ([]*slackChannel, error) {
+	var all []*slackChannel
+	for _, kind := range []string{"channels", "groups", "dms", "mpims"} {
+		var channels []*slackChannel
+		if _, err := c.readJSON(kind+".json", &channels); err != nil {
+			return nil, err
+		}
+		for _, ch := range channels {
+			ch.kind = kind
+			// direct messages are exported in folders named by ID, the others by name
+			ch.folder = ch.Name
+			if kind == "dms" || ch.folder == "" {
+				ch.folder = ch.ID
+			}
+			all = append(all, ch)
+		}
+	}
+	return all, nil
+}
+
+func (c *slackConverter) channelType(ch *slackChannel) string {
+	if ch.kind == "dms" || ch.kind == "mpims" {
+		return c.opts.DirectChannelType
+	}
+	return c.opts.ChannelType
+}
+
+func (c *slackConverter) cid(ch *slackChannel) string {
+	return c.channelType(ch) + ":" + ch.ID
+}
+
+func (c *slackConverter) convertChannel(ch *slackChannel) (bool, error) {
+	if ch.ID == "" {
+		c.report.unmapped("channel", ch.Name, ch.Name, "missing channel id")
+		return false, nil
+	}
+
+	creator := ch.Creator
+	if _, ok := c.users[creator]; !ok {
+		creator = ""
+		for _, id := range ch.Members {
+			if _, ok := c.users[id]; ok {
+				creator = id
+				break
+			}
+		}
+	}
+	if creator == "" {
+		creator = c.opts.BotUserID
+	}
+	if creator == "" {
+		c.report.unmapped("channel", ch.Name, ch.ID, "no known creator or member")
+		return false, nil
+	}
+
+	channel := &Channel{
+		Type:      c.channelType(ch),
+		ID:        ch.ID,
+		CreatedBy: &User{ID: creator},
+		Frozen:    ch.IsArchived,
+		ExtraData: map[string]interface{}{},
+	}
+	if ch.Created > 0 {
+		channel.CreatedAt = time.Unix(ch.Created, 0)
+	}
+	setIfNotEmpty(channel.ExtraData, "name", ch.Name)
+	setIfNotEmpty(channel.ExtraData, "topic", ch.Topic.Value)
+	setIfNotEmpty(channel.ExtraData, "description", ch.Purpose.Value)
+	if ch.kind == "groups" || ch.kind == "mpims" {
+		channel.ExtraData["private"] = true
+	}
+
+	return true, c.w.WriteChannel(channel)
+}
+
+func (c *slackConverter) convertMembers(ch *slackChannel) error {
+	seen := make(map[string]bool, len(ch.Members))
+	for _, id := range ch.Members {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if _, ok := c.users[id]; !ok {
+			c.report.unmapped("member", ch.ID, id, "user not in users.json")
+			continue
+		}
+		if err := c.w.WriteMember(c.cid(ch), &ChannelMember{UserID: id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMessages reads the daily message files of a conversation, sorted by timestamp.
+func (c *slackConverter) readMessages(ch *slackChannel) ([]*slackMessage, error) {
+	entries, err := fs.ReadDir(c.export, ch.folder)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*slackMessage
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".json" {
+			continue
+		}
+		var day []*slackMessage
+		if _, err := c.readJSON(path.Join(ch.folder, e.Name()), &day); err != nil {
+			return nil, err
+		}
+		messages = append(messages, day...)
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return slackTime(messages[i].TS).Before(slackTime(messages[j].TS))
+	})
+	return messages, nil
+}
+
+func (c *slackConverter) convertMessages(ch *slackChannel) error {
+	messages, err := c.readMessages(ch)
+	if err != nil {
+		return err
+	}
+
+	written := make(map[string]bool, len(messages))
+	for _, sm := range messages {
+		id := slackMessageID(ch.ID, sm.TS)
+		if sm.TS == "" || (sm.Type != "" && sm.Type != "message") {
+			c.report.unmapped("message", ch.ID, sm.TS, fmt.Sprintf("unsupported item type %q", sm.Type))
+			continue
+		}
+
+		typ, ok := slackMessageSubtypes[sm.Subtype]
+		if !ok {
+			c.report.unmapped("message", ch.ID, id, fmt.Sprintf("unsupported message subtype %q", sm.Subtype))
+			continue
+		}
+
+		userID := sm.User
+		if userID == "" && sm.BotID != "" {
+			userID = c.opts.BotUserID
+		}
+		if _, ok := c.users[userID]; !ok || userID == "" {
+			c.report.unmapped("message", ch.ID, id, fmt.Sprintf("unknown author %q", sm.User+sm.BotID))
+			continue
+		}
+
+		createdAt := slackTime(sm.TS)
+		text, mentioned := c.convertText(sm.Text)
+		msg := &Message{
+			ID:        id,
+			Type:      typ,
+			Text:      text,
+			UserID:    userID,
+			CreatedAt: &createdAt,
+		}
+		for _, uid := range mentioned {
+			msg.MentionedUsers = append(msg.MentionedUsers, &User{ID: uid})
+		}
+		if sm.ThreadTS != "" && sm.ThreadTS != sm.TS {
+			parentID := slackMessageID(ch.ID, sm.ThreadTS)
+			if written[parentID] {
+				msg.ParentID = parentID
+				msg.Type = MessageTypeReply
+				msg.ShowInChannel = sm.Subtype == "thread_broadcast"
+			} else {
+				c.report.unmapped("message", ch.ID, id, fmt.Sprintf("thread parent %s not in export, imported as a regular message", sm.ThreadTS))
+			}
+		}
+		for _, a := range sm.Attachments {
+			msg.Attachments = append(msg.Attachments, a.toAttachment())
+		}
+		for i := range sm.Files {
+			if a := c.convertFile(ch, id, &sm.Files[i]); a != nil {
+				msg.Attachments = append(msg.Attachments, a)
+			}
+		}
+
+		if err := c.w.WriteMessage(c.cid(ch), msg); err != nil {
+			return err
+		}
+		written[id] = true
+
+		for _, r := range sm.Reactions {
+			for _, uid := range r.Users {
+				if _, ok := c.users[uid]; !ok {
+					c.report.unmapped("reaction", ch.ID, id, fmt.Sprintf("%s by unknown user %q", r.Name, uid))
+					continue
+				}
+				c.reactions = append(c.reactions, slackPendingReaction{
+					reaction:  &Reaction{MessageID: id, UserID: uid, Type: r.Name},
+					createdAt: createdAt,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func (c *slackConverter) convertFile(ch *slackChannel, messageID string, f *slackFile) *Attachment {
+	if f.Mode == "tombstone" || f.Mode == "hidden_by_limit" || f.URLPrivate == "" {
+		c.report.unmapped("file", ch.ID, f.ID, "file not available in the export")
+		return nil
+	}
+
+	c.report.Files = append(c.report.Files, SlackFileReference{
+		MessageID: messageID,
+		FileID:    f.ID,
+		Name:      f.Name,
+		MimeType:  f.MimeType,
+		URL:       f.URLPrivate,
+	})
+
+	a := &Attachment{
+		Type:     "file",
+		Title:    f.Name,
+		AssetURL: f.URLPrivate,
+		MimeType: f.MimeType,
+	}
+	switch {
+	case strings.HasPrefix(f.MimeType, "image/"):
+		a.Type = "image"
+		a.ImageURL = f.URLPrivate
+	case strings.HasPrefix(f.MimeType, "video/"):
+		a.Type = "video"
+	case strings.HasPrefix(f.MimeType, "audio/"):
+		a.Type = "audio"
+	}
+	if f.Size > 0 {
+		a.ExtraData = map[string]interface{}{"file_size": f.Size}
+	}
+	return a
+}
+
+func (a *slackAttachment) toAttachment() *Attachment {
+	att := &Attachment{
+		Title:       a.Title,
+		TitleLink:   a.TitleLink,
+		Text:        a.Text,
+		AuthorName:  a.AuthorName,
+		ImageURL:    a.ImageURL,
+		ThumbURL:    a.ThumbURL,
+		OGScrapeURL: a.FromURL,
+	}
+	if att.Text == "" {
+		att.Text = a.Fallback
+	}
+	return att
+}
+
+var slackEntityRegexp = regexp.MustCompile(`<([^<>]+)>`)
+
+// convertText converts Slack markup to plain text with markdown links, returning the mentioned user IDs.
+func (c *slackConverter) convertText(text string) (string, []string) {
+	var mentioned []string
+	text = slackEntityRegexp.ReplaceAllStringFunc(text, func(entity string) string {
+		ref, label, _ := strings.Cut(entity[1:len(entity)-1], "|")
+		switch {
+		case strings.HasPrefix(ref, "@"):
+			id := ref[1:]
+			if u, ok := c.users[id]; ok {
+				if !slices.Contains(mentioned, id) {
+					mentioned = append(mentioned, id)
+				}
+				return "@" + u.displayName()
+			}
+			if label != "" {
+				return "@" + label
+			}
+			return ref
+		case strings.HasPrefix(ref, "#"):
+			if label != "" {
+				return "#" + label
+			}
+			return ref
+		case strings.HasPrefix(ref, "!subteam^"):
+			return label
+		case strings.HasPrefix(ref, "!"):
+			return "@" + ref[1:]
+		case strings.HasPrefix(ref, "mailto:") && label != "":
+			return label
+		case label != "":
+			return "[" + label + "](" + ref + ")"
+		default:
+			return ref
+		}
+	})
+	return html.UnescapeString(text), mentioned
+}
+
+// slackMessageID returns the Stream message ID of a Slack message, unique per conversation and timestamp.
+func slackMessageID(channelID, ts string) string {
+	return channelID + "-" + strings.ReplaceAll(ts, ".", "-")
+}
+
+// slackTime parses Slack timestamps such as "1500000000.000100".
+func slackTime(ts string) time.Time {
+	secs, frac, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	var nsec int64
+	if frac != "" {
+		frac = (frac + "000000000")[:9]
+		nsec, _ = strconv.ParseInt(frac, 10, 64)
+	}
+	return time.Unix(s, nsec).UTC()
+}
@@ -0,0 +1,144 @@
+package stream_chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func slackExportFS() fstest.MapFS {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+
+	return fstest.MapFS{
+		"users.json": file(`[
+			{"id": "U1", "name": "jane", "is_admin": true, "profile": {"real_name": "Jane Doe", "display_name": "jane", "email": "jane@example.com", "image_192": "https://img/jane.png"}},
+			{"id": "U2", "name": "bob", "deleted": true, "profile": {"real_name": "Bob"}}
+		]`),
+		"channels.json": file(`[
+			{"id": "C1", "name": "general", "created": 1500000000, "creator": "U1", "members": ["U1", "U2", "U9"], "purpose": {"value": "Company wide"}}
+		]`),
+		"dms.json":   file(`[{"id": "D1", "created": 1500000000, "members": ["U2", "U1"]}]`),
+		"mpims.json": file(`[{"id": "G1", "name": "mpdm-ghosts", "members": ["U8", "U9"]}]`),
+		"general/2017-07-14.json": file(`[
+			{"type": "message", "user": "U1", "text": "hi <@U2> &amp; <!here>, see <https://example.com|docs>", "ts": "1500000001.000100", "thread_ts": "1500000001.000100",
+			 "reactions": [{"name": "tada", "users": ["U2", "U9"], "count": 2}]},
+			{"type": "message", "subtype": "channel_join", "user": "U2", "text": "<@U2> has joined the channel", "ts": "1500000000.000200"}
+		]`),
+		"general/2017-07-15.json": file(`[
+			{"type": "message", "user": "U2", "text": "reply", "ts": "1500090000.000100", "thread_ts": "1500000001.000100"},
+			{"type": "message", "subtype": "thread_broadcast", "user": "U2", "text": "broadcast", "ts": "1500090001.000100", "thread_ts": "1500000001.000100"},
+			{"type": "message", "user": "U1", "text": "orphan", "ts": "1500090002.000100", "thread_ts": "1400000000.000100"},
+			{"type": "message", "subtype": "file_share", "user": "U1", "text": "", "ts": "1500090003.000100",
+			 "files": [{"id": "F1", "name": "cat.png", "mimetype": "image/png", "url_private": "https://files.slack.com/cat.png", "size": 10}, {"id": "F2", "mode": "tombstone"}]},
+			{"type": "message", "subtype": "bot_message", "bot_id": "B1", "text": "deploy done", "ts": "1500090004.000100",
+			 "attachments": [{"title": "Build 42", "title_link": "https://ci/42", "fallback": "build passed"}]},
+			{"type": "message", "subtype": "huddle_thread", "user": "U1", "ts": "1500090005.000100"}
+		]`),
+		"D1/2017-07-14.json": file(`[{"type": "message", "user": "U2", "text": "psst", "ts": "1500000002.000100"}]`),
+	}
+}
+
+func TestConvertSlackExport(t *testing.T) {
+	var buf bytes.Buffer
+	report, err := ConvertSlackExport(slackExportFS(), &buf, &SlackImportOptions{DirectChannelType: "dm", BotUserID: "slackbot"})
+	require.NoError(t, err)
+
+	require.Equal(t, map[ImportItemType]int{
+		ImportItemUser:     3,
+		ImportItemChannel:  3,
+		ImportItemMember:   4,
+		ImportItemMessage:  8,
+		ImportItemReaction: 1,
+	}, report.Counts)
+
+	require.Equal(t, []SlackUnmappedItem{
+		{Kind: "member", Channel: "C1", ID: "U9", Reason: "user not in users.json"},
+		{Kind: "member", Channel: "G1", ID: "U8", Reason: "user not in users.json"},
+		{Kind: "member", Channel: "G1", ID: "U9", Reason: "user not in users.json"},
+		{Kind: "reaction", Channel: "C1", ID: "C1-1500000001-000100", Reason: `tada by unknown user "U9"`},
+		{Kind: "message", Channel: "C1", ID: "C1-1500090002-000100", Reason: "thread parent 1400000000.000100 not in export, imported as a regular message"},
+		{Kind: "file", Channel: "C1", ID: "F2", Reason: "file not available in the export"},
+		{Kind: "message", Channel: "C1", ID: "C1-1500090005-000100", Reason: `unsupported message subtype "huddle_thread"`},
+	}, report.Unmapped)
+	require.Equal(t, []SlackFileReference{
+		{MessageID: "C1-1500090003-000100", FileID: "F1", Name: "cat.png", MimeType: "image/png", URL: "https://files.slack.com/cat.png"},
+	}, report.Files)
+
+	items := map[string]map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var item struct {
+			Type string                 `json:"type"`
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &item))
+		id, _ := item.Data["id"].(string)
+		items[item.Type+"/"+id] = item.Data
+	}
+
+	jane := items["user/U1"]
+	require.Equal(t, "jane", jane["name"])
+	require.Equal(t, "user", jane["role"])
+	require.Empty(t, report.ElevatedUsers)
+	require.Equal(t, "jane@example.com", jane["email"])
+	require.Equal(t, true, items["user/U2"]["slack_deleted"])
+
+	general := items["channel/C1"]
+	require.Equal(t, "messaging", general["type"])
+	require.Equal(t, "general", general["name"])
+	require.Equal(t, "Company wide", general["description"])
+	require.Equal(t, "U1", general["created_by"])
+	require.Equal(t, "U2", items["channel/D1"]["created_by"])
+	require.Equal(t, "dm", items["channel/D1"]["type"])
+	require.Equal(t, "slackbot", items["channel/G1"]["created_by"])
+	require.Equal(t, true, items["channel/G1"]["private"])
+
+	first := items["message/C1-1500000001-000100"]
+	require.Equal(t, "hi @Bob & @here, see [docs](https://example.com)", first["text"])
+	require.Equal(t, []interface{}{"U2"}, first["mentioned_users_ids"])
+	require.Equal(t, "2017-07-14T02:40:01.0001Z", first["created_at"])
+
+	require.Equal(t, "system", items["message/C1-1500000000-000200"]["type"])
+
+	reply := items["message/C1-1500090000-000100"]
+	require.Equal(t, "reply", reply["type"])
+	require.Equal(t, "C1-1500000001-000100", reply["parent_id"])
+	require.Equal(t, true, items["message/C1-1500090001-000100"]["show_in_channel"])
+	require.Nil(t, items["message/C1-1500090002-000100"]["parent_id"])
+
+	file := items["message/C1-1500090003-000100"]["attachments"].([]interface{})
+	require.Len(t, file, 1)
+	require.Equal(t, "image", file[0].(map[string]interface{})["type"])
+
+	bot := items["message/C1-1500090004-000100"]
+	require.Equal(t, "slackbot", bot["user"])
+	require.Equal(t, "build passed", bot["attachments"].([]interface{})[0].(map[string]interface{})["text"])
+
+	validation, err := ValidateImport(&buf, nil)
+	require.NoError(t, err)
+	require.True(t, validation.Valid(), validation.Err())
+}
+
+func TestConvertSlackExport_AdminRole(t *testing.T) {
+	var buf bytes.Buffer
+	report, err := ConvertSlackExport(slackExportFS(), &buf, &SlackImportOptions{AdminRole: "admin"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"U1"}, report.ElevatedUsers)
+	require.Contains(t, strings.Split(buf.String(), "\n")[0], `"role":"admin"`)
+}
+
+func TestConvertSlackExport_UnknownCreator(t *testing.T) {
+	var buf bytes.Buffer
+	report, err := ConvertSlackExport(slackExportFS(), &buf, nil)
+	require.NoError(t, err)
+	require.Contains(t, report.Unmapped, SlackUnmappedItem{Kind: "channel", Channel: "mpdm-ghosts", ID: "G1", Reason: "no known creator or member"})
+	require.Contains(t, report.Unmapped, SlackUnmappedItem{Kind: "message", Channel: "C1", ID: "C1-1500090004-000100", Reason: `unknown author "B1"`})
+}
+
+func TestConvertSlackExport_MissingUsers(t *testing.T) {
+	_, err := ConvertSlackExport(fstest.MapFS{}, &bytes.Buffer{}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,560 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operations recorded by the TaskTracker helpers.
+const (
+	TaskOperationDeleteChannels  = "delete_channels"
+	TaskOperationDeleteUsers     = "delete_users"
+	TaskOperationExportChannels  = "export_channels"
+	TaskOperationExportUsers     = "export_users"
+	TaskOperationDeactivateUsers = "deactivate_users"
+	TaskOperationReactivateUsers = "reactivate_users"
+	TaskOperationChannelsBatch   = "channels_batch"
+)
+
+// ErrTrackedTaskNotFound is returned by a TaskStore when the task is unknown.
+var ErrTrackedTaskNotFound = errors.New("tracked task not found")
+
+// TrackedTask is an async task recorded by a TaskTracker.
+type TrackedTask struct {
+	TaskID    string          `json:"task_id"`
+	Operation string          `json:"operation"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+
+	Status      TaskStatus             `json:"status"`
+	Result      map[string]interface{} `json:"result,omitempty"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+
+	// Notified is set once the completion callbacks were invoked, see TaskDelivery.
+	Notified      bool   `json:"notified"`
+	CallbackError string `json:"callback_error,omitempty"`
+	// DeliveredCallbacks are the positions of the callbacks already invoked for the task,
+	// in the order they were registered with OnComplete.
+	DeliveredCallbacks []int `json:"delivered_callbacks,omitempty"`
+	// CallbackAttempts is the number of rounds in which a callback of the task failed.
+	CallbackAttempts int `json:"callback_attempts,omitempty"`
+}
+
+// Done reports whether the task reached a final state.
+func (t *TrackedTask) Done() bool {
+	return t.Status == TaskStatusCompleted || t.Status == TaskStatusFailed
+}
+
+// DecodeParams decodes the parameters recorded with the task into v.
+func (t *TrackedTask) DecodeParams(v interface{}) error {
+	if len(t.Params) == 0 {
+		return nil
+	}
+	return json.Unmarshal(t.Params, v)
+}
+
+// TaskStore persists tracked tasks. Implementations must be safe for concurrent use.
+type TaskStore interface {
+	// Save inserts or replaces the task.
+	Save(ctx context.Context, task *TrackedTask) error
+	// Get returns the task or ErrTrackedTaskNotFound.
+	Get(ctx context.Context, taskID string) (*TrackedTask, error)
+	// List returns all stored tasks.
+	List(ctx context.Context) ([]*TrackedTask, error)
+	// Delete removes the task. Deleting an unknown task is not an error.
+	Delete(ctx context.Context, taskID string) error
+}
+
+// MemoryTaskStore is a TaskStore that keeps tasks in memory.
+type MemoryTaskStore struct {
+	mu    sync.Mutex
+	tasks map[string]TrackedTask
+}
+
+// NewMemoryTaskStore returns an empty MemoryTaskStore.
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{tasks: make(map[string]TrackedTask)}
+}
+
+func (s *MemoryTaskStore) Save(_ context.Context, task *TrackedTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[task.TaskID] = *task
+	return nil
+}
+
+func (s *MemoryTaskStore) Get(_ context.Context, taskID string) (*TrackedTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[taskID]
+	if !ok {
+		return nil, ErrTrackedTaskNotFound
+	}
+	return &task, nil
+}
+
+func (s *MemoryTaskStore) List(_ context.Context) ([]*TrackedTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedTrackedTasks(s.tasks), nil
+}
+
+func (s *MemoryTaskStore) Delete(_ context.Context, taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tasks, taskID)
+	return nil
+}
+
+// FileTaskStore is a TaskStore backed by a single JSON file.
+// Every change rewrites the file atomically so it survives process restarts.
+type FileTaskStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileTaskStore returns a store persisting tasks to path. The file is created on first write.
+func NewFileTaskStore(path string) (*FileTaskStore, error) {
+	if path == "" {
+		return nil, errors.New("path must not be empty")
+	}
+	s := &FileTaskStore{path: path}
+	if _, err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileTaskStore) Save(_ context.Context, task *TrackedTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks, err := s.load()
+	if err != nil {
+		return err
+	}
+	tasks[task.TaskID] = *task
+	return s.write(tasks)
+}
+
+func (s *FileTaskStore) Get(_ context.Context, taskID string) (*TrackedTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	task, ok := tasks[taskID]
+	if !ok {
+		return nil, ErrTrackedTaskNotFound
+	}
+	return &task, nil
+}
+
+func (s *FileTaskStore) List(_ context.Context) ([]*TrackedTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return sortedTrackedTasks(tasks), nil
+}
+
+func (s *FileTaskStore) Delete(_ context.Context, taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := tasks[taskID]; !ok {
+		return nil
+	}
+	delete(tasks, taskID)
+	return s.write(tasks)
+}
+
+type taskStoreFile struct {
+	Tasks map[string]TrackedTask `json:"tasks"`
+}
+
+func (s *FileTaskStore) load() (map[string]TrackedTask, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]TrackedTask), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f taskStoreFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("cannot decode task store %s: %w", s.path, err)
+	}
+	if f.Tasks == nil {
+		f.Tasks = make(map[string]TrackedTask)
+	}
+	return f.Tasks, nil
+}
+
+func (s *FileTaskStore) write(tasks map[string]TrackedTask) error {
+	b, err := json.MarshalIndent(taskStoreFile{Tasks: tasks}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+func sortedTrackedTasks(m map[string]TrackedTask) []*TrackedTask {
+	tasks := make([]*TrackedTask, 0, len(m))
+	for id := range m {
+		task := m[id]
+		tasks = append(tasks, &task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].TaskID < tasks[j].TaskID
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+	return tasks
+}
+
+// TaskCallback is invoked when a tracked task completes or fails.
+// resp is the final state returned by GetTask.
+type TaskCallback func(ctx context.Context, task *TrackedTask, resp *TaskResponse) error
+
+// TaskDelivery is the guarantee with which completion callbacks are invoked.
+type TaskDelivery string
+
+const (
+	// TaskDeliveryAtMostOnce records each callback as delivered before it runs. A crash or a
+	// callback error never causes it to run again, but a crash loses the notification.
+	TaskDeliveryAtMostOnce TaskDelivery = "at_most_once"
+	// TaskDeliveryAtLeastOnce records each callback as delivered once it succeeded. Callbacks
+	// that failed, or were interrupted by a crash, run again on the next round while those that
+	// already succeeded are skipped. A crash right after a callback returns can still repeat it,
+	// so callbacks should be idempotent, for example by recording the task ID with their effect.
+	TaskDeliveryAtLeastOnce TaskDelivery = "at_least_once"
+)
+
+// TaskTrackerOptions configures a TaskTracker.
+type TaskTrackerOptions struct {
+	// PollInterval is the delay between polling rounds in Run. Defaults to 5 seconds.
+	PollInterval time.Duration
+	// Delivery is the guarantee for completion callbacks. Defaults to TaskDeliveryAtLeastOnce.
+	Delivery TaskDelivery
+	// MaxCallbackAttempts is the number of rounds a failing callback is retried with
+	// TaskDeliveryAtLeastOnce before the task is given up and finished. Defaults to 5.
+	MaxCallbackAttempts int
+	// KeepFinished keeps finished tasks in the store instead of deleting them once notified.
+	KeepFinished bool
+	// OnError is called with errors that happen while polling in the background, if set.
+	OnError func(task *TrackedTask, err error)
+}
+
+const (
+	defaultTaskTrackerPollInterval = 5 * time.Second
+	defaultTaskCallbackAttempts    = 5
+)
+
+// TaskTracker records async tasks in a TaskStore and polls GetTask until they finish,
+// so tasks started before a restart are picked up again by the next Run.
+//
+// By default completion callbacks are invoked at least once per task: every callback is
+// recorded as delivered in the store once it succeeded, so failed or interrupted callbacks
+// are retried on the next round without repeating the ones that already ran. Callbacks are
+// identified by their registration order, so register them in the same order on every start.
+// A task is finished, and deleted unless KeepFinished is set, once all its callbacks were
+// delivered or a callback exhausted MaxCallbackAttempts.
+type TaskTracker struct {
+	client *Client
+	store  TaskStore
+	opts   TaskTrackerOptions
+
+	mu        sync.Mutex
+	callbacks map[string][]TaskCallback
+	poll      sync.Mutex
+}
+
+// NewTaskTracker returns a tracker using client to poll tasks recorded in store.
+func NewTaskTracker(client *Client, store TaskStore, opts *TaskTrackerOptions) (*TaskTracker, error) {
+	switch {
+	case client == nil:
+		return nil, errors.New("client must not be nil")
+	case store == nil:
+		return nil, errors.New("store must not be nil")
+	}
+
+	t := &TaskTracker{
+		client:    client,
+		store:     store,
+		callbacks: make(map[string][]TaskCallback),
+	}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.PollInterval <= 0 {
+		t.opts.PollInterval = defaultTaskTrackerPollInterval
+	}
+	if t.opts.MaxCallbackAttempts <= 0 {
+		t.opts.MaxCallbackAttempts = defaultTaskCallbackAttempts
+	}
+	switch t.opts.Delivery {
+	case "":
+		t.opts.Delivery = TaskDeliveryAtLeastOnce
+	case TaskDeliveryAtMostOnce, TaskDeliveryAtLeastOnce:
+	default:
+		return nil, fmt.Errorf("unknown task delivery %q", t.opts.Delivery)
+	}
+	return t, nil
+}
+
+// OnComplete registers fn to be called when a task of the given operation finishes.
+// An empty operation registers fn for all tasks. Callbacks must be registered before Run.
+func (t *TaskTracker) OnComplete(operation string, fn TaskCallback) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.callbacks[operation] = append(t.callbacks[operation], fn)
+}
+
+// Track records a task started by operation with the given parameters.
+func (t *TaskTracker) Track(ctx context.Context, taskID, operation string, params interface{}) (*TrackedTask, error) {
+	if taskID == "" {
+		return nil, errors.New("task ID must be not empty")
+	}
+
+	task := &TrackedTask{
+		TaskID:    taskID,
+		Operation: operation,
+		CreatedAt: time.Now().UTC(),
+		Status:    TaskStatusPending,
+	}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode task params: %w", err)
+		}
+		task.Params = b
+	}
+
+	if err := t.store.Save(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// DeleteUsers calls Client.DeleteUsers and tracks the resulting task.
+func (t *TaskTracker) DeleteUsers(ctx context.Context, userIDs []string, options DeleteUserOptions) (*TrackedTask, error) {
+	resp, err := t.client.DeleteUsers(ctx, userIDs, options)
+	if err != nil {
+		return nil, err
+	}
+	params := struct {
+		UserIDs []string          `json:"user_ids"`
+		Options DeleteUserOptions `json:"options"`
+	}{userIDs, options}
+	return t.Track(ctx, resp.TaskID, TaskOperationDeleteUsers, params)
+}
+
+// DeleteChannels calls Client.DeleteChannels and tracks the resulting task.
+func (t *TaskTracker) DeleteChannels(ctx context.Context, cids []string, hardDelete bool) (*TrackedTask, error) {
+	resp, err := t.client.DeleteChannels(ctx, cids, hardDelete)
+	if err != nil {
+		return nil, err
+	}
+	params := struct {
+		CIDs       []string `json:"cids"`
+		HardDelete bool     `json:"hard_delete"`
+	}{cids, hardDelete}
+	return t.Track(ctx, resp.TaskID, TaskOperationDeleteChannels, params)
+}
+
+// ExportChannels calls Client.ExportChannels and tracks the resulting task.
+func (t *TaskTracker) ExportChannels(ctx context.Context, channels []*ExportableChannel, options *ExportChannelOptions) (*TrackedTask, error) {
+	resp, err := t.client.ExportChannels(ctx, channels, options)
+	if err != nil {
+		return nil, err
+	}
+	params := struct {
+		Channels []*ExportableChannel  `json:"channels"`
+		Options  *ExportChannelOptions `json:"options,omitempty"`
+	}{channels, options}
+	return t.Track(ctx, resp.TaskID, TaskOperationExportChannels, params)
+}
+
+// ExportUsers calls Client.ExportUsers and tracks the resulting task.
+func (t *TaskTracker) ExportUsers(ctx context.Context, userIDs []string) (*TrackedTask, error) {
+	resp, err := t.client.ExportUsers(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	params := struct {
+		UserIDs []string `json:"user_ids"`
+	}{userIDs}
+	return t.Track(ctx, resp.TaskID, TaskOperationExportUsers, params)
+}
+
+// Pending returns the tracked tasks whose callbacks have not been invoked yet.
+func (t *TaskTracker) Pending(ctx context.Context) ([]*TrackedTask, error) {
+	tasks, err := t.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := tasks[:0]
+	for _, task := range tasks {
+		if !task.Notified {
+			pending = append(pending, task)
+		}
+	}
+	return pending, nil
+}
+
+// Run polls all pending tasks every PollInterval until ctx is done.
+// Tasks recorded by a previous process are resumed on the first round.
+func (t *TaskTracker) Run(ctx context.Context) error {
+	for {
+		if err := t.PollOnce(ctx); err != nil && ctx.Err() == nil {
+			t.reportError(nil, err)
+		}
+		if err := sleepContext(ctx, t.opts.PollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// PollOnce checks every pending task once and runs the callbacks of finished tasks.
+// Errors for individual tasks are reported to OnError; only store failures are returned.
+func (t *TaskTracker) PollOnce(ctx context.Context) error {
+	t.poll.Lock()
+	defer t.poll.Unlock()
+
+	tasks, err := t.Pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := t.pollTask(ctx, task); err != nil {
+			t.reportError(task, err)
+		}
+	}
+	return nil
+}
+
+func (t *TaskTracker) pollTask(ctx context.Context, task *TrackedTask) error {
+	resp, err := t.client.GetTask(ctx, task.TaskID)
+	if err != nil {
+		return err
+	}
+
+	if !resp.Done() {
+		if resp.Status != task.Status {
+			task.Status = resp.Status
+			return t.store.Save(ctx, task)
+		}
+		return nil
+	}
+
+	if task.CompletedAt == nil {
+		now := time.Now().UTC()
+		task.CompletedAt = &now
+	}
+	task.Status = resp.Status
+	task.Result = resp.Result
+	if err := t.store.Save(ctx, task); err != nil {
+		return err
+	}
+
+	cbErr := t.runCallbacks(ctx, task, resp)
+	if errors.Is(cbErr, errTaskStoreSave) {
+		return cbErr
+	}
+	if cbErr != nil {
+		task.CallbackError = cbErr.Error()
+		task.CallbackAttempts++
+		if t.opts.Delivery == TaskDeliveryAtLeastOnce && task.CallbackAttempts < t.opts.MaxCallbackAttempts {
+			// the task stays pending and its failed callbacks are retried on the next round
+			if err := t.store.Save(ctx, task); err != nil {
+				return err
+			}
+			return cbErr
+		}
+	} else {
+		task.CallbackError = ""
+	}
+
+	task.Notified = true
+	if t.opts.KeepFinished {
+		if err := t.store.Save(ctx, task); err != nil {
+			return err
+		}
+		return cbErr
+	}
+	if err := t.store.Delete(ctx, task.TaskID); err != nil {
+		return err
+	}
+	return cbErr
+}
+
+// errTaskStoreSave wraps store failures while recording delivered callbacks.
+var errTaskStoreSave = errors.New("cannot record delivered callback")
+
+func (t *TaskTracker) runCallbacks(ctx context.Context, task *TrackedTask, resp *TaskResponse) error {
+	t.mu.Lock()
+	callbacks := append([]TaskCallback(nil), t.callbacks[task.Operation]...)
+	if task.Operation != "" {
+		callbacks = append(callbacks, t.callbacks[""]...)
+	}
+	t.mu.Unlock()
+
+	var errs []error
+	for i, fn := range callbacks {
+		if slices.Contains(task.DeliveredCallbacks, i) {
+			continue
+		}
+		if t.opts.Delivery == TaskDeliveryAtMostOnce {
+			if err := t.markDelivered(ctx, task, i); err != nil {
+				return err
+			}
+		}
+		if err := fn(ctx, task, resp); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if t.opts.Delivery == TaskDeliveryAtLeastOnce {
+			if err := t.markDelivered(ctx, task, i); err != nil {
+				return err
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *TaskTracker) markDelivered(ctx context.Context, task *TrackedTask, callback int) error {
+	task.DeliveredCallbacks = append(task.DeliveredCallbacks, callback)
+	if err := t.store.Save(ctx, task); err != nil {
+		return fmt.Errorf("%w: %w", errTaskStoreSave, err)
+	}
+	return nil
+}
+
+func (t *TaskTracker) reportError(task *TrackedTask, err error) {
+	if t.opts.OnError != nil {
+		t.opts.OnError(task, err)
+	}
+}
@@ -0,0 +1,289 @@
+package stream_chat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTaskServer struct {
+	mu     sync.Mutex
+	states map[string]string
+}
+
+func (s *fakeTaskServer) set(taskID, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[taskID] = body
+}
+
+func (s *fakeTaskServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/users/delete":
+		_, _ = w.Write([]byte(`{"task_id":"delete-1"}`))
+	case strings.HasPrefix(r.URL.Path, "/tasks/"):
+		body, ok := s.states[strings.TrimPrefix(r.URL.Path, "/tasks/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"not found","StatusCode":404}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestTaskTracker_ResumesAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	srv := &fakeTaskServer{states: map[string]string{
+		"delete-1": `{"task_id":"delete-1","status":"running"}`,
+		"export-1": `{"task_id":"export-1","status":"pending"}`,
+	}}
+	c := newTestClient(t, srv)
+
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	store, err := NewFileTaskStore(path)
+	require.NoError(t, err)
+
+	tracker, err := NewTaskTracker(c, store, nil)
+	require.NoError(t, err)
+
+	tracked, err := tracker.DeleteUsers(ctx, []string{"jane"}, DeleteUserOptions{User: HardDelete})
+	require.NoError(t, err)
+	require.Equal(t, "delete-1", tracked.TaskID)
+
+	_, err = tracker.Track(ctx, "export-1", TaskOperationExportChannels, map[string]string{"cid": "messaging:general"})
+	require.NoError(t, err)
+
+	require.NoError(t, tracker.PollOnce(ctx))
+
+	// simulate a restart: a new tracker and store reading the same file
+	srv.set("delete-1", `{"task_id":"delete-1","status":"completed","result":{"jane":{"status":"ok"}}}`)
+	srv.set("export-1", `{"task_id":"export-1","status":"failed","result":{"description":"boom"}}`)
+
+	store2, err := NewFileTaskStore(path)
+	require.NoError(t, err)
+	pending, err := store2.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, TaskStatusRunning, pending[0].Status)
+
+	tracker2, err := NewTaskTracker(c, store2, &TaskTrackerOptions{KeepFinished: true})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	tracker2.OnComplete(TaskOperationDeleteUsers, func(_ context.Context, task *TrackedTask, resp *TaskResponse) error {
+		var params struct {
+			UserIDs []string `json:"user_ids"`
+		}
+		require.NoError(t, task.DecodeParams(&params))
+		require.Equal(t, []string{"jane"}, params.UserIDs)
+		require.Empty(t, resp.FailedIDs())
+		return nil
+	})
+	tracker2.OnComplete("", func(_ context.Context, task *TrackedTask, resp *TaskResponse) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[task.TaskID]++
+		return nil
+	})
+
+	require.NoError(t, tracker2.PollOnce(ctx))
+	require.NoError(t, tracker2.PollOnce(ctx))
+	require.Equal(t, map[string]int{"delete-1": 1, "export-1": 1}, calls)
+
+	task, err := store2.Get(ctx, "export-1")
+	require.NoError(t, err)
+	require.Equal(t, TaskStatusFailed, task.Status)
+	require.True(t, task.Notified)
+	require.NotNil(t, task.CompletedAt)
+
+	pending, err = tracker2.Pending(ctx)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+func TestTaskTracker_CallbackErrors(t *testing.T) {
+	ctx := context.Background()
+	srv := &fakeTaskServer{states: map[string]string{
+		"t1": `{"task_id":"t1","status":"completed"}`,
+	}}
+	c := newTestClient(t, srv)
+	store := NewMemoryTaskStore()
+
+	var reported []error
+	tracker, err := NewTaskTracker(c, store, &TaskTrackerOptions{
+		Delivery: TaskDeliveryAtMostOnce,
+		OnError:  func(_ *TrackedTask, err error) { reported = append(reported, err) },
+	})
+	require.NoError(t, err)
+
+	calls := 0
+	tracker.OnComplete("op", func(context.Context, *TrackedTask, *TaskResponse) error {
+		calls++
+		return errors.New("callback failed")
+	})
+
+	_, err = tracker.Track(ctx, "t1", "op", nil)
+	require.NoError(t, err)
+	_, err = tracker.Track(ctx, "missing", "op", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, tracker.PollOnce(ctx))
+	require.NoError(t, tracker.PollOnce(ctx))
+	require.Equal(t, 1, calls)
+
+	// the failed notification is not retried and the finished task is removed
+	_, err = store.Get(ctx, "t1")
+	require.ErrorIs(t, err, ErrTrackedTaskNotFound)
+	require.EqualError(t, reported[0], "callback failed")
+
+	// the unknown task stays pending and its error is reported on each round
+	_, err = store.Get(ctx, "missing")
+	require.NoError(t, err)
+	require.Len(t, reported, 3)
+}
+
+func TestTaskTracker_AtLeastOnceDelivery(t *testing.T) {
+	ctx := context.Background()
+	srv := &fakeTaskServer{states: map[string]string{
+		"t1": `{"task_id":"t1","status":"completed"}`,
+	}}
+	c := newTestClient(t, srv)
+	store := NewMemoryTaskStore()
+
+	tracker, err := NewTaskTracker(c, store, &TaskTrackerOptions{Delivery: TaskDeliveryAtLeastOnce, KeepFinished: true})
+	require.NoError(t, err)
+
+	calls := 0
+	tracker.OnComplete("op", func(ctx context.Context, task *TrackedTask, _ *TaskResponse) error {
+		calls++
+		// a crash during the callback leaves the task pending
+		stored, err := store.Get(ctx, task.TaskID)
+		require.NoError(t, err)
+		require.False(t, stored.Notified)
+		if calls == 1 {
+			return errors.New("callback failed")
+		}
+		return nil
+	})
+
+	_, err = tracker.Track(ctx, "t1", "op", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, tracker.PollOnce(ctx))
+	task, err := store.Get(ctx, "t1")
+	require.NoError(t, err)
+	require.False(t, task.Notified)
+	require.Equal(t, "callback failed", task.CallbackError)
+
+	require.NoError(t, tracker.PollOnce(ctx))
+	require.NoError(t, tracker.PollOnce(ctx))
+	require.Equal(t, 2, calls)
+	task, err = store.Get(ctx, "t1")
+	require.NoError(t, err)
+	require.True(t, task.Notified)
+	require.Empty(t, task.CallbackError)
+
+	_, err = NewTaskTracker(c, store, &TaskTrackerOptions{Delivery: "exactly_once"})
+	require.Error(t, err)
+}
+
+func TestTaskTracker_RetriesOnlyFailedCallbacks(t *testing.T) {
+	ctx := context.Background()
+	srv := &fakeTaskServer{states: map[string]string{
+		"t1": `{"task_id":"t1","status":"completed"}`,
+	}}
+	c := newTestClient(t, srv)
+	store := NewMemoryTaskStore()
+
+	tracker, err := NewTaskTracker(c, store, nil)
+	require.NoError(t, err)
+
+	first, second := 0, 0
+	tracker.OnComplete("op", func(context.Context, *TrackedTask, *TaskResponse) error {
+		first++
+		return nil
+	})
+	tracker.OnComplete("op", func(context.Context, *TrackedTask, *TaskResponse) error {
+		second++
+		if second == 1 {
+			return errors.New("callback failed")
+		}
+		return nil
+	})
+
+	_, err = tracker.Track(ctx, "t1", "op", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, tracker.PollOnce(ctx))
+	task, err := store.Get(ctx, "t1")
+	require.NoError(t, err)
+	require.False(t, task.Notified)
+	require.Equal(t, []int{0}, task.DeliveredCallbacks)
+
+	require.NoError(t, tracker.PollOnce(ctx))
+	require.Equal(t, 1, first)
+	require.Equal(t, 2, second)
+	_, err = store.Get(ctx, "t1")
+	require.ErrorIs(t, err, ErrTrackedTaskNotFound)
+}
+
+func TestTaskTracker_GivesUpAfterMaxCallbackAttempts(t *testing.T) {
+	ctx := context.Background()
+	srv := &fakeTaskServer{states: map[string]string{
+		"t1": `{"task_id":"t1","status":"failed"}`,
+	}}
+	c := newTestClient(t, srv)
+	store := NewMemoryTaskStore()
+
+	tracker, err := NewTaskTracker(c, store, &TaskTrackerOptions{MaxCallbackAttempts: 2, KeepFinished: true})
+	require.NoError(t, err)
+
+	calls := 0
+	tracker.OnComplete("op", func(context.Context, *TrackedTask, *TaskResponse) error {
+		calls++
+		return errors.New("callback failed")
+	})
+
+	_, err = tracker.Track(ctx, "t1", "op", nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, tracker.PollOnce(ctx))
+	}
+	require.Equal(t, 2, calls)
+
+	task, err := store.Get(ctx, "t1")
+	require.NoError(t, err)
+	require.True(t, task.Notified)
+	require.Equal(t, 2, task.CallbackAttempts)
+	require.Equal(t, "callback failed", task.CallbackError)
+
+	pending, err := tracker.Pending(ctx)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+func TestTaskTracker_RunStopsWithContext(t *testing.T) {
+	srv := &fakeTaskServer{states: map[string]string{}}
+	c := newTestClient(t, srv)
+
+	tracker, err := NewTaskTracker(c, NewMemoryTaskStore(), nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, tracker.Run(ctx), context.Canceled)
+}
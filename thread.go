@@ -2,7 +2,12 @@ package stream_chat
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
 	"time"
 )
 
@@ -22,13 +27,14 @@ type QueryThreadsResponse struct {
 	PagerResponse
 }
 
+// ThreadResponse is the state of a thread as returned by QueryThreads and GetThread.
 type ThreadResponse struct {
 	ChannelCID             string             `json:"channel_cid"`
 	Channel                *Channel           `json:"channel,omitempty"`
 	ParentMessageID        string             `json:"parent_message_id"`
-	ParentMessage          *MessageResponse   `json:"parent_message,omitempty"`
+	ParentMessage          *Message           `json:"parent_message,omitempty"`
 	CreatedByUserID        string             `json:"created_by_user_id"`
-	CreatedBy              *UsersResponse     `json:"created_by,omitempty"`
+	CreatedBy              *User              `json:"created_by,omitempty"`
 	ReplyCount             int                `json:"reply_count,omitempty"`
 	ParticipantCount       int                `json:"participant_count,omitempty"`
 	ActiveParticipantCount int                `json:"active_participant_count,omitempty"`
@@ -40,9 +46,9 @@ type ThreadResponse struct {
 	Title                  string             `json:"title"`
 	Custom                 map[string]any     `json:"custom"`
 
-	LatestReplies []MessageResponse `json:"latest_replies,omitempty"`
-	Read          ChannelRead       `json:"read,omitempty"`
-	Draft         Draft
+	LatestReplies []*Message     `json:"latest_replies,omitempty"`
+	Read          []*ChannelRead `json:"read,omitempty"`
+	Draft         *Draft         `json:"draft,omitempty"`
 }
 
 type Thread struct {
@@ -100,3 +106,100 @@ func (c *Client) QueryThreads(ctx context.Context, query *QueryThreadsRequest) (
 
 	return &resp, nil
 }
+
+// GetThreadOptions configures the GetThread request.
+type GetThreadOptions struct {
+	// ReplyLimit is the number of latest replies returned.
+	ReplyLimit int
+	// ParticipantLimit is the number of thread participants returned.
+	ParticipantLimit int
+	// MemberLimit is the number of channel members returned.
+	MemberLimit int
+}
+
+// GetThreadResponse is the response of GetThread.
+type GetThreadResponse struct {
+	Thread *ThreadResponse `json:"thread"`
+	Response
+}
+
+// GetThread returns the thread started by the given parent message.
+func (c *Client) GetThread(ctx context.Context, parentMessageID string, opts *GetThreadOptions) (*GetThreadResponse, error) {
+	if parentMessageID == "" {
+		return nil, errors.New("parent message ID must be not empty")
+	}
+
+	params := url.Values{}
+	if opts != nil {
+		for name, limit := range map[string]int{
+			"reply_limit":       opts.ReplyLimit,
+			"participant_limit": opts.ParticipantLimit,
+			"member_limit":      opts.MemberLimit,
+		} {
+			if limit < 0 {
+				return nil, fmt.Errorf("%s must be not negative", name)
+			}
+			if limit > 0 {
+				params.Set(name, strconv.Itoa(limit))
+			}
+		}
+	}
+
+	p := path.Join("threads", url.PathEscape(parentMessageID))
+
+	var resp GetThreadResponse
+	err := c.makeRequest(ctx, http.MethodGet, p, params, nil, &resp)
+	return &resp, err
+}
+
+// threadReadOnlyFields are the thread fields which cannot be set or unset.
+var threadReadOnlyFields = map[string]bool{
+	"channel_cid": true, "channel": true, "parent_message_id": true, "parent_message": true,
+	"created_by_user_id": true, "created_by": true, "reply_count": true, "participant_count": true,
+	"active_participant_count": true, "thread_participants": true, "last_message_at": true,
+	"created_at": true, "updated_at": true, "deleted_at": true, "latest_replies": true, "read": true, "draft": true,
+}
+
+// UpdateThreadPartialResponse is the response of UpdateThreadPartial with the updated thread.
+type UpdateThreadPartialResponse struct {
+	Thread *ThreadResponse `json:"thread"`
+	Response
+}
+
+// UpdateThreadPartial sets and unsets the title and custom fields of the thread
+// started by the given parent message.
+func (c *Client) UpdateThreadPartial(ctx context.Context, parentMessageID string, update PartialUpdate) (*UpdateThreadPartialResponse, error) {
+	if parentMessageID == "" {
+		return nil, errors.New("parent message ID must be not empty")
+	}
+	if len(update.Set) == 0 && len(update.Unset) == 0 {
+		return nil, errors.New("set or unset must be not empty")
+	}
+	for _, field := range append(sortedKeys(update.Set), update.Unset...) {
+		if threadReadOnlyFields[field] {
+			return nil, fmt.Errorf("thread field %q cannot be updated", field)
+		}
+	}
+
+	p := path.Join("threads", url.PathEscape(parentMessageID))
+
+	var resp UpdateThreadPartialResponse
+	err := c.makeRequest(ctx, http.MethodPatch, p, nil, update, &resp)
+	return &resp, err
+}
+
+// MarkThreadRead marks the thread started by the given parent message as read for userID.
+func (ch *Channel) MarkThreadRead(ctx context.Context, userID, parentMessageID string) (*Response, error) {
+	if parentMessageID == "" {
+		return nil, errors.New("parent message ID must be not empty")
+	}
+	return ch.MarkRead(ctx, userID, MarkReadThread(parentMessageID))
+}
+
+// MarkThreadUnread marks the thread started by the given parent message as unread for userID.
+func (ch *Channel) MarkThreadUnread(ctx context.Context, userID, parentMessageID string) (*Response, error) {
+	if parentMessageID == "" {
+		return nil, errors.New("parent message ID must be not empty")
+	}
+	return ch.MarkUnread(ctx, userID, MarkUnreadThread(parentMessageID))
+}
@@ -0,0 +1,91 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ThreadManagement(t *testing.T) {
+	ctx := context.Background()
+
+	type request struct {
+		method, path string
+		query        url.Values
+		body         map[string]interface{}
+	}
+	var requests []request
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{method: r.Method, path: r.URL.Path, query: r.URL.Query()}
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			require.NoError(t, json.Unmarshal(b, &req.body))
+		}
+		requests = append(requests, req)
+
+		switch r.URL.Path {
+		case "/threads/parent-1":
+			_, _ = w.Write([]byte(`{"thread": {"channel_cid": "messaging:general", "parent_message_id": "parent-1",
+				"parent_message": {"id": "parent-1", "text": "hi", "user": {"id": "jane"}},
+				"created_by_user_id": "jane", "created_by": {"id": "jane", "name": "Jane"},
+				"reply_count": 2, "title": "Lunch", "custom": {"topic": "food"},
+				"latest_replies": [{"id": "reply-1", "parent_id": "parent-1", "user": {"id": "bob"}}],
+				"read": [{"user": {"id": "bob"}, "unread_messages": 1}]}}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+
+	resp, err := c.GetThread(ctx, "parent-1", &GetThreadOptions{ReplyLimit: 10, ParticipantLimit: 5})
+	require.NoError(t, err)
+	thread := resp.Thread
+	require.Equal(t, "hi", thread.ParentMessage.Text)
+	require.Equal(t, "Jane", thread.CreatedBy.Name)
+	require.Equal(t, "reply-1", thread.LatestReplies[0].ID)
+	require.Equal(t, "bob", thread.Read[0].User.ID)
+	require.Equal(t, "food", thread.Custom["topic"])
+	require.Equal(t, url.Values{"reply_limit": {"10"}, "participant_limit": {"5"}}, withoutAPIKey(requests[0].query))
+
+	_, err = c.UpdateThreadPartial(ctx, "parent-1", PartialUpdate{
+		Set:   map[string]interface{}{"title": "Dinner", "topic": "food"},
+		Unset: []string{"priority"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPatch, requests[1].method)
+	require.Equal(t, map[string]interface{}{
+		"set":   map[string]interface{}{"title": "Dinner", "topic": "food"},
+		"unset": []interface{}{"priority"},
+	}, requests[1].body)
+
+	ch := c.Channel("messaging", "general")
+	_, err = ch.MarkThreadRead(ctx, "bob", "parent-1")
+	require.NoError(t, err)
+	require.Equal(t, "/channels/messaging/general/read", requests[2].path)
+	require.Equal(t, "parent-1", requests[2].body["thread_id"])
+
+	_, err = ch.MarkThreadUnread(ctx, "bob", "parent-1")
+	require.NoError(t, err)
+	require.Equal(t, "/channels/messaging/general/unread", requests[3].path)
+	require.Equal(t, "parent-1", requests[3].body["thread_id"])
+
+	_, err = c.GetThread(ctx, "", nil)
+	require.Error(t, err)
+	_, err = c.GetThread(ctx, "parent-1", &GetThreadOptions{ReplyLimit: -1})
+	require.Error(t, err)
+	_, err = c.UpdateThreadPartial(ctx, "parent-1", PartialUpdate{})
+	require.Error(t, err)
+	_, err = c.UpdateThreadPartial(ctx, "parent-1", PartialUpdate{Unset: []string{"reply_count"}})
+	require.Error(t, err)
+	_, err = ch.MarkThreadRead(ctx, "bob", "")
+	require.Error(t, err)
+	require.Len(t, requests, 4)
+}
+
+func withoutAPIKey(v url.Values) url.Values {
+	v.Del("api_key")
+	return v
+}
@@ -0,0 +1,663 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TranscriptFormat is the output format of a Transcript.
+type TranscriptFormat string
+
+const (
+	TranscriptMarkdown TranscriptFormat = "markdown"
+	// TranscriptHTML is a standalone HTML page, styled to be printed or converted to PDF.
+	TranscriptHTML TranscriptFormat = "html"
+	TranscriptCSV  TranscriptFormat = "csv"
+	TranscriptJSON TranscriptFormat = "json"
+)
+
+const (
+	defaultTranscriptPageSize   = 100
+	defaultTranscriptTimeFormat = "2006-01-02 15:04:05 MST"
+	transcriptRedacted          = "[redacted]"
+)
+
+// TranscriptOptions configures Channel.Transcript.
+type TranscriptOptions struct {
+	// Since and Until limit the transcript to the messages and replies created in that period.
+	Since, Until time.Time
+	// PageSize is the number of messages read per request. Defaults to 100.
+	PageSize int
+	// SkipReplies leaves out thread replies.
+	SkipReplies bool
+	// IncludeEdits reads the previous versions of edited messages with QueryMessageHistory.
+	// If the message history is not available, the transcript is made without it.
+	// Messages count as edited when the API returns their message_text_updated_at time;
+	// updated_at is not used since reactions, pins and replies change it as well.
+	IncludeEdits bool
+
+	// Location is the time zone of the rendered times. Defaults to UTC.
+	Location *time.Location
+	// TimeFormat is the layout of the rendered times. Defaults to "2006-01-02 15:04:05 MST".
+	TimeFormat string
+
+	// RedactUsers replaces the user IDs and names with "User 1", "User 2" and so on. In texts,
+	// the IDs and names of all authors, editors and mentioned users of the transcript are
+	// replaced; other users named in plain text are not detected.
+	RedactUsers bool
+	// RedactPatterns are replaced with "[redacted]" in texts and attachments.
+	RedactPatterns []*regexp.Regexp
+}
+
+// TranscriptAttachment is an attachment of a transcript message, rendered as a link.
+type TranscriptAttachment struct {
+	Type  string `json:"type,omitempty"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// TranscriptEdit is a previous version of an edited message.
+type TranscriptEdit struct {
+	Text     string    `json:"text"`
+	EditedAt time.Time `json:"edited_at"`
+	EditedBy string    `json:"edited_by,omitempty"`
+}
+
+// TranscriptMessage is a message of a transcript.
+type TranscriptMessage struct {
+	ID          string                  `json:"id"`
+	ParentID    string                  `json:"parent_id,omitempty"`
+	Type        string                  `json:"type"`
+	UserID      string                  `json:"user_id,omitempty"`
+	UserName    string                  `json:"user_name,omitempty"`
+	Text        string                  `json:"text"`
+	CreatedAt   time.Time               `json:"created_at"`
+	EditedAt    *time.Time              `json:"edited_at,omitempty"`
+	DeletedAt   *time.Time              `json:"deleted_at,omitempty"`
+	Attachments []*TranscriptAttachment `json:"attachments,omitempty"`
+	Reactions   map[string]int          `json:"reactions,omitempty"`
+	// Edits are the previous versions of the message, oldest first.
+	Edits   []*TranscriptEdit    `json:"edits,omitempty"`
+	Replies []*TranscriptMessage `json:"replies,omitempty"`
+}
+
+// System reports whether the message is a system message.
+func (m *TranscriptMessage) System() bool {
+	return m.Type == string(MessageTypeSystem)
+}
+
+// Deleted reports whether the message was deleted.
+func (m *TranscriptMessage) Deleted() bool {
+	return m.DeletedAt != nil
+}
+
+// Transcript is the conversation of a channel, ready to be rendered with Write.
+type Transcript struct {
+	CID         string               `json:"cid"`
+	Name        string               `json:"name,omitempty"`
+	GeneratedAt time.Time            `json:"generated_at"`
+	Messages    []*TranscriptMessage `json:"messages"`
+	// EditsUnavailable is true if edits were requested but the message history couldn't be read.
+	EditsUnavailable bool `json:"edits_unavailable,omitempty"`
+
+	location   *time.Location
+	timeFormat string
+}
+
+// Transcript pages through the messages and thread replies of the channel, oldest first,
+// and returns its transcript.
+func (ch *Channel) Transcript(ctx context.Context, opts *TranscriptOptions) (*Transcript, error) {
+	if opts == nil {
+		opts = &TranscriptOptions{}
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultTranscriptPageSize
+	}
+
+	b := &transcriptBuilder{ch: ch, opts: opts, users: make(map[string]string), names: make(map[string]string)}
+	b.t = &Transcript{
+		CID:         ch.cid(),
+		GeneratedAt: time.Now(),
+		location:    opts.Location,
+		timeFormat:  opts.TimeFormat,
+	}
+	if err := b.collect(ctx, pageSize); err != nil {
+		return nil, err
+	}
+	// texts are redacted once all users are known, so earlier messages
+	// don't leak the names of users who only show up later
+	if opts.RedactUsers {
+		b.redactUsers(b.t.Messages)
+	}
+	return b.t, nil
+}
+
+type transcriptBuilder struct {
+	ch    *Channel
+	opts  *TranscriptOptions
+	t     *Transcript
+	users map[string]string // user ID to alias
+	names map[string]string // user ID to name
+}
+
+func (b *transcriptBuilder) collect(ctx context.Context, pageSize int) error {
+	ch, opts := b.ch, b.opts
+	seen := make(map[string]bool)
+	after := opts.Since
+	for {
+		resp, err := ch.Query(ctx, &QueryRequest{State: true, Messages: messagesFrom(after, pageSize)})
+		if err != nil {
+			return fmt.Errorf("query messages: %w", err)
+		}
+		if resp.Channel != nil {
+			b.t.Name, _ = resp.Channel.ExtraData["name"].(string)
+		}
+
+		messages := append([]*Message{}, resp.Messages...)
+		sort.SliceStable(messages, func(i, j int) bool { return messageTime(messages[i]).Before(messageTime(messages[j])) })
+
+		added := 0
+		for _, m := range messages {
+			if seen[m.ID] || m.ParentID != "" && !m.ShowInChannel {
+				continue
+			}
+			seen[m.ID] = true
+			if !opts.Until.IsZero() && messageTime(m).After(opts.Until) {
+				return nil
+			}
+
+			tm, err := b.message(ctx, m)
+			if err != nil {
+				return err
+			}
+			if m.ReplyCount > 0 && !opts.SkipReplies {
+				if tm.Replies, err = b.replies(ctx, m.ID, pageSize); err != nil {
+					return err
+				}
+			}
+			b.t.Messages = append(b.t.Messages, tm)
+			added++
+			after = messageTime(m)
+		}
+		if len(resp.Messages) < pageSize {
+			return nil
+		}
+		if added == 0 {
+			return errMessagePageNotAdvanced(after, pageSize)
+		}
+	}
+}
+
+// replies returns the replies of a thread in the transcript period. Replies also shown in
+// the channel are left out, since they are part of the channel messages.
+func (b *transcriptBuilder) replies(ctx context.Context, parentID string, pageSize int) ([]*TranscriptMessage, error) {
+	var replies []*TranscriptMessage
+	err := eachReply(ctx, b.ch, parentID, pageSize, func(m *Message) error {
+		created := messageTime(m)
+		if m.ShowInChannel || created.Before(b.opts.Since) || !b.opts.Until.IsZero() && created.After(b.opts.Until) {
+			return nil
+		}
+		tm, err := b.message(ctx, m)
+		if err != nil {
+			return err
+		}
+		replies = append(replies, tm)
+		return nil
+	})
+	return replies, err
+}
+
+func (b *transcriptBuilder) message(ctx context.Context, m *Message) (*TranscriptMessage, error) {
+	tm := &TranscriptMessage{
+		ID:        m.ID,
+		ParentID:  m.ParentID,
+		Type:      string(m.Type),
+		CreatedAt: messageTime(m),
+		DeletedAt: m.DeletedAt,
+	}
+	if tm.Type == "" {
+		tm.Type = string(MessageTypeRegular)
+	}
+	userID := m.UserID
+	if m.User != nil {
+		userID = m.User.ID
+		tm.UserName = m.User.Name
+	}
+	tm.UserID, tm.UserName = b.user(userID, tm.UserName)
+	tm.Text = b.redactPatterns(m.Text)
+	for _, u := range m.MentionedUsers {
+		b.user(u.ID, u.Name)
+	}
+
+	if m.DeletedAt == nil {
+		tm.EditedAt = messageTextUpdatedAt(m)
+	}
+	for _, a := range m.Attachments {
+		ta := &TranscriptAttachment{Type: a.Type, Title: b.redactPatterns(a.Title)}
+		for _, u := range []string{a.AssetURL, a.ImageURL, a.TitleLink, a.OGScrapeURL, a.ThumbURL} {
+			if u != "" {
+				ta.URL = b.redactPatterns(u)
+				break
+			}
+		}
+		tm.Attachments = append(tm.Attachments, ta)
+	}
+	if len(m.ReactionCounts) > 0 {
+		tm.Reactions = make(map[string]int, len(m.ReactionCounts))
+		for k, v := range m.ReactionCounts {
+			tm.Reactions[k] = v
+		}
+	}
+
+	if tm.EditedAt != nil && b.opts.IncludeEdits && !b.t.EditsUnavailable {
+		edits, err := b.edits(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		tm.Edits = edits
+	}
+	return tm, nil
+}
+
+// edits returns the previous versions of m. API errors mean that the message history is
+// not enabled, which is recorded in the transcript instead of failing.
+func (b *transcriptBuilder) edits(ctx context.Context, m *Message) ([]*TranscriptEdit, error) {
+	var edits []*TranscriptEdit
+	req := QueryMessageHistoryRequest{
+		Filter: map[string]any{"message_id": m.ID},
+		Sort:   []*SortOption{{Field: "message_updated_at", Direction: 1}},
+	}
+	for {
+		resp, err := b.ch.client.QueryMessageHistory(ctx, req)
+		var apiErr Error
+		if errors.As(err, &apiErr) && !isTransientError(err) {
+			b.t.EditsUnavailable = true
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query message history of %s: %w", m.ID, err)
+		}
+		for _, h := range resp.MessageHistory {
+			_, by := b.user(h.MessageUpdatedByID, "")
+			edits = append(edits, &TranscriptEdit{Text: b.redactPatterns(h.Text), EditedAt: h.MessageUpdatedAt, EditedBy: by})
+		}
+		if resp.Next == nil || *resp.Next == "" {
+			return edits, nil
+		}
+		req.Next = *resp.Next
+	}
+}
+
+// messageTextUpdatedAt returns the time the text of m was last edited, which the API
+// returns as message_text_updated_at for edited messages.
+func messageTextUpdatedAt(m *Message) *time.Time {
+	s, _ := m.ExtraData["message_text_updated_at"].(string)
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// user returns the ID and name to show for a user, replaced by a pseudonym if users are redacted.
+func (b *transcriptBuilder) user(id, name string) (string, string) {
+	if !b.opts.RedactUsers || id == "" {
+		if name == "" {
+			name = id
+		}
+		return id, name
+	}
+	if name != "" {
+		b.names[id] = name
+	}
+	alias, ok := b.users[id]
+	if !ok {
+		alias = fmt.Sprintf("User %d", len(b.users)+1)
+		b.users[id] = alias
+	}
+	return alias, alias
+}
+
+// redactUsers replaces the IDs and names of all users seen in the texts of messages.
+func (b *transcriptBuilder) redactUsers(messages []*TranscriptMessage) {
+	aliases := make(map[string]string)
+	for id, alias := range b.users {
+		aliases[id] = alias
+		if name := b.names[id]; name != "" {
+			aliases[name] = alias
+		}
+	}
+	if len(aliases) == 0 {
+		return
+	}
+
+	// longest first, so a name containing another one is replaced as a whole
+	terms := sortedKeys(aliases)
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	re := regexp.MustCompile(strings.Join(quoted, "|"))
+
+	var redact func(messages []*TranscriptMessage)
+	redact = func(messages []*TranscriptMessage) {
+		for _, m := range messages {
+			m.Text = replaceWords(re, m.Text, aliases)
+			for _, e := range m.Edits {
+				e.Text = replaceWords(re, e.Text, aliases)
+			}
+			redact(m.Replies)
+		}
+	}
+	redact(messages)
+}
+
+// replaceWords replaces the matches of re which are whole words with their value in repl.
+func replaceWords(re *regexp.Regexp, s string, repl map[string]string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringIndex(s, -1) {
+		before, _ := utf8.DecodeLastRuneInString(s[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(s[loc[1]:])
+		if isWordRune(before) || isWordRune(after) {
+			continue
+		}
+		sb.WriteString(s[last:loc[0]])
+		sb.WriteString(repl[s[loc[0]:loc[1]]])
+		last = loc[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (b *transcriptBuilder) redactPatterns(s string) string {
+	for _, re := range b.opts.RedactPatterns {
+		s = re.ReplaceAllString(s, transcriptRedacted)
+	}
+	return s
+}
+
+// MessageCount returns the number of messages including replies.
+func (t *Transcript) MessageCount() int {
+	n := 0
+	for _, m := range t.Messages {
+		n += 1 + len(m.Replies)
+	}
+	return n
+}
+
+func (t *Transcript) formatTime(ts time.Time) string {
+	loc := t.location
+	if loc == nil {
+		loc = time.UTC
+	}
+	layout := t.timeFormat
+	if layout == "" {
+		layout = defaultTranscriptTimeFormat
+	}
+	return ts.In(loc).Format(layout)
+}
+
+func (t *Transcript) title() string {
+	if t.Name != "" {
+		return fmt.Sprintf("%s (%s)", t.Name, t.CID)
+	}
+	return t.CID
+}
+
+// Write renders the transcript in the given format.
+func (t *Transcript) Write(w io.Writer, format TranscriptFormat) error {
+	switch format {
+	case TranscriptMarkdown:
+		return t.writeMarkdown(w)
+	case TranscriptHTML:
+		return t.writeHTML(w)
+	case TranscriptCSV:
+		return t.writeCSV(w)
+	case TranscriptJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t)
+	default:
+		return fmt.Errorf("unknown transcript format %q", format)
+	}
+}
+
+func sortedReactions(reactions map[string]int) []string {
+	out := make([]string, 0, len(reactions))
+	for _, k := range sortedKeys(reactions) {
+		out = append(out, fmt.Sprintf("%s x%d", k, reactions[k]))
+	}
+	return out
+}
+
+func (t *Transcript) writeMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Transcript of %s\n\n", t.title())
+	fmt.Fprintf(&sb, "Generated %s, %d messages.\n", t.formatTime(t.GeneratedAt), t.MessageCount())
+	if t.EditsUnavailable {
+		sb.WriteString("\nThe message history was not available, edits are not included.\n")
+	}
+
+	var writeMessage func(m *TranscriptMessage, prefix string)
+	writeMessage = func(m *TranscriptMessage, prefix string) {
+		line := func(format string, args ...interface{}) {
+			fmt.Fprintf(&sb, prefix+format+"\n", args...)
+		}
+		sb.WriteString(strings.TrimRight(prefix, " ") + "\n")
+		switch {
+		case m.Deleted():
+			line("*%s · message by %s deleted at %s*", t.formatTime(m.CreatedAt), m.UserName, t.formatTime(*m.DeletedAt))
+			return
+		case m.System():
+			line("*%s · %s*", t.formatTime(m.CreatedAt), m.Text)
+			return
+		}
+
+		header := fmt.Sprintf("**%s** · %s", m.UserName, t.formatTime(m.CreatedAt))
+		if m.EditedAt != nil {
+			header += fmt.Sprintf(" (edited %s)", t.formatTime(*m.EditedAt))
+		}
+		line("%s", header)
+		line("")
+		for _, text := range strings.Split(m.Text, "\n") {
+			line("%s", text)
+		}
+		for _, a := range m.Attachments {
+			title := a.Title
+			if title == "" {
+				title = a.Type
+			}
+			line("- Attachment: [%s](%s)", title, a.URL)
+		}
+		if len(m.Reactions) > 0 {
+			line("- Reactions: %s", strings.Join(sortedReactions(m.Reactions), ", "))
+		}
+		for _, e := range m.Edits {
+			line("- Version of %s: %s", t.formatTime(e.EditedAt), e.Text)
+		}
+		for _, r := range m.Replies {
+			writeMessage(r, prefix+"> ")
+		}
+	}
+	for _, m := range t.Messages {
+		writeMessage(m, "")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+var transcriptHTMLTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript of {{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; color: #222; }
+.message { margin: 1em 0; page-break-inside: avoid; }
+.meta { color: #666; font-size: 0.85em; }
+.system, .deleted { color: #666; font-style: italic; }
+.text { white-space: pre-wrap; }
+.replies { margin-left: 2em; border-left: 2px solid #ddd; padding-left: 1em; }
+@media print { body { margin: 0; max-width: none; } a { color: inherit; } }
+</style>
+</head>
+<body>
+<h1>Transcript of {{.Title}}</h1>
+<p class="meta">Generated {{.GeneratedAt}}, {{.Count}} messages.{{if .EditsUnavailable}} The message history was not available, edits are not included.{{end}}</p>
+{{range .Messages}}{{template "message" .}}{{end}}
+</body>
+</html>
+{{define "message"}}<div class="message">
+{{- if .Deleted}}
+<div class="deleted">{{.CreatedAt}} · message by {{.UserName}} deleted at {{.DeletedAt}}</div>
+{{- else if .System}}
+<div class="system">{{.CreatedAt}} · {{.Text}}</div>
+{{- else}}
+<div class="meta"><strong>{{.UserName}}</strong> · {{.CreatedAt}}{{if .EditedAt}} (edited {{.EditedAt}}){{end}}</div>
+<div class="text">{{.Text}}</div>
+{{- range .Attachments}}
+<div class="attachment">Attachment: <a href="{{.URL}}">{{if .Title}}{{.Title}}{{else}}{{.Type}}{{end}}</a></div>
+{{- end}}
+{{- if .Reactions}}
+<div class="meta">Reactions: {{.Reactions}}</div>
+{{- end}}
+{{- range .Edits}}
+<div class="meta">Version of {{.EditedAt}}: {{.Text}}</div>
+{{- end}}
+{{- if .Replies}}
+<div class="replies">{{range .Replies}}{{template "message" .}}{{end}}</div>
+{{- end}}
+{{- end}}
+</div>
+{{end}}`))
+
+type transcriptHTMLMessage struct {
+	UserName, Text, Reactions string
+	CreatedAt, EditedAt       string
+	DeletedAt                 string
+	Deleted, System           bool
+	Attachments               []*TranscriptAttachment
+	Edits                     []transcriptHTMLEdit
+	Replies                   []*transcriptHTMLMessage
+}
+
+type transcriptHTMLEdit struct {
+	EditedAt, Text string
+}
+
+func (t *Transcript) htmlMessage(m *TranscriptMessage) *transcriptHTMLMessage {
+	hm := &transcriptHTMLMessage{
+		UserName:    m.UserName,
+		Text:        m.Text,
+		Reactions:   strings.Join(sortedReactions(m.Reactions), ", "),
+		CreatedAt:   t.formatTime(m.CreatedAt),
+		Deleted:     m.Deleted(),
+		System:      m.System(),
+		Attachments: m.Attachments,
+	}
+	if m.EditedAt != nil {
+		hm.EditedAt = t.formatTime(*m.EditedAt)
+	}
+	if m.DeletedAt != nil {
+		hm.DeletedAt = t.formatTime(*m.DeletedAt)
+	}
+	for _, e := range m.Edits {
+		hm.Edits = append(hm.Edits, transcriptHTMLEdit{EditedAt: t.formatTime(e.EditedAt), Text: e.Text})
+	}
+	for _, r := range m.Replies {
+		hm.Replies = append(hm.Replies, t.htmlMessage(r))
+	}
+	return hm
+}
+
+func (t *Transcript) writeHTML(w io.Writer) error {
+	data := struct {
+		Title, GeneratedAt string
+		Count              int
+		EditsUnavailable   bool
+		Messages           []*transcriptHTMLMessage
+	}{
+		Title:            t.title(),
+		GeneratedAt:      t.formatTime(t.GeneratedAt),
+		Count:            t.MessageCount(),
+		EditsUnavailable: t.EditsUnavailable,
+	}
+	for _, m := range t.Messages {
+		data.Messages = append(data.Messages, t.htmlMessage(m))
+	}
+	return transcriptHTMLTemplate.Execute(w, data)
+}
+
+var transcriptCSVHeader = []string{
+	"id", "parent_id", "created_at", "user_id", "user_name", "type", "text",
+	"attachments", "reactions", "edited_at", "deleted_at", "previous_versions",
+}
+
+func (t *Transcript) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(transcriptCSVHeader); err != nil {
+		return err
+	}
+
+	optionalTime := func(ts *time.Time) string {
+		if ts == nil {
+			return ""
+		}
+		return t.formatTime(*ts)
+	}
+	var writeMessage func(m *TranscriptMessage) error
+	writeMessage = func(m *TranscriptMessage) error {
+		attachments := make([]string, 0, len(m.Attachments))
+		for _, a := range m.Attachments {
+			attachments = append(attachments, a.URL)
+		}
+		edits := make([]string, 0, len(m.Edits))
+		for _, e := range m.Edits {
+			edits = append(edits, e.Text)
+		}
+		record := []string{
+			m.ID, m.ParentID, t.formatTime(m.CreatedAt), m.UserID, m.UserName, m.Type, m.Text,
+			strings.Join(attachments, " "), strings.Join(sortedReactions(m.Reactions), ", "),
+			optionalTime(m.EditedAt), optionalTime(m.DeletedAt), strings.Join(edits, "\n"),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		for _, r := range m.Replies {
+			if err := writeMessage(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, m := range t.Messages {
+		if err := writeMessage(m); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,243 @@
+package stream_chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pageMessages returns the page of messages selected by the limit and the
+// created_at_after_or_equal cursor of a channel query.
+func pageMessages(q map[string]interface{}, messages []map[string]interface{}) []map[string]interface{} {
+	page := q["messages"].(map[string]interface{})
+	var out []map[string]interface{}
+	for _, m := range messages {
+		if m["created_at"].(string) >= page["created_at_after_or_equal"].(string) && len(out) < int(page["limit"].(float64)) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// pageReplies serves the replies endpoint like the API: without a creation time
+// bound it returns the latest replies.
+func pageReplies(q url.Values, replies []map[string]interface{}) []map[string]interface{} {
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	from := q.Get("created_at_after_or_equal")
+	if from == "" {
+		if afterID := q.Get("id_gt"); afterID != "" {
+			for i, m := range replies {
+				if m["id"] == afterID {
+					replies = replies[i+1:]
+					break
+				}
+			}
+		}
+		return replies[max(len(replies)-limit, 0):]
+	}
+	var out []map[string]interface{}
+	for _, m := range replies {
+		if m["created_at"].(string) >= from && len(out) < limit {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func TestChannel_Transcript(t *testing.T) {
+	ctx := context.Background()
+
+	var queries []map[string]interface{}
+	var history []string
+	historyEnabled := true
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/channels/messaging/team/query":
+			var q map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			queries = append(queries, q)
+			messages := []map[string]interface{}{
+				{"id": "m1", "text": "hi @bob, my phone is 555-1234", "user": map[string]interface{}{"id": "jane", "name": "Jane"},
+					"mentioned_users": []map[string]interface{}{{"id": "bob", "name": "bob"}},
+					"created_at":      "2024-01-01T10:00:00Z", "updated_at": "2024-01-01T10:06:00Z", "message_text_updated_at": "2024-01-01T10:05:00Z",
+					"reaction_counts": map[string]int{"like": 2}, "reply_count": 1},
+				// updated by a reaction, not edited
+				{"id": "m2", "type": "system", "text": "Bob joined", "created_at": "2024-01-01T10:01:00Z", "updated_at": "2024-01-01T10:20:00Z"},
+			}
+			messages = append(messages,
+				map[string]interface{}{"id": "m3", "text": "", "user": map[string]interface{}{"id": "bob", "name": "bob"}, "created_at": "2024-01-01T10:02:00Z",
+					"deleted_at": "2024-01-01T10:03:00Z", "attachments": []map[string]interface{}{{"type": "file", "title": "report.pdf", "asset_url": "https://cdn/report.pdf"}}})
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"channel":  map[string]interface{}{"type": "messaging", "id": "team", "cid": "messaging:team", "name": "Team"},
+				"messages": pageMessages(q, messages),
+			})
+		case "/messages/m1/replies":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": []map[string]interface{}{
+				{"id": "r1", "parent_id": "m1", "text": "hello <b>, thanks Jane", "user": map[string]interface{}{"id": "bob", "name": "bob"}, "created_at": "2024-01-01T10:04:00Z"},
+			}})
+		case "/messages/history":
+			var q map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+			history = append(history, q["filter"].(map[string]interface{})["message_id"].(string))
+			if !historyEnabled {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"code": 17, "message": "message history is not enabled", "StatusCode": 403}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"message_history": []map[string]interface{}{
+				{"message_id": "m1", "text": "hi", "message_updated_by_id": "jane", "message_updated_at": "2024-01-01T10:00:00Z"},
+			}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	ch := c.Channel("messaging", "team")
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	opts := &TranscriptOptions{
+		PageSize:       2,
+		IncludeEdits:   true,
+		Location:       berlin,
+		TimeFormat:     "15:04",
+		RedactUsers:    true,
+		RedactPatterns: []*regexp.Regexp{regexp.MustCompile(`\d{3}-\d{4}`)},
+	}
+	transcript, err := ch.Transcript(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, queries, 3)
+	require.Equal(t, "1970-01-01T00:00:00Z", queries[0]["messages"].(map[string]interface{})["created_at_after_or_equal"])
+	require.Equal(t, "2024-01-01T10:01:00Z", queries[1]["messages"].(map[string]interface{})["created_at_after_or_equal"])
+	require.Equal(t, "Team", transcript.Name)
+	require.Len(t, transcript.Messages, 3)
+	require.Equal(t, 4, transcript.MessageCount())
+
+	m1 := transcript.Messages[0]
+	require.Equal(t, "User 1", m1.UserName)
+	require.Equal(t, "hi @User 2, my phone is [redacted]", m1.Text)
+	require.Equal(t, "User 2", m1.Replies[0].UserID)
+	require.Equal(t, "hi", m1.Edits[0].Text)
+	require.Equal(t, "User 1", m1.Edits[0].EditedBy)
+	require.Equal(t, "hello <b>, thanks User 1", m1.Replies[0].Text)
+	require.Nil(t, transcript.Messages[1].EditedAt)
+	require.Equal(t, []string{"m1"}, history)
+
+	var md bytes.Buffer
+	require.NoError(t, transcript.Write(&md, TranscriptMarkdown))
+	require.Contains(t, md.String(), "# Transcript of Team (messaging:team)")
+	require.Contains(t, md.String(), "**User 1** · 11:00 (edited 11:05)\n\nhi @User 2, my phone is [redacted]\n- Reactions: like x2\n- Version of 11:00: hi\n")
+	require.Contains(t, md.String(), "> **User 2** · 11:04\n")
+	require.Contains(t, md.String(), "*11:01 · Bob joined*")
+	require.Contains(t, md.String(), "*11:02 · message by User 2 deleted at 11:03*")
+
+	var page bytes.Buffer
+	require.NoError(t, transcript.Write(&page, TranscriptHTML))
+	require.Contains(t, page.String(), "hello &lt;b&gt;, thanks User 1")
+	require.Contains(t, page.String(), "@media print")
+
+	var records bytes.Buffer
+	require.NoError(t, transcript.Write(&records, TranscriptCSV))
+	rows, err := csv.NewReader(&records).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	require.Equal(t, transcriptCSVHeader, rows[0])
+	require.Equal(t, []string{"r1", "m1", "11:04", "User 2", "User 2", "regular", "hello <b>, thanks User 1", "", "", "", "", ""}, rows[2])
+	require.Equal(t, "https://cdn/report.pdf", rows[4][7])
+
+	var doc bytes.Buffer
+	require.NoError(t, transcript.Write(&doc, TranscriptJSON))
+	var decoded Transcript
+	require.NoError(t, json.Unmarshal(doc.Bytes(), &decoded))
+	require.Equal(t, "m3", decoded.Messages[2].ID)
+
+	require.Error(t, transcript.Write(&doc, "pdf"))
+
+	historyEnabled = false
+	queries = nil
+	transcript, err = ch.Transcript(ctx, opts)
+	require.NoError(t, err)
+	require.True(t, transcript.EditsUnavailable)
+	require.Empty(t, transcript.Messages[0].Edits)
+}
+
+func TestChannel_Transcript_SameCreationTime(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+		messages := []map[string]interface{}{
+			{"id": "m1", "created_at": "2024-01-01T10:00:00Z"},
+			{"id": "m2", "created_at": "2024-01-01T10:00:00Z"},
+			{"id": "m3", "created_at": "2024-01-01T10:00:00Z"},
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageMessages(q, messages)})
+	}))
+
+	_, err := c.Channel("messaging", "team").Transcript(context.Background(), &TranscriptOptions{PageSize: 2, SkipReplies: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "more than 2 messages were created at 2024-01-01T10:00:00Z")
+}
+
+func TestChannel_Transcript_ManyReplies(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/messages/m1/replies" {
+			replies := []map[string]interface{}{
+				{"id": "r1", "parent_id": "m1", "created_at": "2024-01-01T10:01:00Z"},
+				{"id": "r2", "parent_id": "m1", "created_at": "2024-01-01T10:02:00Z"},
+				{"id": "r3", "parent_id": "m1", "created_at": "2024-01-01T10:03:00Z"},
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageReplies(r.URL.Query(), replies)})
+			return
+		}
+		var q map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+		messages := []map[string]interface{}{{"id": "m1", "created_at": "2024-01-01T10:00:00Z", "reply_count": 3}}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageMessages(q, messages)})
+	}))
+
+	transcript, err := c.Channel("messaging", "team").Transcript(context.Background(), &TranscriptOptions{PageSize: 2})
+	require.NoError(t, err)
+	var ids []string
+	for _, r := range transcript.Messages[0].Replies {
+		ids = append(ids, r.ID)
+	}
+	require.Equal(t, []string{"r1", "r2", "r3"}, ids)
+}
+
+func TestChannel_Transcript_RepliesInPeriod(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r2 := map[string]interface{}{"id": "r2", "parent_id": "m1", "show_in_channel": true, "created_at": "2024-01-01T10:20:00Z"}
+		if r.URL.Path == "/messages/m1/replies" {
+			replies := []map[string]interface{}{
+				{"id": "r1", "parent_id": "m1", "created_at": "2024-01-01T10:10:00Z"},
+				r2,
+				{"id": "r3", "parent_id": "m1", "created_at": "2024-01-01T11:00:00Z"},
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageReplies(r.URL.Query(), replies)})
+			return
+		}
+		var q map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+		messages := []map[string]interface{}{{"id": "m1", "created_at": "2024-01-01T10:00:00Z", "reply_count": 3}, r2}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"messages": pageMessages(q, messages)})
+	}))
+
+	transcript, err := c.Channel("messaging", "team").Transcript(context.Background(), &TranscriptOptions{
+		Since: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		Until: time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Len(t, transcript.Messages, 2)
+	require.Equal(t, "r2", transcript.Messages[1].ID)
+	require.Len(t, transcript.Messages[0].Replies, 1)
+	require.Equal(t, "r1", transcript.Messages[0].Replies[0].ID)
+	require.Equal(t, 3, transcript.MessageCount())
+}
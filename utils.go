@@ -0,0 +1,218 @@
+package stream_chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// batches splits items into slices of at most size items. size must be positive.
+func batches[T any](items []T, size int) [][]T {
+	var out [][]T
+	for len(items) > 0 {
+		n := min(size, len(items))
+		out = append(out, items[:n])
+		items = items[n:]
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStrings(s []string) []string {
+	out := append([]string{}, s...)
+	sort.Strings(out)
+	return out
+}
+
+func sameStringSet(a, b []string) bool {
+	return reflect.DeepEqual(dedupeSorted(a), dedupeSorted(b))
+}
+
+func dedupeSorted(s []string) []string {
+	out := sortedStrings(s)
+	n := 0
+	for i, v := range out {
+		if i == 0 || v != out[n-1] {
+			out[n] = v
+			n++
+		}
+	}
+	return out[:n]
+}
+
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// isZeroJSONValue reports whether v is the encoding of a zero value, which is how
+// settings that are not set in a desired AppSettings are encoded.
+func isZeroJSONValue(v interface{}) bool {
+	switch vv := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !vv
+	case string:
+		return vv == ""
+	case float64:
+		return vv == 0
+	case map[string]interface{}:
+		for _, item := range vv {
+			if !isZeroJSONValue(item) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(vv) == 0
+	}
+	return false
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func messageTime(m *Message) time.Time {
+	if m.CreatedAt == nil {
+		return time.Time{}
+	}
+	return *m.CreatedAt
+}
+
+// messagesFrom returns the page of the oldest messages created at or after from. Pages are
+// always bounded by a creation time, as the API returns the latest messages otherwise.
+func messagesFrom(from time.Time, limit int) *MessagePaginationParamsRequest {
+	if from.IsZero() {
+		from = time.Unix(0, 0).UTC()
+	}
+	return &MessagePaginationParamsRequest{
+		PaginationParamsRequest: PaginationParamsRequest{Limit: limit},
+		CreatedAtAfterEq:        &from,
+	}
+}
+
+// eachReply calls fn with the replies to parentID, oldest first. Like the channel messages,
+// replies are paged from a creation time, as the API returns the latest replies otherwise.
+func eachReply(ctx context.Context, ch *Channel, parentID string, pageSize int, fn func(*Message) error) error {
+	seen := make(map[string]bool)
+	var from time.Time
+	for {
+		page := messagesFrom(from, pageSize)
+		params := map[string][]string{
+			"limit":                     {strconv.Itoa(pageSize)},
+			"created_at_after_or_equal": {page.CreatedAtAfterEq.UTC().Format(time.RFC3339Nano)},
+		}
+		resp, err := ch.GetReplies(ctx, parentID, params)
+		if err != nil {
+			return fmt.Errorf("get replies of %s: %w", parentID, err)
+		}
+
+		replies := append([]*Message{}, resp.Messages...)
+		sort.SliceStable(replies, func(i, j int) bool { return messageTime(replies[i]).Before(messageTime(replies[j])) })
+
+		added := 0
+		for _, m := range replies {
+			if seen[m.ID] {
+				continue
+			}
+			seen[m.ID] = true
+			if err := fn(m); err != nil {
+				return err
+			}
+			added++
+			from = messageTime(m)
+		}
+		if len(resp.Messages) < pageSize {
+			return nil
+		}
+		if added == 0 {
+			return errMessagePageNotAdvanced(*page.CreatedAtAfterEq, pageSize)
+		}
+	}
+}
+
+// errMessagePageNotAdvanced is returned when a full page of messages was created at the time
+// of the cursor, so the next page would return the same messages.
+func errMessagePageNotAdvanced(cursor time.Time, pageSize int) error {
+	return fmt.Errorf("more than %d messages were created at %s, use a larger page size",
+		pageSize, cursor.UTC().Format(time.RFC3339Nano))
+}
+
+// maxReactionsOffset is the largest offset accepted by GetReactions.
+const maxReactionsOffset = 1000
+
+// errTooManyReactions is returned when the reactions of a message can't all be read, as
+// GetReactions can't page past maxReactionsOffset.
+func errTooManyReactions(messageID string) error {
+	return fmt.Errorf("message %s has more than %d reactions, which can't be paged through", messageID, maxReactionsOffset)
+}
+
+func memberUserID(m *ChannelMember) string {
+	if m.UserID == "" && m.User != nil {
+		return m.User.ID
+	}
+	return m.UserID
+}
+
+// memberChannelRole returns the channel role of m, taking the legacy moderator flag into account.
+func memberChannelRole(m *ChannelMember) string {
+	switch {
+	case m.ChannelRole != "":
+		return m.ChannelRole
+	case m.IsModerator:
+		return channelModeratorRole
+	default:
+		return channelMemberRole
+	}
+}